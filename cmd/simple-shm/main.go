@@ -4,200 +4,104 @@ import (
 	"log"
 	"math/rand"
 	"net"
-	"syscall"
+	"os"
 
-	"golang.org/x/sys/unix"
 	"honnef.co/go/wayland/wlclient"
+	"honnef.co/go/wayland/wlclient/frame"
 	"honnef.co/go/wayland/wlclient/protocols/wayland"
 	xdgShell "honnef.co/go/wayland/wlclient/protocols/xdg-shell"
+	"honnef.co/go/wayland/wlclient/registry"
+	"honnef.co/go/wayland/wlclient/shm"
+	"honnef.co/go/wayland/wlclient/window"
 )
 
 type Display struct {
 	display    *wayland.Display
-	registry   *wayland.Registry
+	registry   *registry.Registry
 	compositor *wayland.Compositor
 	shm        *wayland.Shm
 	wmBase     *xdgShell.WmBase
+	shell      *window.Shell
 	hasXRGB    bool
 }
 
 type Window struct {
-	display          *Display
-	width            int32
-	height           int32
-	surface          *wayland.Surface
-	xdgSurface       *xdgShell.Surface
-	xdgToplevel      *xdgShell.Toplevel
-	waitForConfigure bool
-	buffers          [2]Buffer
-	callback         *wayland.Callback
-}
-
-type Buffer struct {
-	buffer  *wayland.Buffer
-	shmData []byte
-	busy    bool
-}
-
-func roundtrip(dsp *wayland.Display) {
-	queue := wlclient.NewEventQueue()
-	cb := dsp.WithQueue(queue).Sync()
-	var done bool
-	cb.AddListener(wayland.CallbackEvents{
-		Done: func(obj *wayland.Callback, _ uint32) {
-			log.Println("callback fired")
-			done = true
-			cb.Destroy()
-		},
-	})
-	for !done {
-		queue.Dispatch()
-	}
+	display  *Display
+	surface  *wayland.Surface
+	toplevel *window.Toplevel
+	pool     *shm.Pool
+	loop     *frame.Loop
 }
 
 func createDisplay(c *wlclient.Conn) *Display {
 	dsp := &Display{
 		display: wayland.GetDisplay(c),
 	}
-	dsp.registry = dsp.display.GetRegistry()
-	dsp.registry.AddListener(wayland.RegistryEvents{
-		Global: func(_ *wayland.Registry, name uint32, iface string, version uint32) {
-			switch iface {
-			case "wl_compositor":
-				dsp.compositor = &wayland.Compositor{}
-				dsp.registry.Bind(name, dsp.compositor, 1)
-			case "xdg_wm_base":
-				dsp.wmBase = &xdgShell.WmBase{}
-				dsp.registry.Bind(name, dsp.wmBase, 1)
-			case "wl_shm":
-				dsp.shm = &wayland.Shm{}
-				dsp.registry.Bind(name, dsp.shm, 1)
-				dsp.shm.AddListener(wayland.ShmEvents{
-					Format: func(obj *wayland.Shm, format wayland.ShmFormat) {
-						if format == wayland.ShmFormatXrgb8888 {
-							dsp.hasXRGB = true
-						}
-					},
-				})
-			}
-		},
+	dsp.registry = registry.New(dsp.display)
+	registry.Require(dsp.registry, &dsp.compositor, 1, 1, nil)
+	registry.Require(dsp.registry, &dsp.wmBase, 1, 1, nil)
+	registry.Require(dsp.registry, &dsp.shm, 1, 1, func(shm *wayland.Shm, _ uint32) {
+		shm.AddListener(wayland.ShmEvents{
+			Format: func(obj *wayland.Shm, format wayland.ShmFormat) {
+				if format == wayland.ShmFormatXrgb8888 {
+					dsp.hasXRGB = true
+				}
+			},
+		})
 	})
 
-	// make sure the server has processed all requests and sent out
-	// all events, so that we have the full initial state of the
-	// registry
-	roundtrip(dsp.display)
-	dsp.display.Queue().Dispatch()
-
-	if dsp.shm == nil {
-		log.Fatal("no SHM")
-	}
-	if dsp.wmBase == nil {
-		log.Fatal("no XDG")
+	if err := dsp.registry.Init(); err != nil {
+		log.Fatal(err)
 	}
-
-	// this time make sure that we've processed all initial Shm events
-	roundtrip(dsp.display)
-	dsp.display.Queue().Dispatch()
-
 	if !dsp.hasXRGB {
 		log.Fatal("no XRGB8888")
 	}
+	dsp.shell = window.NewShell(dsp.wmBase, 1)
 
 	return dsp
 }
 
 func createWindow(dsp *Display, width, height int32) *Window {
+	pool, err := shm.NewPool(dsp.shm, wayland.ShmFormatXrgb8888)
+	if err != nil {
+		log.Fatal(err)
+	}
 	win := &Window{
+		pool:    pool,
 		display: dsp,
-		width:   width,
-		height:  height,
 		surface: dsp.compositor.CreateSurface(),
 	}
 
-	win.xdgSurface = dsp.wmBase.GetXdgSurface(win.surface)
-	win.xdgSurface.AddListener(xdgShell.SurfaceEvents{
-		Configure: func(_ *xdgShell.Surface, serial uint32) {
-			win.xdgSurface.AckConfigure(serial)
-			if win.waitForConfigure {
-				redraw(win, nil, 0)
-				win.waitForConfigure = false
-			}
-		},
-	})
-
-	win.xdgToplevel = win.xdgSurface.GetToplevel()
-	win.xdgToplevel.SetTitle("simple-shm")
-	win.surface.Commit()
-	win.waitForConfigure = true
-	return win
-}
-
-func redraw(win *Window, callback *wayland.Callback, time uint32) {
-	buf := windowNextBuffer(win)
-
-	for i := range buf.shmData {
-		buf.shmData[i] = byte(rand.Int())
-	}
+	win.loop = frame.NewLoop(win.surface, func(f frame.Info) bool { return redraw(win, f) })
 
-	win.surface.Attach(buf.buffer, 0, 0)
-	win.surface.Damage(0, 0, win.width, win.height)
-	if callback != nil {
-		callback.Destroy()
-	}
-	win.callback = win.surface.Frame()
-	win.callback.AddListener(wayland.CallbackEvents{
-		Done: func(_ *wayland.Callback, data uint32) {
-			redraw(win, win.callback, data)
+	win.toplevel = dsp.shell.NewToplevel(win.surface, width, height, window.ToplevelEvents{
+		Configure: func(*window.Toplevel, window.State) {
+			win.loop.Start()
+		},
+		Close: func(*window.Toplevel) {
+			os.Exit(0)
 		},
 	})
-	buf.busy = true
-	win.surface.Commit()
-}
-
-func paintPixels() {
-
-}
-
-func windowNextBuffer(win *Window) *Buffer {
-	var buf *Buffer
-	if !win.buffers[0].busy {
-		buf = &win.buffers[0]
-	} else {
-		buf = &win.buffers[1]
-	}
-
-	if buf.buffer == nil {
-		createShmBuffer(win.display, buf, win.width, win.height, wayland.ShmFormatXrgb8888)
-	}
-
-	return buf
+	win.toplevel.SetTitle("simple-shm")
+	win.toplevel.Show()
+	return win
 }
 
-func createShmBuffer(dsp *Display, buf *Buffer, width, height int32, format wayland.ShmFormat) {
-	stride := width * 4
-	size := stride * height
-	fd, err := unix.MemfdCreate("", 0)
-	if err != nil {
-		log.Fatal(err)
-	}
-	unix.Ftruncate(fd, int64(size))
-	data, err := syscall.Mmap(fd, 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+func redraw(win *Window, f frame.Info) bool {
+	width, height := win.toplevel.Size()
+	buf, err := win.pool.Get(width, height)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	pool := dsp.shm.CreatePool(uintptr(fd), size)
-	buf.buffer = pool.CreateBuffer(0, width, height, stride, format)
-	buf.buffer.AddListener(wayland.BufferEvents{
-		Release: func(_ *wayland.Buffer) {
-			buf.busy = false
-		},
-	})
-	pool.Destroy()
-	unix.Close(fd)
+	data := buf.Data()
+	for i := range data {
+		data[i] = byte(rand.Int())
+	}
 
-	buf.shmData = data
+	buf.Attach(win.surface, 0, 0)
+	win.surface.Damage(0, 0, width, height)
+	return true
 }
 
 func main() {
@@ -47,6 +47,7 @@ type elRequest struct {
 
 type elEvent struct {
 	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
 	Since string `xml:"since,attr"`
 
 	Description elDescription `xml:"description"`
@@ -166,10 +167,14 @@ func (b *Builder) wlprotoArg(arg elArg, ctx elInterface) string {
 		panic("XXX")
 	}
 	if arg.Interface == "" {
+		if arg.Type == "string" && arg.AllowNull == "true" {
+			return fmt.Sprintf("{Type: wlproto.%s, AllowNull: true}", typ)
+		}
 		if arg.Enum == "" {
 			return fmt.Sprintf("{Type: wlproto.%s}", typ)
 		} else {
-			return fmt.Sprintf("{Type: wlproto.%s, Aux: reflect.TypeOf(%s(0))}", typ, b.goTypeFromWlType(arg, ctx))
+			enum := b.goTypeFromWlType(arg, ctx)
+			return fmt.Sprintf("{Type: wlproto.%s, Aux: reflect.TypeOf(%s(0)), Enum: %sEnum}", typ, enum, enum)
 		}
 	} else {
 		if b.ServerMode {
@@ -335,7 +340,7 @@ func (b *Builder) printSpecs(out io.Writer) {
 			fmt.Fprintf(out, "%q\n", imp)
 		}
 		fmt.Fprintln(out, ")")
-		fmt.Fprintln(out, "var _ wlshared.Fixed\n") // make sure the import isn't unused
+		fmt.Fprint(out, "var _ wlshared.Fixed\n\n") // make sure the import isn't unused
 	}
 
 	printMaps := func() {
@@ -365,6 +370,18 @@ func (b *Builder) printSpecs(out io.Writer) {
 				fmt.Fprintf(b, "\"%s_%s\": &%s.Events[%d],\n", iface.Name, ev.Name, b.wlprotoInterfaceName(iface), iev)
 			}
 		}
+		fmt.Fprint(b, "}\n\n")
+
+		registry := "ClientRegistry"
+		if b.ServerMode {
+			registry = "ServerRegistry"
+		}
+		fmt.Fprintln(b, "func init() {")
+		fmt.Fprintf(b, "wlproto.%s.Register(\n", registry)
+		for _, iface := range b.Spec.Interfaces {
+			fmt.Fprintf(b, "%s,\n", b.wlprotoInterfaceName(iface))
+		}
+		fmt.Fprintln(b, ")")
 		fmt.Fprintln(b, "}")
 	}
 
@@ -382,6 +399,22 @@ func (b *Builder) printSpecs(out io.Writer) {
 					fmt.Fprintf(b, "%[1]s%[2]s%[3]s %[1]s%[2]s = %[4]s\n", b.typeName(iface.Name), exportedGoIdentifier(enum.Name), exportedGoIdentifier(entry.Name), entry.Value)
 				}
 				fmt.Fprintln(b, ")")
+				fmt.Fprintln(b)
+
+				typ := b.typeName(iface.Name) + exportedGoIdentifier(enum.Name)
+				fmt.Fprintf(b, "var %sEnum = &wlproto.Enum{\n", typ)
+				fmt.Fprintf(b, "Name: %q,\n", iface.Name+"."+enum.Name)
+				if enum.Bitfield == "true" {
+					fmt.Fprintln(b, "Bitfield: true,")
+				}
+				fmt.Fprintln(b, "Entries: []wlproto.EnumEntry{")
+				for _, entry := range enum.Entries {
+					fmt.Fprintf(b, "{Name: %q, Value: %s},\n", entry.Name, entry.Value)
+				}
+				fmt.Fprintln(b, "},")
+				fmt.Fprintln(b, "}")
+				fmt.Fprintln(b)
+				fmt.Fprintf(b, "func (v %[1]s) String() string { return %[1]sEnum.Format(uint32(v)) }\n\n", typ)
 			}
 		}
 
@@ -465,9 +498,9 @@ func (b *Builder) printSpecs(out io.Writer) {
 				}
 				fmt.Fprint(b, "}\n\n")
 
-				fmt.Fprintf(b, "func Add%sGlobal(dsp *wlserver.Display, version int, bind func(res %s) %s) {\n",
+				fmt.Fprintf(b, "func Add%sGlobal(dsp *wlserver.Display, version int, bind func(res %s) %s) uint32 {\n",
 					b.typeName(iface.Name), b.typeName(iface.Name), b.eventsTypeName(iface))
-				fmt.Fprintf(b, "dsp.AddGlobal(%s, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(%s))})\n", b.wlprotoInterfaceName(iface), b.typeName(iface.Name))
+				fmt.Fprintf(b, "return dsp.AddGlobal(%s, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(%s))})\n", b.wlprotoInterfaceName(iface), b.typeName(iface.Name))
 				fmt.Fprint(b, "}\n\n")
 			} else {
 				fmt.Fprintf(b, "type %s struct {\n", b.eventsTypeName(iface))
@@ -544,7 +577,11 @@ func (b *Builder) printSpecs(out io.Writer) {
 
 			fmt.Fprintln(b, "{")
 			if b.ServerMode {
-				fmt.Fprintf(b, "obj.Conn().SendEvent(obj, %d, ", ireq)
+				if typ == "destructor" {
+					fmt.Fprintf(b, "obj.Conn().SendDestructor(obj, %d, ", ireq)
+				} else {
+					fmt.Fprintf(b, "obj.Conn().SendEvent(obj, %d, ", ireq)
+				}
 				for _, arg := range args {
 					if arg.Type == "new_id" {
 						if ctor.Interface == "" {
@@ -594,7 +631,7 @@ func (b *Builder) printSpecs(out io.Writer) {
 		}
 
 		printEvent := func(ireq int, ev elEvent) {
-			printMethod(ireq, ev.Description, ev.Name, ev.Args, "")
+			printMethod(ireq, ev.Description, ev.Name, ev.Args, ev.Type)
 		}
 
 		printEnums()
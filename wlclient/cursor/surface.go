@@ -0,0 +1,122 @@
+package cursor
+
+import (
+	"time"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+)
+
+// Surface displays cursors on a dedicated wl_surface and animates
+// cursors that have more than one frame.
+type Surface struct {
+	surface *wayland.Surface
+	pointer *wayland.Pointer
+
+	cursor *Cursor
+	serial uint32
+	frame  int
+	scale  int
+	hotX   int32
+	hotY   int32
+
+	timer *time.Timer
+	// gen is incremented whenever the animation stops, to discard
+	// timer events that were already queued
+	gen uint64
+}
+
+// NewSurface creates a cursor surface for pointer. Displaying cursors
+// with a scale other than 1 requires a wl_compositor of at least
+// version 3.
+func NewSurface(compositor *wayland.Compositor, pointer *wayland.Pointer) *Surface {
+	return &Surface{
+		surface: compositor.CreateSurface(),
+		pointer: pointer,
+		scale:   1,
+	}
+}
+
+// Surface returns the underlying wl_surface.
+func (s *Surface) Surface() *wayland.Surface { return s.surface }
+
+// Cursor returns the cursor that is being displayed, or nil.
+func (s *Surface) Cursor() *Cursor { return s.cursor }
+
+// Set makes c the pointer's cursor. Serial is the serial of the
+// wl_pointer.enter event of the surface the pointer is over; setting
+// the cursor has to be repeated after every enter event, as
+// compositors reset the cursor when the pointer leaves a client's
+// surfaces. A nil cursor, as returned by failed theme lookups, or a
+// cursor without frames hides the pointer like Hide.
+func (s *Surface) Set(serial uint32, c *Cursor) {
+	if c == s.cursor && serial == s.serial {
+		return
+	}
+	s.stop()
+	if c == nil || len(c.Frames) == 0 {
+		s.Hide(serial)
+		return
+	}
+	s.cursor = c
+	s.serial = serial
+	if c.Scale != s.scale {
+		s.scale = c.Scale
+		s.surface.SetBufferScale(int32(c.Scale))
+	}
+	s.show(0, true)
+}
+
+// Hide hides the cursor while the pointer is over the surface entered
+// with serial.
+func (s *Surface) Hide(serial uint32) {
+	s.stop()
+	s.cursor = nil
+	s.serial = serial
+	s.pointer.SetCursor(serial, nil, 0, 0)
+}
+
+// Destroy stops the animation and destroys the wl_surface.
+func (s *Surface) Destroy() {
+	s.stop()
+	s.cursor = nil
+	s.surface.Destroy()
+}
+
+func (s *Surface) stop() {
+	s.gen++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+func (s *Surface) show(frame int, force bool) {
+	s.frame = frame
+	f := s.cursor.Frames[frame]
+	img := f.Image
+	scale := s.cursor.Scale
+	hotX := int32(img.HotX / scale)
+	hotY := int32(img.HotY / scale)
+
+	f.Buffer.Attach(s.surface, 0, 0)
+	s.surface.Damage(0, 0, int32(img.Width), int32(img.Height))
+	if force || hotX != s.hotX || hotY != s.hotY {
+		// Frames of animated cursors may have different hotspots.
+		s.hotX, s.hotY = hotX, hotY
+		s.pointer.SetCursor(s.serial, s.surface, hotX, hotY)
+	}
+	s.surface.Commit()
+
+	if len(s.cursor.Frames) > 1 && img.Delay > 0 {
+		gen := s.gen
+		queue := s.surface.Queue()
+		s.timer = time.AfterFunc(img.Delay, func() {
+			queue.Post(func() {
+				if gen != s.gen {
+					return
+				}
+				s.show((s.frame+1)%len(s.cursor.Frames), false)
+			})
+		})
+	}
+}
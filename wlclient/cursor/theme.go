@@ -0,0 +1,305 @@
+// Package cursor loads cursor themes and displays cursors, like
+// libwayland's wayland-cursor.
+//
+// Cursor themes are directories of Xcursor files, found in the same
+// locations libXcursor searches. The theme and size default to the
+// values of the XCURSOR_THEME and XCURSOR_SIZE environment variables.
+// Cursors are uploaded to shared memory once and displayed on a
+// dedicated cursor Surface, which also animates cursors that consist
+// of more than one image.
+package cursor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	"honnef.co/go/wayland/wlclient/shm"
+)
+
+// DefaultSize is the cursor size used if neither LoadTheme nor
+// XCURSOR_SIZE specify one.
+const DefaultSize = 24
+
+// ErrNotFound is returned when a theme doesn't contain a cursor.
+var ErrNotFound = errors.New("cursor not found")
+
+// SearchPath returns the directories that are searched for cursor
+// themes. It is the value of XCURSOR_PATH or, if that isn't set, the
+// icon directories defined by the XDG base directory specification.
+func SearchPath() []string {
+	if p := os.Getenv("XCURSOR_PATH"); p != "" {
+		return expandPath(filepath.SplitList(p))
+	}
+	var dirs []string
+	if d := os.Getenv("XDG_DATA_HOME"); d != "" {
+		dirs = append(dirs, filepath.Join(d, "icons"))
+	} else {
+		dirs = append(dirs, "~/.local/share/icons")
+	}
+	dirs = append(dirs, "~/.icons")
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, d := range filepath.SplitList(dataDirs) {
+		dirs = append(dirs, filepath.Join(d, "icons"))
+	}
+	dirs = append(dirs, "/usr/share/pixmaps")
+	return expandPath(dirs)
+}
+
+func expandPath(dirs []string) []string {
+	home, _ := os.UserHomeDir()
+	out := dirs[:0]
+	for _, d := range dirs {
+		if d == "~" || strings.HasPrefix(d, "~/") {
+			if home == "" {
+				continue
+			}
+			d = home + d[1:]
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// Theme is a cursor theme at a particular size. Cursors are loaded
+// lazily and cached.
+type Theme struct {
+	name string
+	size int
+	path []string
+	pool *shm.Pool
+
+	cursors map[cursorKey]*Cursor
+}
+
+type cursorKey struct {
+	name  string
+	scale int
+}
+
+// LoadTheme returns the cursor theme with the given name and nominal
+// size. An empty name selects the theme named by XCURSOR_THEME, or the
+// theme called "default". A size of zero selects the size in
+// XCURSOR_SIZE, or DefaultSize. Shm is used to create the buffers
+// holding the cursor images.
+//
+// Themes that don't exist are treated like empty themes, so that
+// LoadTheme only fails if it couldn't create the shared memory pool.
+func LoadTheme(shmObj *wayland.Shm, name string, size int) (*Theme, error) {
+	if name == "" {
+		name = os.Getenv("XCURSOR_THEME")
+	}
+	if name == "" {
+		name = "default"
+	}
+	if size <= 0 {
+		size, _ = strconv.Atoi(os.Getenv("XCURSOR_SIZE"))
+	}
+	if size <= 0 {
+		size = DefaultSize
+	}
+	pool, err := shm.NewPool(shmObj, wayland.ShmFormatArgb8888)
+	if err != nil {
+		return nil, err
+	}
+	return &Theme{
+		name:    name,
+		size:    size,
+		path:    SearchPath(),
+		pool:    pool,
+		cursors: make(map[cursorKey]*Cursor),
+	}, nil
+}
+
+// Name returns the name of the theme.
+func (t *Theme) Name() string { return t.name }
+
+// Size returns the nominal size of cursors, in surface-local
+// coordinates.
+func (t *Theme) Size() int { return t.size }
+
+// Cursor returns the named cursor, such as "default" or "text", for
+// outputs with the given scale factor. It picks the images whose size
+// is closest to the theme's size multiplied by the scale. If the
+// theme and the themes it inherits from don't contain the cursor, it
+// returns an error wrapping ErrNotFound.
+func (t *Theme) Cursor(name string, scale int) (*Cursor, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	key := cursorKey{name, scale}
+	if c, ok := t.cursors[key]; ok {
+		return c, nil
+	}
+
+	images, err := t.loadImages(name)
+	if err != nil {
+		return nil, err
+	}
+	c, err := t.newCursor(name, scale, bestImages(images, t.size*scale))
+	if err != nil {
+		return nil, err
+	}
+	t.cursors[key] = c
+	return c, nil
+}
+
+// Close destroys all cursors and releases the theme's shared memory.
+// Cursors must no longer be used afterwards.
+func (t *Theme) Close() error {
+	t.cursors = nil
+	return t.pool.Close()
+}
+
+func (t *Theme) loadImages(name string) ([]*Image, error) {
+	seen := map[string]bool{}
+	path, ok := t.find(t.name, name, seen)
+	if !ok {
+		// Like libXcursor, fall back to the default theme.
+		path, ok = t.find("default", name, seen)
+	}
+	if !ok {
+		return nil, fmt.Errorf("cursor %q in theme %q: %w", name, t.name, ErrNotFound)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	images, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return images, nil
+}
+
+// find looks for a cursor in a theme and the themes it inherits from.
+func (t *Theme) find(theme, name string, seen map[string]bool) (string, bool) {
+	if seen[theme] {
+		return "", false
+	}
+	seen[theme] = true
+	for _, dir := range t.path {
+		p := filepath.Join(dir, theme, "cursors", name)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p, true
+		}
+	}
+	for _, dir := range t.path {
+		for _, parent := range inherits(filepath.Join(dir, theme, "index.theme")) {
+			if p, ok := t.find(parent, name, seen); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+// inherits returns the themes listed in the Inherits key of an
+// index.theme file.
+func inherits(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		// Unreadable theme files are treated like missing ones.
+		return nil
+	}
+	defer f.Close()
+	var out []string
+	section := ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		if section != "Icon Theme" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "Inherits" {
+			continue
+		}
+		for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+			if name = strings.TrimSpace(name); name != "" {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+// bestImages returns the images whose nominal size is closest to size.
+func bestImages(images []*Image, size int) []*Image {
+	best := images[0].Size
+	for _, img := range images {
+		if abs(img.Size-size) < abs(best-size) {
+			best = img.Size
+		}
+	}
+	var out []*Image
+	for _, img := range images {
+		if img.Size == best {
+			out = append(out, img)
+		}
+	}
+	return out
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func (t *Theme) newCursor(name string, scale int, images []*Image) (*Cursor, error) {
+	c := &Cursor{
+		Name:  name,
+		Scale: scale,
+	}
+	for _, img := range images {
+		buf, err := t.pool.Alloc(int32(img.Width), int32(img.Height))
+		if err != nil {
+			for _, f := range c.Frames {
+				f.Buffer.Destroy()
+			}
+			return nil, err
+		}
+		copy(buf.Data(), img.Pix)
+		c.Frames = append(c.Frames, Frame{
+			Image:  img,
+			Buffer: buf,
+		})
+		c.Duration += img.Delay
+	}
+	return c, nil
+}
+
+// Cursor is a cursor that has been uploaded to shared memory.
+type Cursor struct {
+	Name string
+	// Scale is the scale factor the cursor was loaded for. Its images
+	// have to be displayed on a surface with the same buffer scale.
+	Scale int
+	// Frames contains one frame for static cursors, and several for
+	// animated ones.
+	Frames []Frame
+	// Duration is the total duration of one loop of the animation.
+	Duration time.Duration
+}
+
+// Frame is a single image of a cursor.
+type Frame struct {
+	Image  *Image
+	Buffer *shm.Buffer
+}
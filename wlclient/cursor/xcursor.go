@@ -0,0 +1,104 @@
+package cursor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Xcursor files are always little endian.
+var le = binary.LittleEndian
+
+const (
+	xcursorMagic     = 0x72756358 // "Xcur"
+	xcursorImageType = 0xfffd0002
+	// maximum width and height of images, as enforced by libXcursor
+	xcursorMaxSize = 0x7fff
+)
+
+// ErrInvalidXcursor is returned when decoding a malformed Xcursor file.
+var ErrInvalidXcursor = errors.New("invalid Xcursor file")
+
+// Image is a single image of a cursor in the Xcursor format.
+type Image struct {
+	// Size is the nominal size of the image. Cursor files usually
+	// contain the same cursor in several sizes.
+	Size          int
+	Width, Height int
+	// HotX and HotY are the position of the cursor's hotspot.
+	HotX, HotY int
+	// Delay is how long the image is shown in animated cursors.
+	Delay time.Duration
+	// Pix contains the pixels as premultiplied ARGB, stored as little
+	// endian 32-bit values, with no padding between rows. This is the
+	// same layout as a wl_shm buffer using the argb8888 format.
+	Pix []byte
+}
+
+// Decode decodes all images contained in an Xcursor file, in the
+// order they appear in the file.
+func Decode(r io.Reader) ([]*Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 16 || le.Uint32(data) != xcursorMagic {
+		return nil, ErrInvalidXcursor
+	}
+	hdrLen := le.Uint32(data[4:])
+	ntoc := le.Uint32(data[12:])
+	if hdrLen < 16 || uint64(hdrLen)+uint64(ntoc)*12 > uint64(len(data)) {
+		return nil, ErrInvalidXcursor
+	}
+
+	var images []*Image
+	toc := data[hdrLen:]
+	for i := uint32(0); i < ntoc; i++ {
+		entry := toc[i*12:]
+		if le.Uint32(entry) != xcursorImageType {
+			// comments and other chunks we don't care about
+			continue
+		}
+		img, err := decodeImage(data, le.Uint32(entry[8:]))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%w: no images", ErrInvalidXcursor)
+	}
+	return images, nil
+}
+
+func decodeImage(data []byte, pos uint32) (*Image, error) {
+	if uint64(pos)+36 > uint64(len(data)) {
+		return nil, ErrInvalidXcursor
+	}
+	chunk := data[pos:]
+	hdrLen := le.Uint32(chunk)
+	if le.Uint32(chunk[4:]) != xcursorImageType || hdrLen < 36 {
+		return nil, ErrInvalidXcursor
+	}
+	img := &Image{
+		Size:   int(le.Uint32(chunk[8:])),
+		Width:  int(le.Uint32(chunk[16:])),
+		Height: int(le.Uint32(chunk[20:])),
+		HotX:   int(le.Uint32(chunk[24:])),
+		HotY:   int(le.Uint32(chunk[28:])),
+		Delay:  time.Duration(le.Uint32(chunk[32:])) * time.Millisecond,
+	}
+	if img.Width == 0 || img.Height == 0 || img.Width > xcursorMaxSize || img.Height > xcursorMaxSize ||
+		img.HotX > img.Width || img.HotY > img.Height {
+		return nil, fmt.Errorf("%w: bad image dimensions", ErrInvalidXcursor)
+	}
+	n := img.Width * img.Height * 4
+	if uint64(hdrLen)+uint64(n) > uint64(len(chunk)) {
+		return nil, fmt.Errorf("%w: truncated image", ErrInvalidXcursor)
+	}
+	img.Pix = make([]byte, n)
+	copy(img.Pix, chunk[hdrLen:])
+	return img, nil
+}
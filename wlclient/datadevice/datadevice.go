@@ -0,0 +1,281 @@
+// Package datadevice implements copy and paste as well as drag and
+// drop on top of wl_data_device.
+//
+// A Device tracks the data offers the compositor sends for a seat.
+// The current selection, i.e. the clipboard, is available as an Offer
+// whose data can be read in any of the offered mime types. Setting the
+// selection makes the client serve data to other clients on request,
+// using providers that are called once per paste.
+//
+// Drag and drop uses the same mechanism: StartDrag offers data to
+// whichever client it is dropped on, and drags entering the client's
+// surfaces are reported as a Drag, which carries an Offer.
+//
+// Data is transferred through pipes. Reading from an offer blocks
+// until the source client writes the data, which requires the source
+// client to dispatch its events. Offers should therefore not be read
+// on the goroutine that dispatches events, as the source may be the
+// reading client itself.
+package datadevice
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+)
+
+// Provider returns the data to send to a client that requested it. It
+// is called once for every request and is called on the goroutine
+// dispatching events. The returned reader is read from and, if it
+// implements io.Closer, closed in a separate goroutine.
+type Provider func() io.Reader
+
+// Events are the callbacks of a Device. All callbacks are optional.
+type Events struct {
+	// Selection is called when the selection changes. The offer is
+	// nil if the selection has been cleared. It remains valid until
+	// the next call of Selection.
+	Selection func(offer *Offer)
+
+	// DragEnter is called when a drag enters one of the client's
+	// surfaces. The client has to call Drag.Accept to indicate
+	// whether it accepts the drop, or the drop will fail.
+	DragEnter func(drag *Drag)
+	// DragMotion is called when a drag moves within a surface.
+	DragMotion func(drag *Drag)
+	// DragLeave is called when a drag leaves a surface without being
+	// dropped, or after a drop.
+	DragLeave func(drag *Drag)
+	// Drop is called when a drag is dropped on a surface. The client
+	// should receive the data and then call Drag.Finish.
+	Drop func(drag *Drag)
+}
+
+// Device handles the wl_data_device of a seat.
+type Device struct {
+	manager *wayland.DataDeviceManager
+	device  *wayland.DataDevice
+	version uint32
+	events  Events
+
+	// offers introduced by wl_data_device.data_offer that haven't been
+	// used by a selection or drag and drop event yet
+	offers    map[*wayland.DataOffer]*Offer
+	selection *Offer
+	// source is the source of our own selection
+	source *wayland.DataSource
+	// drag is the drag over one of our surfaces
+	drag *Drag
+}
+
+// New returns a Device for seat. Version is the version of the
+// wl_data_device_manager. Drag and drop actions require version 3.
+func New(manager *wayland.DataDeviceManager, version uint32, seat *wayland.Seat, events Events) *Device {
+	d := &Device{
+		manager: manager,
+		device:  manager.GetDataDevice(seat),
+		version: version,
+		events:  events,
+		offers:  make(map[*wayland.DataOffer]*Offer),
+	}
+	d.device.AddListener(wayland.DataDeviceEvents{
+		DataOffer: d.handleDataOffer,
+		Enter:     d.handleEnter,
+		Leave:     d.handleLeave,
+		Motion:    d.handleMotion,
+		Drop:      d.handleDrop,
+		Selection: d.handleSelection,
+	})
+	return d
+}
+
+// DataDevice returns the underlying wl_data_device.
+func (d *Device) DataDevice() *wayland.DataDevice { return d.device }
+
+// Selection returns the current selection, or nil.
+func (d *Device) Selection() *Offer { return d.selection }
+
+// SetSelection sets the selection to data, which maps mime types to
+// providers of the data in that type. Serial is the serial of the
+// input event that caused the selection to be set, such as a key
+// press; compositors ignore requests that don't correspond to recent
+// input events of the focused client.
+func (d *Device) SetSelection(serial uint32, data map[string]Provider) {
+	source := newSource(d.manager, data, wayland.DataSourceEvents{
+		Cancelled: func(source *wayland.DataSource) {
+			// another client set the selection
+			if source == d.source {
+				d.source = nil
+			}
+			source.Destroy()
+		},
+	})
+	d.device.SetSelection(source, serial)
+	if d.source != nil {
+		d.source.Destroy()
+	}
+	d.source = source
+}
+
+// ClearSelection clears the selection.
+func (d *Device) ClearSelection(serial uint32) {
+	d.device.SetSelection(nil, serial)
+	if d.source != nil {
+		d.source.Destroy()
+		d.source = nil
+	}
+}
+
+// Close destroys all offers and our selection's source. It doesn't
+// release the wl_data_device, which remains the responsibility of the
+// caller.
+func (d *Device) Close() {
+	for _, o := range d.offers {
+		o.destroy()
+	}
+	d.offers = nil
+	if d.selection != nil {
+		d.selection.destroy()
+		d.selection = nil
+	}
+	if d.drag != nil {
+		d.drag.Destroy()
+	}
+	if d.source != nil {
+		d.source.Destroy()
+		d.source = nil
+	}
+}
+
+func (d *Device) handleDataOffer(_ *wayland.DataDevice, wo *wayland.DataOffer) {
+	o := &Offer{offer: wo}
+	wo.AddListener(wayland.DataOfferEvents{
+		Offer: func(_ *wayland.DataOffer, mimeType string) {
+			o.mimeTypes = append(o.mimeTypes, mimeType)
+		},
+		SourceActions: func(_ *wayland.DataOffer, actions wayland.DataDeviceManagerDndAction) {
+			o.sourceActions = actions
+		},
+		Action: func(_ *wayland.DataOffer, action wayland.DataDeviceManagerDndAction) {
+			o.action = action
+		},
+	})
+	d.offers[wo] = o
+}
+
+// take returns the Offer for a wl_data_offer introduced by
+// wl_data_device.data_offer.
+func (d *Device) take(wo *wayland.DataOffer) *Offer {
+	if wo == nil {
+		return nil
+	}
+	o, ok := d.offers[wo]
+	if !ok {
+		return nil
+	}
+	delete(d.offers, wo)
+	return o
+}
+
+func (d *Device) handleSelection(_ *wayland.DataDevice, wo *wayland.DataOffer) {
+	if d.selection != nil {
+		d.selection.destroy()
+	}
+	d.selection = d.take(wo)
+	if d.events.Selection != nil {
+		d.events.Selection(d.selection)
+	}
+}
+
+// newSource creates a data source offering data. Events.Send is
+// provided by newSource, all other events are passed through.
+func newSource(manager *wayland.DataDeviceManager, data map[string]Provider, events wayland.DataSourceEvents) *wayland.DataSource {
+	source := manager.CreateDataSource()
+	events.Send = func(_ *wayland.DataSource, mimeType string, fd uintptr) {
+		send(data[mimeType], fd)
+	}
+	source.AddListener(events)
+	for mimeType := range data {
+		source.Offer(mimeType)
+	}
+	return source
+}
+
+// send writes the data of p to fd and closes it.
+func send(p Provider, fd uintptr) {
+	f := os.NewFile(fd, "data-source")
+	if p == nil {
+		// a mime type we never offered
+		f.Close()
+		return
+	}
+	r := p()
+	go func() {
+		defer f.Close()
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+		// Errors are expected, e.g. when the receiver closes its end of
+		// the pipe early, and there's nobody to report them to.
+		io.Copy(f, r)
+	}()
+}
+
+// Offer is data offered by another client, or by ourselves.
+type Offer struct {
+	offer         *wayland.DataOffer
+	mimeTypes     []string
+	sourceActions wayland.DataDeviceManagerDndAction
+	action        wayland.DataDeviceManagerDndAction
+	destroyed     bool
+}
+
+// DataOffer returns the underlying wl_data_offer.
+func (o *Offer) DataOffer() *wayland.DataOffer { return o.offer }
+
+// MimeTypes returns the mime types the data is offered in.
+func (o *Offer) MimeTypes() []string { return o.mimeTypes }
+
+// SourceActions returns the drag and drop actions supported by the
+// source. Offers of version 3 or later announce them before entering
+// a surface.
+func (o *Offer) SourceActions() wayland.DataDeviceManagerDndAction { return o.sourceActions }
+
+// HasMimeType reports whether the data is offered in mimeType.
+func (o *Offer) HasMimeType(mimeType string) bool {
+	for _, m := range o.mimeTypes {
+		if m == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// Receive requests the data in the given mime type. The data can be
+// read from the returned reader until EOF, after which the reader
+// should be closed.
+func (o *Offer) Receive(mimeType string) (io.ReadCloser, error) {
+	if o.destroyed {
+		return nil, os.ErrClosed
+	}
+	var fds [2]int
+	if err := unix.Pipe2(fds[:], unix.O_CLOEXEC); err != nil {
+		return nil, os.NewSyscallError("pipe2", err)
+	}
+	o.offer.Receive(mimeType, uintptr(fds[1]))
+	// The write end has been sent to the compositor, which passes it
+	// on to the source. Our copy has to be closed for the reader to
+	// see EOF.
+	unix.Close(fds[1])
+	return os.NewFile(uintptr(fds[0]), "data-offer"), nil
+}
+
+func (o *Offer) destroy() {
+	if o.destroyed {
+		return
+	}
+	o.destroyed = true
+	o.offer.Destroy()
+}
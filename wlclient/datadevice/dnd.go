@@ -0,0 +1,296 @@
+package datadevice
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+// Drag and drop actions. The compositor picks one of the actions
+// supported by both the source and the target, taking the target's
+// preference and pressed modifiers into account.
+const (
+	ActionNone = wayland.DataDeviceManagerDndActionNone
+	ActionCopy = wayland.DataDeviceManagerDndActionCopy
+	ActionMove = wayland.DataDeviceManagerDndActionMove
+	// ActionAsk lets the target ask the user which action to
+	// perform after the drop, before calling Drag.Accept with the
+	// final action.
+	ActionAsk = wayland.DataDeviceManagerDndActionAsk
+)
+
+// ErrActionAsk is returned by Drag.Finish if the final action of a
+// drop with ActionAsk hasn't been chosen yet.
+var ErrActionAsk = errors.New("drop with ActionAsk needs a final action before finishing")
+
+// Drag is a drag over one of the client's surfaces.
+type Drag struct {
+	// Offer is the dragged data. It is nil for drags started by the
+	// client itself without any data.
+	Offer *Offer
+	// Surface is the surface the drag is over.
+	Surface *wayland.Surface
+	// Serial is the serial of the enter event.
+	Serial uint32
+	// X and Y are the surface-local coordinates of the drag.
+	X, Y float64
+
+	device  *Device
+	dropped bool
+	done    bool
+}
+
+// Dropped reports whether the data has been dropped.
+func (dr *Drag) Dropped() bool { return dr.dropped }
+
+// Action returns the action the compositor selected. It is only
+// meaningful for wl_data_device_manager version 3 or later, and is
+// ActionNone if the source and target have no action in common.
+func (dr *Drag) Action() wayland.DataDeviceManagerDndAction {
+	if dr.Offer == nil {
+		return ActionNone
+	}
+	return dr.Offer.action
+}
+
+// Accept signals whether the client would accept a drop at the current
+// position. MimeType is the preferred type of the data, or the empty
+// string to reject the drop. Actions are the actions the client
+// supports and preferred is the one it prefers; they are ignored
+// before version 3 of wl_data_device_manager.
+//
+// Accept may be called again in response to motion, e.g. if only
+// parts of the surface accept drops. After a drop with ActionAsk, it
+// has to be called with the single action the user chose.
+func (dr *Drag) Accept(mimeType string, actions, preferred wayland.DataDeviceManagerDndAction) {
+	if dr.Offer == nil || dr.done {
+		return
+	}
+	if !dr.dropped {
+		// Accept can't be used after a drop
+		dr.Offer.offer.Accept(dr.Serial, mimeType)
+	}
+	if dr.device.version >= 3 {
+		if mimeType == "" {
+			actions, preferred = ActionNone, ActionNone
+		}
+		dr.Offer.offer.SetActions(actions, preferred)
+	}
+}
+
+// Receive requests the dropped data in the given mime type, like
+// Offer.Receive.
+func (dr *Drag) Receive(mimeType string) (io.ReadCloser, error) {
+	if dr.Offer == nil || dr.done {
+		return nil, os.ErrClosed
+	}
+	return dr.Offer.Receive(mimeType)
+}
+
+// Finish signals that the drop was successful and destroys the offer.
+// It should be called once all data has been received. If the action
+// is ActionMove, the source will delete its data.
+//
+// Compositors only accept finishing drops with ActionCopy or
+// ActionMove. After a drop with ActionAsk, Accept has to be called
+// with the action the user chose, and Finish returns ErrActionAsk
+// without destroying the offer until the compositor has confirmed
+// that action.
+func (dr *Drag) Finish() error {
+	if dr.done {
+		return nil
+	}
+	if dr.Offer != nil && dr.dropped && dr.device.version >= 3 {
+		switch dr.Action() {
+		case ActionCopy, ActionMove:
+			dr.Offer.offer.Finish()
+		case ActionAsk:
+			return ErrActionAsk
+		}
+	}
+	dr.Destroy()
+	return nil
+}
+
+// Destroy destroys the offer without finishing the drop, which
+// cancels the operation if the drag has been dropped already.
+func (dr *Drag) Destroy() {
+	if dr.done {
+		return
+	}
+	dr.done = true
+	if dr.Offer != nil {
+		dr.Offer.destroy()
+	}
+}
+
+func (d *Device) handleEnter(_ *wayland.DataDevice, serial uint32, surface *wayland.Surface, x, y wlshared.Fixed, wo *wayland.DataOffer) {
+	if d.drag != nil && !d.drag.dropped {
+		d.drag.Destroy()
+	}
+	d.drag = &Drag{
+		Offer:   d.take(wo),
+		Surface: surface,
+		Serial:  serial,
+		X:       x.Float64(),
+		Y:       y.Float64(),
+		device:  d,
+	}
+	if d.events.DragEnter != nil {
+		d.events.DragEnter(d.drag)
+	}
+}
+
+func (d *Device) handleMotion(_ *wayland.DataDevice, _ uint32, x, y wlshared.Fixed) {
+	dr := d.drag
+	if dr == nil {
+		return
+	}
+	dr.X = x.Float64()
+	dr.Y = y.Float64()
+	if d.events.DragMotion != nil {
+		d.events.DragMotion(dr)
+	}
+}
+
+func (d *Device) handleLeave(_ *wayland.DataDevice) {
+	dr := d.drag
+	if dr == nil {
+		return
+	}
+	d.drag = nil
+	if d.events.DragLeave != nil {
+		d.events.DragLeave(dr)
+	}
+	if !dr.dropped {
+		// The offer of a dropped drag stays valid until the client
+		// finishes it.
+		dr.Destroy()
+	}
+}
+
+func (d *Device) handleDrop(_ *wayland.DataDevice) {
+	dr := d.drag
+	if dr == nil {
+		return
+	}
+	dr.dropped = true
+	if d.events.Drop != nil {
+		d.events.Drop(dr)
+	} else {
+		dr.Destroy()
+	}
+}
+
+// DragEvents are the callbacks of a DragSource. All callbacks are
+// optional.
+type DragEvents struct {
+	// Target is called when the target accepts a mime type, or with
+	// the empty string if no target accepts the data. It can be used
+	// to give feedback, e.g. by changing the drag icon.
+	Target func(mimeType string)
+	// Action is called when the compositor selects an action.
+	Action func(action wayland.DataDeviceManagerDndAction)
+	// Dropped is called when the user dropped the data. The operation
+	// may still be cancelled.
+	Dropped func()
+	// Finished is called when the target finished the drop. If the
+	// action is ActionMove, the client should delete the data.
+	Finished func(action wayland.DataDeviceManagerDndAction)
+	// Cancelled is called if the drag has been cancelled, for example
+	// because it was dropped where it isn't accepted.
+	//
+	// Before version 3 of wl_data_device_manager, a successful drop
+	// is indistinguishable from one that hasn't finished yet, and
+	// neither Dropped nor Finished are called.
+	Cancelled func()
+}
+
+// DragSource is a drag started by the client.
+type DragSource struct {
+	source *wayland.DataSource
+	events DragEvents
+	action wayland.DataDeviceManagerDndAction
+	done   bool
+}
+
+// StartDrag starts a drag and drop operation. Serial is the serial of
+// the implicit grab on origin, such as a pointer button press. Icon,
+// which may be nil, is a surface to use as the drag icon. Data maps
+// mime types to providers of the data, like with SetSelection, and
+// actions are the drag and drop actions the client supports. Actions
+// are ignored before version 3 of wl_data_device_manager.
+//
+// If data is nil, the drag is only visible to the client's own
+// surfaces, which receive drags without an offer.
+func (d *Device) StartDrag(serial uint32, origin, icon *wayland.Surface, data map[string]Provider, actions wayland.DataDeviceManagerDndAction, events DragEvents) *DragSource {
+	ds := &DragSource{events: events}
+	if data != nil {
+		ds.source = newSource(d.manager, data, wayland.DataSourceEvents{
+			Target:           ds.handleTarget,
+			Cancelled:        ds.handleCancelled,
+			DndDropPerformed: ds.handleDropPerformed,
+			DndFinished:      ds.handleFinished,
+			Action:           ds.handleAction,
+		})
+		if d.version >= 3 {
+			ds.source.SetActions(actions)
+		}
+	}
+	d.device.StartDrag(ds.source, origin, icon, serial)
+	return ds
+}
+
+// Action returns the most recent action selected by the compositor.
+func (ds *DragSource) Action() wayland.DataDeviceManagerDndAction { return ds.action }
+
+// Cancel cancels the drag, if it hasn't finished yet.
+func (ds *DragSource) Cancel() {
+	ds.destroy()
+}
+
+func (ds *DragSource) destroy() {
+	if ds.done {
+		return
+	}
+	ds.done = true
+	if ds.source != nil {
+		ds.source.Destroy()
+	}
+}
+
+func (ds *DragSource) handleTarget(_ *wayland.DataSource, mimeType string) {
+	if ds.events.Target != nil {
+		ds.events.Target(mimeType)
+	}
+}
+
+func (ds *DragSource) handleAction(_ *wayland.DataSource, action wayland.DataDeviceManagerDndAction) {
+	ds.action = action
+	if ds.events.Action != nil {
+		ds.events.Action(action)
+	}
+}
+
+func (ds *DragSource) handleDropPerformed(_ *wayland.DataSource) {
+	if ds.events.Dropped != nil {
+		ds.events.Dropped()
+	}
+}
+
+func (ds *DragSource) handleFinished(_ *wayland.DataSource) {
+	ds.destroy()
+	if ds.events.Finished != nil {
+		ds.events.Finished(ds.action)
+	}
+}
+
+func (ds *DragSource) handleCancelled(_ *wayland.DataSource) {
+	ds.destroy()
+	if ds.events.Cancelled != nil {
+		ds.events.Cancelled()
+	}
+}
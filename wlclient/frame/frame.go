@@ -0,0 +1,138 @@
+// Package frame paces rendering using frame callbacks.
+//
+// Compositors signal a good time to draw the next frame by firing the
+// wl_callback requested via wl_surface.frame. A Loop requests these
+// callbacks, calls a render function for every frame and commits the
+// surface. Compositors stop firing frame callbacks for surfaces that
+// aren't visible, which automatically suspends rendering of occluded
+// windows.
+package frame
+
+import (
+	"time"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+)
+
+// Info describes a frame that is about to be rendered.
+type Info struct {
+	// Time is the timestamp provided by the compositor, with
+	// millisecond granularity and an undefined base. It can only be
+	// compared with other timestamps.
+	Time time.Duration
+	// Delta is the time passed since the previous frame. It is zero
+	// for frames that weren't triggered by a frame callback, such as
+	// the first frame after Start or frames drawn in response to
+	// Redraw while the loop was idle.
+	Delta time.Duration
+}
+
+// Loop drives rendering of a surface.
+type Loop struct {
+	surface *wayland.Surface
+	render  func(f Info) bool
+
+	callback *wayland.Callback
+	// last is the timestamp of the most recent frame callback
+	last    uint32
+	hasLast bool
+	// dirty is set when a redraw has been requested while waiting for
+	// a frame callback or while rendering
+	dirty     bool
+	rendering bool
+	running   bool
+}
+
+// NewLoop returns a loop that renders to surface.
+//
+// Render is called for every frame. It should attach a buffer and
+// damage the surface, but not commit it; the loop commits the surface
+// after requesting the next frame callback. Render returns whether it
+// wants to draw another frame, e.g. because an animation is running.
+// If it returns false, the loop goes idle until Redraw is called.
+func NewLoop(surface *wayland.Surface, render func(f Info) bool) *Loop {
+	return &Loop{
+		surface: surface,
+		render:  render,
+	}
+}
+
+// Start starts the loop by rendering the first frame immediately.
+func (l *Loop) Start() {
+	if l.running {
+		return
+	}
+	l.running = true
+	l.hasLast = false
+	l.frame(Info{})
+}
+
+// Stop stops the loop. The frame callback that may currently be
+// pending gets destroyed.
+func (l *Loop) Stop() {
+	l.running = false
+	l.dirty = false
+	if l.callback != nil {
+		l.callback.Destroy()
+		l.callback = nil
+	}
+}
+
+// Redraw requests that another frame be rendered. If the loop is
+// waiting for a frame callback, the frame will be rendered once the
+// callback fires. Otherwise, it is rendered immediately.
+func (l *Loop) Redraw() {
+	if !l.running {
+		return
+	}
+	if l.callback != nil || l.rendering {
+		l.dirty = true
+		return
+	}
+	l.frame(Info{Time: time.Duration(l.last) * time.Millisecond})
+}
+
+// Waiting reports whether the loop is waiting for the compositor to
+// fire a frame callback. A loop that remains waiting for a long time
+// is likely rendering to a surface that isn't visible.
+func (l *Loop) Waiting() bool {
+	return l.callback != nil
+}
+
+func (l *Loop) done(cb *wayland.Callback, ms uint32) {
+	if cb != l.callback {
+		// stale callback from before Stop
+		return
+	}
+	// wl_callback is destroyed by the compositor after it fires
+	l.callback.Destroy()
+	l.callback = nil
+
+	var delta time.Duration
+	if l.hasLast {
+		// uint32 arithmetic handles the timestamp wrapping around
+		delta = time.Duration(ms-l.last) * time.Millisecond
+	}
+	l.last = ms
+	l.hasLast = true
+	l.frame(Info{Time: time.Duration(ms) * time.Millisecond, Delta: delta})
+}
+
+func (l *Loop) frame(f Info) {
+	l.dirty = false
+	l.rendering = true
+	more := l.render(f)
+	l.rendering = false
+	if l.running && (more || l.dirty) {
+		l.callback = l.surface.Frame()
+		cb := l.callback
+		cb.AddListener(wayland.CallbackEvents{
+			Done: func(_ *wayland.Callback, ms uint32) { l.done(cb, ms) },
+		})
+	} else {
+		// Going idle. The next frame's delta would span the idle
+		// period, which isn't useful for animations.
+		l.hasLast = false
+	}
+	l.surface.Commit()
+}
@@ -0,0 +1,311 @@
+// Package input turns the raw events of wl_keyboard, wl_pointer and
+// wl_touch into higher-level events that are easier for applications to
+// consume.
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	"honnef.co/go/wayland/wlclient/xkb"
+)
+
+// Key repeat settings used if the compositor doesn't send
+// wl_keyboard.repeat_info, which was added in version 4.
+const (
+	DefaultRepeatRate  = 25
+	DefaultRepeatDelay = 600 * time.Millisecond
+)
+
+var byteOrder binary.ByteOrder
+
+func init() {
+	var x uint32 = 0x01020304
+	if *(*byte)(unsafe.Pointer(&x)) == 0x01 {
+		byteOrder = binary.BigEndian
+	} else {
+		byteOrder = binary.LittleEndian
+	}
+}
+
+// evdevOffset is the offset between evdev key codes, as used by
+// wl_keyboard.key, and XKB keycodes.
+const evdevOffset = 8
+
+// KeyEvent describes a key being pressed, released or repeated.
+type KeyEvent struct {
+	// Serial is the serial of the wl_keyboard.key event. It is zero for
+	// repeated keys.
+	Serial uint32
+	// Time is the timestamp of the event, with millisecond granularity
+	// and an undefined base. Timestamps of repeated keys are
+	// extrapolated from the initial key press.
+	Time time.Duration
+	// Keycode is the XKB keycode of the key.
+	Keycode xkb.Keycode
+	// Keysym is the keysym produced by the key, or xkb.NoSymbol.
+	Keysym xkb.Keysym
+	// Text is the UTF-8 text produced by the key. It is only set for
+	// key presses and repeats.
+	Text  string
+	State wayland.KeyboardKeyState
+	// Repeat is set for key presses generated by key repeat.
+	Repeat bool
+}
+
+// KeyboardEvents are the callbacks of a Keyboard. All callbacks are
+// optional and are called on the goroutine dispatching the
+// wl_keyboard's event queue.
+type KeyboardEvents struct {
+	// Keymap is called when the compositor provides a new keymap.
+	Keymap func(km *xkb.Keymap)
+	// Enter is called when a surface gains keyboard focus. Keys
+	// contains the keys that are already pressed.
+	Enter func(surface *wayland.Surface, serial uint32, keys []xkb.Keycode)
+	// Leave is called when a surface loses keyboard focus.
+	Leave func(surface *wayland.Surface, serial uint32)
+	Key   func(ev KeyEvent)
+	// Modifiers is called when the modifier state changes.
+	Modifiers func(state *xkb.State)
+	// Error is called when the keymap couldn't be loaded. Keys will
+	// not produce any keysyms until a valid keymap has been received.
+	Error func(err error)
+}
+
+// Keyboard tracks the keymap and modifier state of a wl_keyboard and
+// translates key events to keysyms and text.
+type Keyboard struct {
+	keyboard *wayland.Keyboard
+	events   KeyboardEvents
+
+	keymap *xkb.Keymap
+	state  *xkb.State
+	focus  *wayland.Surface
+
+	repeatRate  int32
+	repeatDelay time.Duration
+	repeatKey   xkb.Keycode
+	repeatTime  time.Duration
+	repeatTimer *time.Timer
+	// repeatGen is incremented whenever key repeat stops, to discard
+	// timer events that were already queued
+	repeatGen uint64
+}
+
+// NewKeyboard returns a Keyboard that handles the events of kbd.
+func NewKeyboard(kbd *wayland.Keyboard, events KeyboardEvents) *Keyboard {
+	k := &Keyboard{
+		keyboard:    kbd,
+		events:      events,
+		repeatRate:  DefaultRepeatRate,
+		repeatDelay: DefaultRepeatDelay,
+	}
+	kbd.AddListener(wayland.KeyboardEvents{
+		Keymap:     k.handleKeymap,
+		Enter:      k.handleEnter,
+		Leave:      k.handleLeave,
+		Key:        k.handleKey,
+		Modifiers:  k.handleModifiers,
+		RepeatInfo: k.handleRepeatInfo,
+	})
+	return k
+}
+
+// Keyboard returns the underlying wl_keyboard.
+func (k *Keyboard) Keyboard() *wayland.Keyboard { return k.keyboard }
+
+// Keymap returns the current keymap, or nil if none has been received
+// yet.
+func (k *Keyboard) Keymap() *xkb.Keymap { return k.keymap }
+
+// State returns the current keyboard state, or nil if no keymap has
+// been received yet.
+func (k *Keyboard) State() *xkb.State { return k.state }
+
+// Focus returns the surface that has keyboard focus, or nil.
+func (k *Keyboard) Focus() *wayland.Surface { return k.focus }
+
+// RepeatInfo returns the key repeat rate in characters per second and
+// the delay before keys start repeating. A rate of zero means that
+// keys don't repeat.
+func (k *Keyboard) RepeatInfo() (rate int32, delay time.Duration) {
+	return k.repeatRate, k.repeatDelay
+}
+
+// Close stops key repeat. It doesn't release the wl_keyboard, which
+// remains the responsibility of the caller.
+func (k *Keyboard) Close() {
+	k.stopRepeat()
+}
+
+func (k *Keyboard) error(err error) {
+	if k.events.Error != nil {
+		k.events.Error(err)
+	}
+}
+
+func (k *Keyboard) handleKeymap(_ *wayland.Keyboard, format wayland.KeyboardKeymapFormat, fd uintptr, size uint32) {
+	defer unix.Close(int(fd))
+	k.stopRepeat()
+	k.keymap = nil
+	k.state = nil
+	if format != wayland.KeyboardKeymapFormatXkbV1 {
+		if format != wayland.KeyboardKeymapFormatNoKeymap {
+			k.error(fmt.Errorf("unsupported keymap format %d", format))
+		}
+		return
+	}
+	km, err := loadKeymap(int(fd), int(size))
+	if err != nil {
+		k.error(err)
+		return
+	}
+	k.keymap = km
+	k.state = xkb.NewState(km)
+	if k.events.Keymap != nil {
+		k.events.Keymap(km)
+	}
+}
+
+func loadKeymap(fd int, size int) (*xkb.Keymap, error) {
+	if size == 0 {
+		return nil, errors.New("empty keymap")
+	}
+	// Since version 7, the fd must be mapped with MAP_PRIVATE.
+	data, err := unix.Mmap(fd, 0, size, unix.PROT_READ, unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't map keymap: %w", err)
+	}
+	defer unix.Munmap(data)
+	// The size includes the terminating NUL byte.
+	if i := bytes.IndexByte(data, 0); i != -1 {
+		data = data[:i]
+	}
+	return xkb.ParseKeymap(string(data))
+}
+
+func (k *Keyboard) handleEnter(_ *wayland.Keyboard, serial uint32, surface *wayland.Surface, keys []byte) {
+	k.focus = surface
+	if k.events.Enter == nil {
+		return
+	}
+	codes := make([]xkb.Keycode, 0, len(keys)/4)
+	for i := 0; i+4 <= len(keys); i += 4 {
+		codes = append(codes, xkb.Keycode(byteOrder.Uint32(keys[i:])+evdevOffset))
+	}
+	k.events.Enter(surface, serial, codes)
+}
+
+func (k *Keyboard) handleLeave(_ *wayland.Keyboard, serial uint32, surface *wayland.Surface) {
+	k.stopRepeat()
+	k.focus = nil
+	if k.events.Leave != nil {
+		k.events.Leave(surface, serial)
+	}
+}
+
+func (k *Keyboard) handleKey(_ *wayland.Keyboard, serial uint32, ms uint32, key uint32, state wayland.KeyboardKeyState) {
+	code := xkb.Keycode(key + evdevOffset)
+	t := time.Duration(ms) * time.Millisecond
+	ev := KeyEvent{
+		Serial:  serial,
+		Time:    t,
+		Keycode: code,
+		State:   state,
+	}
+	if k.state != nil {
+		ev.Keysym = k.state.Keysym(code)
+		if state == wayland.KeyboardKeyStatePressed {
+			ev.Text = k.state.Text(code)
+		}
+	}
+
+	switch state {
+	case wayland.KeyboardKeyStatePressed:
+		if k.keymap != nil && k.keymap.Repeats(code) {
+			k.startRepeat(code, t)
+		}
+	case wayland.KeyboardKeyStateReleased:
+		if code == k.repeatKey {
+			k.stopRepeat()
+		}
+	}
+
+	if k.events.Key != nil {
+		k.events.Key(ev)
+	}
+}
+
+func (k *Keyboard) handleModifiers(_ *wayland.Keyboard, _ uint32, depressed, latched, locked, group uint32) {
+	if k.state == nil {
+		return
+	}
+	k.state.UpdateMask(depressed, latched, locked, group)
+	if k.events.Modifiers != nil {
+		k.events.Modifiers(k.state)
+	}
+}
+
+func (k *Keyboard) handleRepeatInfo(_ *wayland.Keyboard, rate int32, delay int32) {
+	k.repeatRate = rate
+	k.repeatDelay = time.Duration(delay) * time.Millisecond
+	if rate <= 0 {
+		k.stopRepeat()
+	}
+}
+
+func (k *Keyboard) startRepeat(code xkb.Keycode, t time.Duration) {
+	k.stopRepeat()
+	if k.repeatRate <= 0 {
+		return
+	}
+	k.repeatKey = code
+	k.repeatTime = t + k.repeatDelay
+	gen := k.repeatGen
+	queue := k.keyboard.Queue()
+	k.repeatTimer = time.AfterFunc(k.repeatDelay, func() {
+		// Deliver repeats via the event queue so that they're
+		// serialized with all other events.
+		queue.Post(func() { k.repeat(gen) })
+	})
+}
+
+func (k *Keyboard) stopRepeat() {
+	k.repeatGen++
+	k.repeatKey = 0
+	if k.repeatTimer != nil {
+		k.repeatTimer.Stop()
+		k.repeatTimer = nil
+	}
+}
+
+func (k *Keyboard) repeat(gen uint64) {
+	if gen != k.repeatGen || k.state == nil {
+		return
+	}
+	code := k.repeatKey
+	ev := KeyEvent{
+		Time:    k.repeatTime,
+		Keycode: code,
+		// Use the current modifier state, so that e.g. pressing Shift
+		// while repeating switches to uppercase letters.
+		Keysym: k.state.Keysym(code),
+		Text:   k.state.Text(code),
+		State:  wayland.KeyboardKeyStatePressed,
+		Repeat: true,
+	}
+	// The next repeat is scheduled only once this one has been
+	// dispatched, so that slow clients don't accumulate repeats.
+	interval := time.Second / time.Duration(k.repeatRate)
+	k.repeatTime += interval
+	k.repeatTimer.Reset(interval)
+	if k.events.Key != nil {
+		k.events.Key(ev)
+	}
+}
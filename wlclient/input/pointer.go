@@ -0,0 +1,253 @@
+package input
+
+import (
+	"time"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+// Linux evdev codes of common pointer buttons, as used by
+// wl_pointer.button.
+const (
+	ButtonLeft    = 0x110
+	ButtonRight   = 0x111
+	ButtonMiddle  = 0x112
+	ButtonSide    = 0x113
+	ButtonExtra   = 0x114
+	ButtonForward = 0x115
+	ButtonBack    = 0x116
+)
+
+// ButtonEvent describes a button being pressed or released.
+type ButtonEvent struct {
+	Serial uint32
+	Time   time.Duration
+	Button uint32
+	State  wayland.PointerButtonState
+}
+
+// AxisEvent describes scrolling along one axis.
+type AxisEvent struct {
+	// Value is the length of the scroll vector in the same coordinate
+	// space as motion events. Values of several axis events in the
+	// same frame are summed up.
+	Value float64
+	// Discrete is the number of discrete steps, such as wheel clicks,
+	// or zero if the axis source isn't discrete.
+	Discrete int32
+	// Stop is set if scrolling along the axis stopped, e.g. because
+	// the user lifted their fingers off the touchpad. It can be used
+	// to start kinetic scrolling.
+	Stop bool
+}
+
+// PointerFrame describes all changes to a pointer that happened in a
+// single logical event, such as a diagonal scroll affecting both
+// axes, or the pointer moving from one surface to another.
+type PointerFrame struct {
+	// Time is the timestamp of the latest timed event in the frame,
+	// with millisecond granularity and an undefined base. It is zero
+	// if the frame only contains enter or leave events.
+	Time time.Duration
+
+	// Leave is the surface the pointer left, if any.
+	Leave *wayland.Surface
+	// Enter is the surface the pointer entered, if any.
+	Enter *wayland.Surface
+	// EnterSerial is the serial of the enter event, which is required
+	// to set the cursor image. It is zero if the frame doesn't contain
+	// an enter event.
+	EnterSerial uint32
+
+	// Focus is the surface the pointer is over after the frame. X and
+	// Y are the surface-local coordinates of the pointer. Moved is set
+	// if they changed due to an enter or motion event.
+	Focus *wayland.Surface
+	X, Y  float64
+	Moved bool
+
+	// Buttons contains all button events, in order.
+	Buttons []ButtonEvent
+
+	// Axes contains the scroll events, indexed by wayland.PointerAxis.
+	// HasAxis reports which axes have events.
+	Axes    [2]AxisEvent
+	HasAxis [2]bool
+	// AxisSource is the source of the axis events. It is only valid
+	// if HasAxisSource is set.
+	AxisSource    wayland.PointerAxisSource
+	HasAxisSource bool
+}
+
+// Pointer accumulates the events of a wl_pointer and delivers them as
+// one PointerFrame per wl_pointer.frame event.
+type Pointer struct {
+	pointer  *wayland.Pointer
+	framed   bool
+	callback func(f PointerFrame)
+
+	focus       *wayland.Surface
+	enterSerial uint32
+	x, y        float64
+
+	cur     PointerFrame
+	pending bool
+}
+
+// NewPointer returns a Pointer that handles the events of ptr. Version
+// is the version of the wl_pointer, which is the same as that of the
+// wl_seat it was created from. Versions before 5 don't group events
+// into frames, and every event is delivered as its own frame.
+//
+// Fn is called on the goroutine dispatching the wl_pointer's event
+// queue.
+func NewPointer(ptr *wayland.Pointer, version uint32, fn func(f PointerFrame)) *Pointer {
+	p := &Pointer{
+		pointer:  ptr,
+		framed:   version >= 5,
+		callback: fn,
+	}
+	ptr.AddListener(wayland.PointerEvents{
+		Enter:        p.handleEnter,
+		Leave:        p.handleLeave,
+		Motion:       p.handleMotion,
+		Button:       p.handleButton,
+		Axis:         p.handleAxis,
+		Frame:        p.handleFrame,
+		AxisSource:   p.handleAxisSource,
+		AxisStop:     p.handleAxisStop,
+		AxisDiscrete: p.handleAxisDiscrete,
+	})
+	return p
+}
+
+// Pointer returns the underlying wl_pointer.
+func (p *Pointer) Pointer() *wayland.Pointer { return p.pointer }
+
+// Focus returns the surface the pointer is over, or nil. Changes to
+// the focus only take effect once the frame has been delivered.
+func (p *Pointer) Focus() *wayland.Surface { return p.focus }
+
+// Position returns the surface-local coordinates of the pointer.
+func (p *Pointer) Position() (x, y float64) { return p.x, p.y }
+
+// EnterSerial returns the serial of the most recent enter event.
+func (p *Pointer) EnterSerial() uint32 { return p.enterSerial }
+
+// SetCursor sets the cursor image, using the serial of the most recent
+// enter event. Surface may be nil to hide the cursor.
+func (p *Pointer) SetCursor(surface *wayland.Surface, hotspotX, hotspotY int32) {
+	p.pointer.SetCursor(p.enterSerial, surface, hotspotX, hotspotY)
+}
+
+func msec(ms uint32) time.Duration { return time.Duration(ms) * time.Millisecond }
+
+// event marks the current frame as having events and delivers it
+// right away if the pointer doesn't send frame events.
+func (p *Pointer) event() {
+	p.pending = true
+	if !p.framed {
+		p.handleFrame(p.pointer)
+	}
+}
+
+func (p *Pointer) handleEnter(_ *wayland.Pointer, serial uint32, surface *wayland.Surface, x, y wlshared.Fixed) {
+	p.cur.Enter = surface
+	p.cur.EnterSerial = serial
+	p.enterSerial = serial
+	p.cur.X = x.Float64()
+	p.cur.Y = y.Float64()
+	p.cur.Moved = true
+	p.event()
+}
+
+func (p *Pointer) handleLeave(_ *wayland.Pointer, _ uint32, surface *wayland.Surface) {
+	if p.cur.Enter != nil && p.cur.Enter == surface {
+		// The pointer entered and left the surface in the same frame.
+		p.cur.Enter = nil
+		p.cur.EnterSerial = 0
+	} else {
+		p.cur.Leave = surface
+	}
+	p.event()
+}
+
+func (p *Pointer) handleMotion(_ *wayland.Pointer, ms uint32, x, y wlshared.Fixed) {
+	p.cur.Time = msec(ms)
+	p.cur.X = x.Float64()
+	p.cur.Y = y.Float64()
+	p.cur.Moved = true
+	p.event()
+}
+
+func (p *Pointer) handleButton(_ *wayland.Pointer, serial uint32, ms uint32, button uint32, state wayland.PointerButtonState) {
+	p.cur.Time = msec(ms)
+	p.cur.Buttons = append(p.cur.Buttons, ButtonEvent{
+		Serial: serial,
+		Time:   msec(ms),
+		Button: button,
+		State:  state,
+	})
+	p.event()
+}
+
+func (p *Pointer) handleAxis(_ *wayland.Pointer, ms uint32, axis wayland.PointerAxis, value wlshared.Fixed) {
+	if axis > wayland.PointerAxisHorizontalScroll {
+		return
+	}
+	p.cur.Time = msec(ms)
+	p.cur.Axes[axis].Value += value.Float64()
+	p.cur.HasAxis[axis] = true
+	p.event()
+}
+
+func (p *Pointer) handleAxisSource(_ *wayland.Pointer, source wayland.PointerAxisSource) {
+	p.cur.AxisSource = source
+	p.cur.HasAxisSource = true
+	p.event()
+}
+
+func (p *Pointer) handleAxisStop(_ *wayland.Pointer, ms uint32, axis wayland.PointerAxis) {
+	if axis > wayland.PointerAxisHorizontalScroll {
+		return
+	}
+	p.cur.Time = msec(ms)
+	p.cur.Axes[axis].Stop = true
+	p.cur.HasAxis[axis] = true
+	p.event()
+}
+
+func (p *Pointer) handleAxisDiscrete(_ *wayland.Pointer, axis wayland.PointerAxis, discrete int32) {
+	if axis > wayland.PointerAxisHorizontalScroll {
+		return
+	}
+	p.cur.Axes[axis].Discrete += discrete
+	p.cur.HasAxis[axis] = true
+	p.event()
+}
+
+func (p *Pointer) handleFrame(_ *wayland.Pointer) {
+	if !p.pending {
+		return
+	}
+	f := p.cur
+	p.cur = PointerFrame{}
+	p.pending = false
+
+	if f.Leave != nil && f.Leave == p.focus {
+		p.focus = nil
+	}
+	if f.Enter != nil {
+		p.focus = f.Enter
+	}
+	if f.Moved {
+		p.x, p.y = f.X, f.Y
+	}
+	f.Focus = p.focus
+	f.X, f.Y = p.x, p.y
+
+	if p.callback != nil {
+		p.callback(f)
+	}
+}
@@ -0,0 +1,221 @@
+package input
+
+import (
+	"sort"
+	"time"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+// TouchPoint describes a single touch point, i.e. a finger on a
+// touchscreen.
+type TouchPoint struct {
+	// ID identifies the touch point. IDs are unique for as long as the
+	// point is down, but may be reused afterwards.
+	ID int32
+	// Surface is the surface the point went down on. Points keep their
+	// surface for as long as they are down, even when moving outside
+	// of it.
+	Surface *wayland.Surface
+	// Serial is the serial of the down event.
+	Serial uint32
+	// DownTime is the timestamp of the down event.
+	DownTime time.Duration
+
+	// X and Y are the surface-local coordinates of the point. StartX
+	// and StartY are the coordinates at which it went down.
+	X, Y           float64
+	StartX, StartY float64
+
+	// Major and Minor are the lengths of the axes of the ellipse
+	// describing the contact area, in surface-local coordinates. They
+	// are only valid if HasShape is set.
+	Major, Minor float64
+	HasShape     bool
+	// Orientation is the angle between the major axis and the Y axis
+	// of the surface, in degrees. It is only valid if HasOrientation
+	// is set.
+	Orientation    float64
+	HasOrientation bool
+
+	// Down is set if the point went down in this frame, Up if it was
+	// lifted, and Moved if its position changed.
+	Down, Up, Moved bool
+}
+
+// TouchFrame is a snapshot of all touch points at the end of a
+// wl_touch.frame.
+type TouchFrame struct {
+	// Time is the timestamp of the latest timed event in the frame,
+	// with millisecond granularity and an undefined base.
+	Time time.Duration
+	// Points contains all points that are down, as well as the points
+	// that were lifted in this frame, ordered by ID.
+	Points []TouchPoint
+	// Cancelled is set if the compositor cancelled the touch sequence,
+	// for example because it recognized a global gesture. All touch
+	// points have been discarded and Points is empty. Gestures in
+	// progress should be aborted, not completed.
+	Cancelled bool
+}
+
+// On returns the points in the frame that belong to surface.
+func (f TouchFrame) On(surface *wayland.Surface) []TouchPoint {
+	var out []TouchPoint
+	for _, pt := range f.Points {
+		if pt.Surface == surface {
+			out = append(out, pt)
+		}
+	}
+	return out
+}
+
+// Touch maintains the set of active touch points of a wl_touch and
+// delivers a snapshot of them for every wl_touch.frame.
+type Touch struct {
+	touch    *wayland.Touch
+	callback func(f TouchFrame)
+
+	points  map[int32]*TouchPoint
+	time    time.Duration
+	pending bool
+	// down are the points that were down at the end of the last
+	// frame
+	down []TouchPoint
+}
+
+// NewTouch returns a Touch that handles the events of touch. Fn is
+// called on the goroutine dispatching the wl_touch's event queue.
+func NewTouch(touch *wayland.Touch, fn func(f TouchFrame)) *Touch {
+	t := &Touch{
+		touch:    touch,
+		callback: fn,
+		points:   make(map[int32]*TouchPoint),
+	}
+	touch.AddListener(wayland.TouchEvents{
+		Down:        t.handleDown,
+		Up:          t.handleUp,
+		Motion:      t.handleMotion,
+		Frame:       t.handleFrame,
+		Cancel:      t.handleCancel,
+		Shape:       t.handleShape,
+		Orientation: t.handleOrientation,
+	})
+	return t
+}
+
+// Touch returns the underlying wl_touch.
+func (t *Touch) Touch() *wayland.Touch { return t.touch }
+
+// Points returns the points that were down at the end of the last
+// frame, ordered by ID. Events of the frame that is still in progress
+// aren't reflected until the frame has been delivered.
+func (t *Touch) Points() []TouchPoint {
+	return append([]TouchPoint(nil), t.down...)
+}
+
+func (t *Touch) snapshot() []TouchPoint {
+	out := make([]TouchPoint, 0, len(t.points))
+	for _, pt := range t.points {
+		out = append(out, *pt)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (t *Touch) point(id int32) *TouchPoint {
+	pt, ok := t.points[id]
+	if !ok {
+		// Event for a point we don't know about. This shouldn't
+		// happen, but track the point anyway.
+		pt = &TouchPoint{ID: id}
+		t.points[id] = pt
+	}
+	t.pending = true
+	return pt
+}
+
+func (t *Touch) handleDown(_ *wayland.Touch, serial uint32, ms uint32, surface *wayland.Surface, id int32, x, y wlshared.Fixed) {
+	t.time = msec(ms)
+	fx, fy := x.Float64(), y.Float64()
+	t.points[id] = &TouchPoint{
+		ID:       id,
+		Surface:  surface,
+		Serial:   serial,
+		DownTime: msec(ms),
+		X:        fx,
+		Y:        fy,
+		StartX:   fx,
+		StartY:   fy,
+		Down:     true,
+	}
+	t.pending = true
+}
+
+func (t *Touch) handleUp(_ *wayland.Touch, _ uint32, ms uint32, id int32) {
+	t.time = msec(ms)
+	t.point(id).Up = true
+}
+
+func (t *Touch) handleMotion(_ *wayland.Touch, ms uint32, id int32, x, y wlshared.Fixed) {
+	t.time = msec(ms)
+	pt := t.point(id)
+	pt.X = x.Float64()
+	pt.Y = y.Float64()
+	pt.Moved = true
+}
+
+func (t *Touch) handleShape(_ *wayland.Touch, id int32, major, minor wlshared.Fixed) {
+	pt := t.point(id)
+	pt.Major = major.Float64()
+	pt.Minor = minor.Float64()
+	pt.HasShape = true
+}
+
+func (t *Touch) handleOrientation(_ *wayland.Touch, id int32, orientation wlshared.Fixed) {
+	pt := t.point(id)
+	pt.Orientation = orientation.Float64()
+	pt.HasOrientation = true
+}
+
+func (t *Touch) handleFrame(_ *wayland.Touch) {
+	if !t.pending {
+		return
+	}
+	f := TouchFrame{
+		Time:   t.time,
+		Points: t.snapshot(),
+	}
+	t.pending = false
+	t.down = t.down[:0]
+	for _, pt := range f.Points {
+		if !pt.Up {
+			t.down = append(t.down, pt)
+		}
+	}
+	for id, pt := range t.points {
+		if pt.Up {
+			delete(t.points, id)
+		} else {
+			pt.Down = false
+			pt.Moved = false
+		}
+	}
+	if t.callback != nil {
+		t.callback(f)
+	}
+}
+
+func (t *Touch) handleCancel(_ *wayland.Touch) {
+	// No further events are sent for the current points, and cancel
+	// isn't necessarily followed by a frame.
+	for id := range t.points {
+		delete(t.points, id)
+	}
+	t.pending = false
+	t.down = nil
+	if t.callback != nil {
+		t.callback(TouchFrame{Time: t.time, Cancelled: true})
+	}
+}
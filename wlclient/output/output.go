@@ -0,0 +1,302 @@
+// Package output tracks the compositor's outputs and the outputs
+// surfaces are displayed on.
+//
+// A Tracker binds all wl_output globals via a registry.Registry. It
+// collects the properties of each output, which the compositor sends
+// as a series of events, and publishes them atomically once the
+// compositor signals that it is done. Surfaces registered with the
+// tracker have their wl_surface.enter and leave events tracked, from
+// which the tracker computes the buffer scale the surface should use.
+package output
+
+import (
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	"honnef.co/go/wayland/wlclient/registry"
+)
+
+// Info describes the state of an output.
+type Info struct {
+	// Name and Description are only provided by compositors that
+	// support version 4 of wl_output.
+	Name        string
+	Description string
+
+	// X and Y are the position of the output in the compositor's
+	// global coordinate space.
+	X, Y int32
+	// PhysicalWidth and PhysicalHeight are the size of the output in
+	// millimeters. They may be zero, for example for projectors.
+	PhysicalWidth  int32
+	PhysicalHeight int32
+	Subpixel       wayland.OutputSubpixel
+	Make           string
+	Model          string
+	Transform      wayland.OutputTransform
+
+	// Width and Height are the size of the current mode, in physical
+	// pixels. Refresh is its refresh rate in mHz, or zero.
+	Width   int32
+	Height  int32
+	Refresh int32
+
+	// Scale is the output's scale factor. It is 1 for compositors
+	// that don't support version 2 of wl_output.
+	Scale int32
+}
+
+// Output is an output bound by a Tracker.
+type Output struct {
+	Output *wayland.Output
+	// GlobalName is the name of the wl_output global.
+	GlobalName uint32
+	Version    uint32
+
+	info    Info
+	pending Info
+	ready   bool
+}
+
+// Info returns the output's state as of the most recent
+// wl_output.done event.
+func (o *Output) Info() Info { return o.info }
+
+// Events are the callbacks of a Tracker. All callbacks are optional.
+type Events struct {
+	// Added is called once the initial state of a new output is
+	// known.
+	Added func(o *Output)
+	// Changed is called when the state of an output changes.
+	Changed func(o *Output)
+	// Removed is called when an output has been unplugged.
+	Removed func(o *Output)
+}
+
+// Tracker tracks outputs and the outputs surfaces are on.
+type Tracker struct {
+	events   Events
+	outputs  map[*wayland.Output]*Output
+	surfaces map[*wayland.Surface]*surfaceState
+}
+
+type surfaceState struct {
+	outputs []*Output
+	scale   int32
+	changed func(scale int32)
+}
+
+// NewTracker returns a tracker that binds outputs using r. It has to
+// be called before r.Init, so that outputs present at startup get
+// tracked.
+func NewTracker(r *registry.Registry, events Events) *Tracker {
+	t := &Tracker{
+		events:   events,
+		outputs:  make(map[*wayland.Output]*Output),
+		surfaces: make(map[*wayland.Surface]*surfaceState),
+	}
+	registry.Multiple(r, 1, 4, t.added, t.removed)
+	return t
+}
+
+// Outputs returns all outputs whose initial state is known.
+func (t *Tracker) Outputs() []*Output {
+	out := make([]*Output, 0, len(t.outputs))
+	for _, o := range t.outputs {
+		if o.ready {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// Lookup returns the Output for a wl_output bound by the tracker.
+func (t *Tracker) Lookup(wo *wayland.Output) (*Output, bool) {
+	o, ok := t.outputs[wo]
+	return o, ok
+}
+
+func (t *Tracker) added(wo *wayland.Output, name, version uint32) {
+	o := &Output{
+		Output:     wo,
+		GlobalName: name,
+		Version:    version,
+		pending:    Info{Scale: 1},
+	}
+	t.outputs[wo] = o
+	// Before version 2 there is no done event, and every event is
+	// applied immediately.
+	apply := func() {
+		if version < 2 {
+			t.done(o)
+		}
+	}
+	wo.AddListener(wayland.OutputEvents{
+		Geometry: func(_ *wayland.Output, x, y, physicalWidth, physicalHeight int32, subpixel wayland.OutputSubpixel, make, model string, transform wayland.OutputTransform) {
+			o.pending.X = x
+			o.pending.Y = y
+			o.pending.PhysicalWidth = physicalWidth
+			o.pending.PhysicalHeight = physicalHeight
+			o.pending.Subpixel = subpixel
+			o.pending.Make = make
+			o.pending.Model = model
+			o.pending.Transform = transform
+			apply()
+		},
+		Mode: func(_ *wayland.Output, flags wayland.OutputMode, width, height, refresh int32) {
+			if flags&wayland.OutputModeCurrent == 0 {
+				return
+			}
+			o.pending.Width = width
+			o.pending.Height = height
+			o.pending.Refresh = refresh
+			apply()
+		},
+		Done: func(*wayland.Output) { t.done(o) },
+		Scale: func(_ *wayland.Output, factor int32) {
+			o.pending.Scale = factor
+		},
+		Name: func(_ *wayland.Output, name string) {
+			o.pending.Name = name
+		},
+		Description: func(_ *wayland.Output, description string) {
+			o.pending.Description = description
+		},
+	})
+}
+
+func (t *Tracker) done(o *Output) {
+	if o.ready && o.info == o.pending {
+		return
+	}
+	scaleChanged := !o.ready || o.info.Scale != o.pending.Scale
+	o.info = o.pending
+	if !o.ready {
+		o.ready = true
+		if t.events.Added != nil {
+			t.events.Added(o)
+		}
+	} else if t.events.Changed != nil {
+		t.events.Changed(o)
+	}
+	if scaleChanged {
+		t.updateScales()
+	}
+}
+
+func (t *Tracker) removed(wo *wayland.Output, _ uint32) {
+	o, ok := t.outputs[wo]
+	if !ok {
+		return
+	}
+	delete(t.outputs, wo)
+	for _, s := range t.surfaces {
+		s.remove(o)
+	}
+	t.updateScales()
+	if o.ready && t.events.Removed != nil {
+		t.events.Removed(o)
+	}
+	if o.Version >= 3 {
+		wo.Release()
+	}
+}
+
+// TrackSurface tracks the outputs surface is on. It replaces the
+// surface's listeners. Changed, which may be nil, is called whenever
+// the surface's preferred buffer scale changes.
+func (t *Tracker) TrackSurface(surface *wayland.Surface, changed func(scale int32)) {
+	s := &surfaceState{changed: changed}
+	s.scale = t.computeScale(s)
+	t.surfaces[surface] = s
+	surface.AddListener(wayland.SurfaceEvents{
+		Enter: func(_ *wayland.Surface, wo *wayland.Output) {
+			o, ok := t.outputs[wo]
+			if !ok {
+				// the output has been removed already, or was bound by
+				// somebody else
+				return
+			}
+			for _, so := range s.outputs {
+				if so == o {
+					return
+				}
+			}
+			s.outputs = append(s.outputs, o)
+			t.updateScale(s)
+		},
+		Leave: func(_ *wayland.Surface, wo *wayland.Output) {
+			if o, ok := t.outputs[wo]; ok && s.remove(o) {
+				t.updateScale(s)
+			}
+		},
+	})
+}
+
+// UntrackSurface stops tracking a surface. It should be called before
+// destroying the surface.
+func (t *Tracker) UntrackSurface(surface *wayland.Surface) {
+	delete(t.surfaces, surface)
+}
+
+// SurfaceOutputs returns the outputs a tracked surface is on.
+func (t *Tracker) SurfaceOutputs(surface *wayland.Surface) []*Output {
+	s, ok := t.surfaces[surface]
+	if !ok {
+		return nil
+	}
+	return append([]*Output(nil), s.outputs...)
+}
+
+// PreferredScale returns the buffer scale a tracked surface should
+// use, which is the largest scale of the outputs it is on. Surfaces
+// that aren't on any output yet use the largest scale of all outputs,
+// so that they don't appear blurry when they are first mapped.
+func (t *Tracker) PreferredScale(surface *wayland.Surface) int32 {
+	s, ok := t.surfaces[surface]
+	if !ok {
+		return 1
+	}
+	return s.scale
+}
+
+func (t *Tracker) computeScale(s *surfaceState) int32 {
+	outputs := s.outputs
+	if len(outputs) == 0 {
+		outputs = t.Outputs()
+	}
+	var scale int32 = 1
+	for _, o := range outputs {
+		if o.info.Scale > scale {
+			scale = o.info.Scale
+		}
+	}
+	return scale
+}
+
+func (t *Tracker) updateScales() {
+	for _, s := range t.surfaces {
+		t.updateScale(s)
+	}
+}
+
+func (t *Tracker) updateScale(s *surfaceState) {
+	scale := t.computeScale(s)
+	if scale == s.scale {
+		return
+	}
+	s.scale = scale
+	if s.changed != nil {
+		s.changed(scale)
+	}
+}
+
+// remove removes o from the surface's outputs and reports whether it
+// was present.
+func (s *surfaceState) remove(o *Output) bool {
+	for i, so := range s.outputs {
+		if so == o {
+			s.outputs = append(s.outputs[:i], s.outputs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
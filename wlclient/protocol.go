@@ -62,10 +62,12 @@ package wlclient
 
 import (
 	"encoding/binary"
+	"io"
 	"net"
+	"os"
 	"reflect"
 	"sync"
-	"syscall"
+	"time"
 	"unsafe"
 
 	"honnef.co/go/wayland/wlproto"
@@ -81,6 +83,8 @@ type Object interface {
 	EventHandlers() []interface{}
 }
 
+var objectType = reflect.TypeOf((*Object)(nil)).Elem()
+
 var byteOrder binary.ByteOrder
 
 func init() {
@@ -164,38 +168,62 @@ func (q *EventQueue) Push(ev Event) {
 	}
 }
 
+// Post queues fn to be called by Dispatch. It may be called from any
+// goroutine and can be used to run code on the goroutine that is
+// dispatching the queue, for example in response to timers.
+func (q *EventQueue) Post(fn func()) {
+	q.Push(Event{fn: fn})
+}
+
 func (q *EventQueue) Dispatch() {
 	<-q.ch
+	// Callbacks run without holding the lock, so that they can queue
+	// further events.
 	q.mu.Lock()
-	defer q.mu.Unlock()
-	for _, ev := range q.events {
+	events := q.events
+	q.events = nil
+	q.mu.Unlock()
+	for _, ev := range events {
+		if ev.fn != nil {
+			ev.fn()
+			continue
+		}
 		handlers := ev.Obj.EventHandlers()
-		cb := handlers[ev.Ev]
-		if cb != nil {
+		// Listeners that weren't set in an AddListener call are typed
+		// nil functions.
+		cb := reflect.ValueOf(handlers[ev.Ev])
+		if cb.IsValid() && !cb.IsNil() {
 			args := []reflect.Value{reflect.ValueOf(ev.Obj)}
 			args = append(args, ev.Args...)
-			reflect.ValueOf(cb).Call(args)
+			cb.Call(args)
 		}
 	}
-	q.events = q.events[:0]
 }
 
 type Event struct {
 	Obj  Object
 	Ev   int
 	Args []reflect.Value
+
+	// fn, if set, is called instead of an event handler
+	fn func()
 }
 
 type Conn struct {
-	debug        bool
 	defaultQueue *EventQueue
 
 	mu      sync.Mutex
 	rw      *net.UnixConn
+	tracer  wlshared.Tracer
 	objects map[wlshared.ObjectID]object
 	maxID   wlshared.ObjectID
 	sendBuf []byte
 
+	// err is the error that ended the connection, and done is closed
+	// once it has been set
+	err  error
+	done chan struct{}
+
 	data []byte
 	fds  []uintptr
 }
@@ -204,14 +232,46 @@ func NewConn(rw *net.UnixConn) *Conn {
 	c := &Conn{
 		rw:           rw,
 		objects:      map[wlshared.ObjectID]object{},
-		debug:        true,
 		defaultQueue: NewEventQueue(),
 		maxID:        1,
+		done:         make(chan struct{}),
+	}
+	if wlshared.DebugEnabled("client") {
+		c.tracer = wlshared.NewWriterTracer(os.Stderr)
 	}
 	go c.readLoop()
 	return c
 }
 
+// SetTracer sets the tracer that receives all requests sent and all
+// events received by the connection, replacing the one installed for
+// WAYLAND_DEBUG. A nil tracer disables tracing.
+func (c *Conn) SetTracer(t wlshared.Tracer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tracer = t
+}
+
+func (c *Conn) trace(tracer wlshared.Tracer, sent bool, obj Object, opcode int, args []interface{}) {
+	iface := obj.Interface()
+	var name string
+	var sig []wlproto.Arg
+	if sent {
+		name, sig = iface.Requests[opcode].Name, iface.Requests[opcode].Args
+	} else {
+		name, sig = iface.Events[opcode].Name, iface.Events[opcode].Args
+	}
+	tracer.Trace(&wlshared.TraceMessage{
+		Time:      time.Now(),
+		Sent:      sent,
+		Interface: iface,
+		Object:    obj.ID(),
+		Opcode:    opcode,
+		Name:      name,
+		Args:      wlshared.TraceArgs(sig, args),
+	})
+}
+
 // NewWrapper initializes the proxy in wrapper with a copy of obj's
 // proxy, but with a different queue.
 //
@@ -301,6 +361,10 @@ func (c *Conn) SendRequest(source Object, request int, args ...interface{}) {
 func (c *Conn) sendRequest(source Object, request int, args ...interface{}) {
 	for _, arg := range args {
 		if arg, ok := arg.(Object); ok {
+			if rv := reflect.ValueOf(arg); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				// null object
+				continue
+			}
 			id := arg.ID()
 			if id == 0 {
 				c.maxID++
@@ -322,47 +386,83 @@ func (c *Conn) sendRequest(source Object, request int, args ...interface{}) {
 		}
 	}
 
+	if c.tracer != nil {
+		c.trace(c.tracer, true, source, request, args)
+	}
+
 	buf := c.sendBuf[:0]
 	var oob []byte
-	buf, oob = wlshared.EncodeRequest(buf, source.ID(), request, args)
+	sig := source.Interface().Requests[request].Args
+	buf, oob = wlshared.EncodeRequest(buf, source.ID(), request, sig, args)
 
 	c.rw.WriteMsgUnix(buf, oob, nil)
 
 	c.sendBuf = buf[:0]
 }
 
-func (c *Conn) read() {
+func (c *Conn) read() error {
 	b := make([]byte, 1<<16)
-	// XXX can there be more than one SCM per message?
-	// XXX in general, be more robust in handling SCM
-	oob := make([]byte, 24)
+	oob := make([]byte, wlshared.OOBSize)
 	n, oobn, _, _, err := c.rw.ReadMsgUnix(b, oob)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	c.data = append(c.data, b[:n]...)
-	if oobn == 24 {
-		scm, err := syscall.ParseSocketControlMessage(oob[:oobn])
-		if err != nil {
-			panic(err)
-		}
-		fds, err := syscall.ParseUnixRights(&scm[0])
-		if err != nil {
-			panic(err)
-		}
-		c.fds = append(c.fds, uintptr(fds[0]))
+	fds, err := wlshared.ParseFds(oob[:oobn])
+	for _, fd := range fds {
+		c.fds = append(c.fds, uintptr(fd))
 	}
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return io.EOF
+	}
+	return nil
 }
 
-func (c *Conn) readAtLeast(n int) {
+func (c *Conn) readAtLeast(n int) error {
 	for len(c.data) < n {
-		c.read()
+		if err := c.read(); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// Done returns a channel that is closed when the connection to the
+// server has been lost, for example because the server disconnected
+// the client after a protocol error. Err returns the reason.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// Err returns the error that ended the connection, or nil if the
+// connection is still alive.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection to the server.
+func (c *Conn) Close() error {
+	return c.rw.Close()
 }
 
 func (c *Conn) readLoop() {
+	err := c.readMessages()
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// readMessages reads messages from the server and queues their events
+// until reading fails.
+func (c *Conn) readMessages() error {
 	for {
-		c.readAtLeast(8)
+		if err := c.readAtLeast(8); err != nil {
+			return err
+		}
 		sender := wlshared.ObjectID(byteOrder.Uint32(c.data[0:4]))
 		h := byteOrder.Uint32(c.data[4:8])
 		size := (h & 0xFFFF0000) >> 16
@@ -373,7 +473,12 @@ func (c *Conn) readLoop() {
 		// XXX guard against invalid opcodes
 		opcode := h & 0x0000FFFF
 		c.data = c.data[8:]
-		c.readAtLeast(int(size))
+		if err := c.readAtLeast(int(size)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
 
 		d := c.data[:size]
 		c.data = c.data[size:]
@@ -397,6 +502,7 @@ func (c *Conn) readLoop() {
 			continue
 		}
 		obj := objw.obj
+		tracer := c.tracer
 		c.mu.Unlock()
 		off := 0
 		sig := obj.Interface().Events[opcode].Args
@@ -408,9 +514,22 @@ func (c *Conn) readLoop() {
 			switch arg.Type {
 			case wlproto.ArgTypeObject:
 				c.mu.Lock()
-				// XXX guard against invalid object id
-				args[i] = reflect.ValueOf(c.objects[argv.(wlshared.ObjectID)])
+				argw, ok := c.objects[argv.(wlshared.ObjectID)]
 				c.mu.Unlock()
+				typ := objectType
+				if arg.Aux != nil {
+					typ = arg.Aux
+				}
+				if !ok || argw.kind == objectKindZombie {
+					// the object is null, unknown or has been
+					// destroyed already, see "Object deletion and races"
+					args[i] = reflect.Zero(typ)
+				} else if arg.Aux == nil {
+					args[i] = reflect.ValueOf(&argw.obj).Elem()
+				} else {
+					// XXX guard against the object having the wrong type
+					args[i] = reflect.ValueOf(argw.obj)
+				}
 			case wlproto.ArgTypeFd:
 				fd := c.fds[0]
 				copy(c.fds, c.fds[1:])
@@ -424,11 +543,20 @@ func (c *Conn) readLoop() {
 				c.mu.Lock()
 				c.objects[argv.(wlshared.ObjectID)] = object{obj: v}
 				c.mu.Unlock()
+				args[i] = reflect.ValueOf(v)
 			default:
 				args[i] = reflect.ValueOf(argv)
 			}
 		}
 
+		if tracer != nil {
+			vals := make([]interface{}, len(args))
+			for i := range args {
+				vals[i] = args[i].Interface()
+			}
+			c.trace(tracer, false, obj, int(opcode), vals)
+		}
+
 		if sender == 1 && opcode == 1 {
 			// Special case for the delete_id event on wl_display.
 			// Primarily to clean up zombies, but this event may also
@@ -440,6 +568,6 @@ func (c *Conn) readLoop() {
 			delete(c.objects, id)
 			c.mu.Unlock()
 		}
-		obj.GetProxy().queue.Push(Event{obj, int(opcode), args})
+		obj.GetProxy().queue.Push(Event{Obj: obj, Ev: int(opcode), Args: args})
 	}
 }
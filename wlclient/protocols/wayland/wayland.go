@@ -190,6 +190,33 @@ var Events = map[string]*wlproto.Event{
 	"wl_output_description":             &OutputInterface.Events[5],
 }
 
+func init() {
+	wlproto.ClientRegistry.Register(
+		DisplayInterface,
+		RegistryInterface,
+		CallbackInterface,
+		CompositorInterface,
+		ShmPoolInterface,
+		ShmInterface,
+		BufferInterface,
+		DataOfferInterface,
+		DataSourceInterface,
+		DataDeviceInterface,
+		DataDeviceManagerInterface,
+		ShellInterface,
+		ShellSurfaceInterface,
+		SurfaceInterface,
+		SeatInterface,
+		PointerInterface,
+		KeyboardInterface,
+		TouchInterface,
+		OutputInterface,
+		RegionInterface,
+		SubcompositorInterface,
+		SubsurfaceInterface,
+	)
+}
+
 // These errors are global and can be emitted in response to any
 // server request.
 type DisplayError uint32
@@ -205,6 +232,18 @@ const (
 	DisplayErrorImplementation DisplayError = 3
 )
 
+var DisplayErrorEnum = &wlproto.Enum{
+	Name: "wl_display.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_object", Value: 0},
+		{Name: "invalid_method", Value: 1},
+		{Name: "no_memory", Value: 2},
+		{Name: "implementation", Value: 3},
+	},
+}
+
+func (v DisplayError) String() string { return DisplayErrorEnum.Format(uint32(v)) }
+
 var DisplayInterface = &wlproto.Interface{
 	Name:    "wl_display",
 	Version: 1,
@@ -497,7 +536,7 @@ var ShmPoolInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0)), Enum: ShmFormatEnum},
 			},
 		},
 		{
@@ -589,6 +628,17 @@ const (
 	ShmErrorInvalidFd ShmError = 2
 )
 
+var ShmErrorEnum = &wlproto.Enum{
+	Name: "wl_shm.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_format", Value: 0},
+		{Name: "invalid_stride", Value: 1},
+		{Name: "invalid_fd", Value: 2},
+	},
+}
+
+func (v ShmError) String() string { return ShmErrorEnum.Format(uint32(v)) }
+
 // This describes the memory layout of an individual pixel.
 //
 // All renderers should support argb8888 and xrgb8888 but any other
@@ -810,6 +860,122 @@ const (
 	ShmFormatAbgr16161616 ShmFormat = 0x38344241
 )
 
+var ShmFormatEnum = &wlproto.Enum{
+	Name: "wl_shm.format",
+	Entries: []wlproto.EnumEntry{
+		{Name: "argb8888", Value: 0},
+		{Name: "xrgb8888", Value: 1},
+		{Name: "c8", Value: 0x20203843},
+		{Name: "rgb332", Value: 0x38424752},
+		{Name: "bgr233", Value: 0x38524742},
+		{Name: "xrgb4444", Value: 0x32315258},
+		{Name: "xbgr4444", Value: 0x32314258},
+		{Name: "rgbx4444", Value: 0x32315852},
+		{Name: "bgrx4444", Value: 0x32315842},
+		{Name: "argb4444", Value: 0x32315241},
+		{Name: "abgr4444", Value: 0x32314241},
+		{Name: "rgba4444", Value: 0x32314152},
+		{Name: "bgra4444", Value: 0x32314142},
+		{Name: "xrgb1555", Value: 0x35315258},
+		{Name: "xbgr1555", Value: 0x35314258},
+		{Name: "rgbx5551", Value: 0x35315852},
+		{Name: "bgrx5551", Value: 0x35315842},
+		{Name: "argb1555", Value: 0x35315241},
+		{Name: "abgr1555", Value: 0x35314241},
+		{Name: "rgba5551", Value: 0x35314152},
+		{Name: "bgra5551", Value: 0x35314142},
+		{Name: "rgb565", Value: 0x36314752},
+		{Name: "bgr565", Value: 0x36314742},
+		{Name: "rgb888", Value: 0x34324752},
+		{Name: "bgr888", Value: 0x34324742},
+		{Name: "xbgr8888", Value: 0x34324258},
+		{Name: "rgbx8888", Value: 0x34325852},
+		{Name: "bgrx8888", Value: 0x34325842},
+		{Name: "abgr8888", Value: 0x34324241},
+		{Name: "rgba8888", Value: 0x34324152},
+		{Name: "bgra8888", Value: 0x34324142},
+		{Name: "xrgb2101010", Value: 0x30335258},
+		{Name: "xbgr2101010", Value: 0x30334258},
+		{Name: "rgbx1010102", Value: 0x30335852},
+		{Name: "bgrx1010102", Value: 0x30335842},
+		{Name: "argb2101010", Value: 0x30335241},
+		{Name: "abgr2101010", Value: 0x30334241},
+		{Name: "rgba1010102", Value: 0x30334152},
+		{Name: "bgra1010102", Value: 0x30334142},
+		{Name: "yuyv", Value: 0x56595559},
+		{Name: "yvyu", Value: 0x55595659},
+		{Name: "uyvy", Value: 0x59565955},
+		{Name: "vyuy", Value: 0x59555956},
+		{Name: "ayuv", Value: 0x56555941},
+		{Name: "nv12", Value: 0x3231564e},
+		{Name: "nv21", Value: 0x3132564e},
+		{Name: "nv16", Value: 0x3631564e},
+		{Name: "nv61", Value: 0x3136564e},
+		{Name: "yuv410", Value: 0x39565559},
+		{Name: "yvu410", Value: 0x39555659},
+		{Name: "yuv411", Value: 0x31315559},
+		{Name: "yvu411", Value: 0x31315659},
+		{Name: "yuv420", Value: 0x32315559},
+		{Name: "yvu420", Value: 0x32315659},
+		{Name: "yuv422", Value: 0x36315559},
+		{Name: "yvu422", Value: 0x36315659},
+		{Name: "yuv444", Value: 0x34325559},
+		{Name: "yvu444", Value: 0x34325659},
+		{Name: "r8", Value: 0x20203852},
+		{Name: "r16", Value: 0x20363152},
+		{Name: "rg88", Value: 0x38384752},
+		{Name: "gr88", Value: 0x38385247},
+		{Name: "rg1616", Value: 0x32334752},
+		{Name: "gr1616", Value: 0x32335247},
+		{Name: "xrgb16161616f", Value: 0x48345258},
+		{Name: "xbgr16161616f", Value: 0x48344258},
+		{Name: "argb16161616f", Value: 0x48345241},
+		{Name: "abgr16161616f", Value: 0x48344241},
+		{Name: "xyuv8888", Value: 0x56555958},
+		{Name: "vuy888", Value: 0x34325556},
+		{Name: "vuy101010", Value: 0x30335556},
+		{Name: "y210", Value: 0x30313259},
+		{Name: "y212", Value: 0x32313259},
+		{Name: "y216", Value: 0x36313259},
+		{Name: "y410", Value: 0x30313459},
+		{Name: "y412", Value: 0x32313459},
+		{Name: "y416", Value: 0x36313459},
+		{Name: "xvyu2101010", Value: 0x30335658},
+		{Name: "xvyu12_16161616", Value: 0x36335658},
+		{Name: "xvyu16161616", Value: 0x38345658},
+		{Name: "y0l0", Value: 0x304c3059},
+		{Name: "x0l0", Value: 0x304c3058},
+		{Name: "y0l2", Value: 0x324c3059},
+		{Name: "x0l2", Value: 0x324c3058},
+		{Name: "yuv420_8bit", Value: 0x38305559},
+		{Name: "yuv420_10bit", Value: 0x30315559},
+		{Name: "xrgb8888_a8", Value: 0x38415258},
+		{Name: "xbgr8888_a8", Value: 0x38414258},
+		{Name: "rgbx8888_a8", Value: 0x38415852},
+		{Name: "bgrx8888_a8", Value: 0x38415842},
+		{Name: "rgb888_a8", Value: 0x38413852},
+		{Name: "bgr888_a8", Value: 0x38413842},
+		{Name: "rgb565_a8", Value: 0x38413552},
+		{Name: "bgr565_a8", Value: 0x38413542},
+		{Name: "nv24", Value: 0x3432564e},
+		{Name: "nv42", Value: 0x3234564e},
+		{Name: "p210", Value: 0x30313250},
+		{Name: "p010", Value: 0x30313050},
+		{Name: "p012", Value: 0x32313050},
+		{Name: "p016", Value: 0x36313050},
+		{Name: "axbxgxrx106106106106", Value: 0x30314241},
+		{Name: "nv15", Value: 0x3531564e},
+		{Name: "q410", Value: 0x30313451},
+		{Name: "q401", Value: 0x31303451},
+		{Name: "xrgb16161616", Value: 0x38345258},
+		{Name: "xbgr16161616", Value: 0x38344258},
+		{Name: "argb16161616", Value: 0x38345241},
+		{Name: "abgr16161616", Value: 0x38344241},
+	},
+}
+
+func (v ShmFormat) String() string { return ShmFormatEnum.Format(uint32(v)) }
+
 var ShmInterface = &wlproto.Interface{
 	Name:    "wl_shm",
 	Version: 1,
@@ -830,7 +996,7 @@ var ShmInterface = &wlproto.Interface{
 			Name:  "format",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0)), Enum: ShmFormatEnum},
 			},
 		},
 	},
@@ -946,6 +1112,18 @@ const (
 	DataOfferErrorInvalidOffer DataOfferError = 3
 )
 
+var DataOfferErrorEnum = &wlproto.Enum{
+	Name: "wl_data_offer.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_finish", Value: 0},
+		{Name: "invalid_action_mask", Value: 1},
+		{Name: "invalid_action", Value: 2},
+		{Name: "invalid_offer", Value: 3},
+	},
+}
+
+func (v DataOfferError) String() string { return DataOfferErrorEnum.Format(uint32(v)) }
+
 var DataOfferInterface = &wlproto.Interface{
 	Name:    "wl_data_offer",
 	Version: 3,
@@ -956,7 +1134,7 @@ var DataOfferInterface = &wlproto.Interface{
 			Since: 1,
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeString},
+				{Type: wlproto.ArgTypeString, AllowNull: true},
 			},
 		},
 		{
@@ -985,8 +1163,8 @@ var DataOfferInterface = &wlproto.Interface{
 			Type:  "",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -1002,14 +1180,14 @@ var DataOfferInterface = &wlproto.Interface{
 			Name:  "source_actions",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 		{
 			Name:  "action",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -1145,6 +1323,16 @@ const (
 	DataSourceErrorInvalidSource DataSourceError = 1
 )
 
+var DataSourceErrorEnum = &wlproto.Enum{
+	Name: "wl_data_source.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_action_mask", Value: 0},
+		{Name: "invalid_source", Value: 1},
+	},
+}
+
+func (v DataSourceError) String() string { return DataSourceErrorEnum.Format(uint32(v)) }
+
 var DataSourceInterface = &wlproto.Interface{
 	Name:    "wl_data_source",
 	Version: 3,
@@ -1168,7 +1356,7 @@ var DataSourceInterface = &wlproto.Interface{
 			Type:  "",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -1177,7 +1365,7 @@ var DataSourceInterface = &wlproto.Interface{
 			Name:  "target",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeString},
+				{Type: wlproto.ArgTypeString, AllowNull: true},
 			},
 		},
 		{
@@ -1207,7 +1395,7 @@ var DataSourceInterface = &wlproto.Interface{
 			Name:  "action",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -1276,6 +1464,15 @@ const (
 	DataDeviceErrorRole DataDeviceError = 0
 )
 
+var DataDeviceErrorEnum = &wlproto.Enum{
+	Name: "wl_data_device.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+	},
+}
+
+func (v DataDeviceError) String() string { return DataDeviceErrorEnum.Format(uint32(v)) }
+
 var DataDeviceInterface = &wlproto.Interface{
 	Name:    "wl_data_device",
 	Version: 3,
@@ -1466,6 +1663,21 @@ const (
 	DataDeviceManagerDndActionAsk DataDeviceManagerDndAction = 4
 )
 
+var DataDeviceManagerDndActionEnum = &wlproto.Enum{
+	Name:     "wl_data_device_manager.dnd_action",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "copy", Value: 1},
+		{Name: "move", Value: 2},
+		{Name: "ask", Value: 4},
+	},
+}
+
+func (v DataDeviceManagerDndAction) String() string {
+	return DataDeviceManagerDndActionEnum.Format(uint32(v))
+}
+
 var DataDeviceManagerInterface = &wlproto.Interface{
 	Name:    "wl_data_device_manager",
 	Version: 3,
@@ -1543,6 +1755,15 @@ const (
 	ShellErrorRole ShellError = 0
 )
 
+var ShellErrorEnum = &wlproto.Enum{
+	Name: "wl_shell.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+	},
+}
+
+func (v ShellError) String() string { return ShellErrorEnum.Format(uint32(v)) }
+
 var ShellInterface = &wlproto.Interface{
 	Name:    "wl_shell",
 	Version: 1,
@@ -1626,6 +1847,24 @@ const (
 	ShellSurfaceResizeBottomRight ShellSurfaceResize = 10
 )
 
+var ShellSurfaceResizeEnum = &wlproto.Enum{
+	Name:     "wl_shell_surface.resize",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "right", Value: 8},
+		{Name: "top_right", Value: 9},
+		{Name: "bottom_right", Value: 10},
+	},
+}
+
+func (v ShellSurfaceResize) String() string { return ShellSurfaceResizeEnum.Format(uint32(v)) }
+
 // These flags specify details of the expected behaviour
 // of transient surfaces. Used in the set_transient request.
 type ShellSurfaceTransient uint32
@@ -1635,6 +1874,16 @@ const (
 	ShellSurfaceTransientInactive ShellSurfaceTransient = 0x1
 )
 
+var ShellSurfaceTransientEnum = &wlproto.Enum{
+	Name:     "wl_shell_surface.transient",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "inactive", Value: 0x1},
+	},
+}
+
+func (v ShellSurfaceTransient) String() string { return ShellSurfaceTransientEnum.Format(uint32(v)) }
+
 // Hints to indicate to the compositor how to deal with a conflict
 // between the dimensions of the surface and the dimensions of the
 // output. The compositor is free to ignore this parameter.
@@ -1651,6 +1900,20 @@ const (
 	ShellSurfaceFullscreenMethodFill ShellSurfaceFullscreenMethod = 3
 )
 
+var ShellSurfaceFullscreenMethodEnum = &wlproto.Enum{
+	Name: "wl_shell_surface.fullscreen_method",
+	Entries: []wlproto.EnumEntry{
+		{Name: "default", Value: 0},
+		{Name: "scale", Value: 1},
+		{Name: "driver", Value: 2},
+		{Name: "fill", Value: 3},
+	},
+}
+
+func (v ShellSurfaceFullscreenMethod) String() string {
+	return ShellSurfaceFullscreenMethodEnum.Format(uint32(v))
+}
+
 var ShellSurfaceInterface = &wlproto.Interface{
 	Name:    "wl_shell_surface",
 	Version: 1,
@@ -1679,7 +1942,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf((*Seat)(nil))},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0)), Enum: ShellSurfaceResizeEnum},
 			},
 		},
 		{
@@ -1696,7 +1959,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf((*Surface)(nil))},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0)), Enum: ShellSurfaceTransientEnum},
 			},
 		},
 		{
@@ -1704,7 +1967,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 			Type:  "",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceFullscreenMethod(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceFullscreenMethod(0)), Enum: ShellSurfaceFullscreenMethodEnum},
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf((*Output)(nil))},
 			},
@@ -1719,7 +1982,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf((*Surface)(nil))},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0)), Enum: ShellSurfaceTransientEnum},
 			},
 		},
 		{
@@ -1759,7 +2022,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 			Name:  "configure",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0)), Enum: ShellSurfaceResizeEnum},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 			},
@@ -1964,6 +2227,18 @@ const (
 	SurfaceErrorInvalidOffset SurfaceError = 3
 )
 
+var SurfaceErrorEnum = &wlproto.Enum{
+	Name: "wl_surface.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_scale", Value: 0},
+		{Name: "invalid_transform", Value: 1},
+		{Name: "invalid_size", Value: 2},
+		{Name: "invalid_offset", Value: 3},
+	},
+}
+
+func (v SurfaceError) String() string { return SurfaceErrorEnum.Format(uint32(v)) }
+
 var SurfaceInterface = &wlproto.Interface{
 	Name:    "wl_surface",
 	Version: 5,
@@ -2030,7 +2305,7 @@ var SurfaceInterface = &wlproto.Interface{
 			Type:  "",
 			Since: 2,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0))},
+				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0)), Enum: OutputTransformEnum},
 			},
 		},
 		{
@@ -2469,6 +2744,18 @@ const (
 	SeatCapabilityTouch SeatCapability = 4
 )
 
+var SeatCapabilityEnum = &wlproto.Enum{
+	Name:     "wl_seat.capability",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "pointer", Value: 1},
+		{Name: "keyboard", Value: 2},
+		{Name: "touch", Value: 4},
+	},
+}
+
+func (v SeatCapability) String() string { return SeatCapabilityEnum.Format(uint32(v)) }
+
 // These errors can be emitted in response to wl_seat requests.
 type SeatError uint32
 
@@ -2477,6 +2764,15 @@ const (
 	SeatErrorMissingCapability SeatError = 0
 )
 
+var SeatErrorEnum = &wlproto.Enum{
+	Name: "wl_seat.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "missing_capability", Value: 0},
+	},
+}
+
+func (v SeatError) String() string { return SeatErrorEnum.Format(uint32(v)) }
+
 var SeatInterface = &wlproto.Interface{
 	Name:    "wl_seat",
 	Version: 7,
@@ -2517,7 +2813,7 @@ var SeatInterface = &wlproto.Interface{
 			Name:  "capabilities",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(SeatCapability(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(SeatCapability(0)), Enum: SeatCapabilityEnum},
 			},
 		},
 		{
@@ -2613,6 +2909,15 @@ const (
 	PointerErrorRole PointerError = 0
 )
 
+var PointerErrorEnum = &wlproto.Enum{
+	Name: "wl_pointer.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+	},
+}
+
+func (v PointerError) String() string { return PointerErrorEnum.Format(uint32(v)) }
+
 // Describes the physical state of a button that produced the button
 // event.
 type PointerButtonState uint32
@@ -2624,6 +2929,16 @@ const (
 	PointerButtonStatePressed PointerButtonState = 1
 )
 
+var PointerButtonStateEnum = &wlproto.Enum{
+	Name: "wl_pointer.button_state",
+	Entries: []wlproto.EnumEntry{
+		{Name: "released", Value: 0},
+		{Name: "pressed", Value: 1},
+	},
+}
+
+func (v PointerButtonState) String() string { return PointerButtonStateEnum.Format(uint32(v)) }
+
 // Describes the axis types of scroll events.
 type PointerAxis uint32
 
@@ -2634,6 +2949,16 @@ const (
 	PointerAxisHorizontalScroll PointerAxis = 1
 )
 
+var PointerAxisEnum = &wlproto.Enum{
+	Name: "wl_pointer.axis",
+	Entries: []wlproto.EnumEntry{
+		{Name: "vertical_scroll", Value: 0},
+		{Name: "horizontal_scroll", Value: 1},
+	},
+}
+
+func (v PointerAxis) String() string { return PointerAxisEnum.Format(uint32(v)) }
+
 // Describes the source types for axis events. This indicates to the
 // client how an axis event was physically generated; a client may
 // adjust the user interface accordingly. For example, scroll events
@@ -2663,6 +2988,18 @@ const (
 	PointerAxisSourceWheelTilt PointerAxisSource = 3
 )
 
+var PointerAxisSourceEnum = &wlproto.Enum{
+	Name: "wl_pointer.axis_source",
+	Entries: []wlproto.EnumEntry{
+		{Name: "wheel", Value: 0},
+		{Name: "finger", Value: 1},
+		{Name: "continuous", Value: 2},
+		{Name: "wheel_tilt", Value: 3},
+	},
+}
+
+func (v PointerAxisSource) String() string { return PointerAxisSourceEnum.Format(uint32(v)) }
+
 var PointerInterface = &wlproto.Interface{
 	Name:    "wl_pointer",
 	Version: 7,
@@ -2720,7 +3057,7 @@ var PointerInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerButtonState(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerButtonState(0)), Enum: PointerButtonStateEnum},
 			},
 		},
 		{
@@ -2728,7 +3065,7 @@ var PointerInterface = &wlproto.Interface{
 			Since: 1,
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0)), Enum: PointerAxisEnum},
 				{Type: wlproto.ArgTypeFixed},
 			},
 		},
@@ -2741,7 +3078,7 @@ var PointerInterface = &wlproto.Interface{
 			Name:  "axis_source",
 			Since: 5,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxisSource(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxisSource(0)), Enum: PointerAxisSourceEnum},
 			},
 		},
 		{
@@ -2749,14 +3086,14 @@ var PointerInterface = &wlproto.Interface{
 			Since: 5,
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0)), Enum: PointerAxisEnum},
 			},
 		},
 		{
 			Name:  "axis_discrete",
 			Since: 5,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0)), Enum: PointerAxisEnum},
 				{Type: wlproto.ArgTypeInt},
 			},
 		},
@@ -2858,6 +3195,16 @@ const (
 	KeyboardKeymapFormatXkbV1 KeyboardKeymapFormat = 1
 )
 
+var KeyboardKeymapFormatEnum = &wlproto.Enum{
+	Name: "wl_keyboard.keymap_format",
+	Entries: []wlproto.EnumEntry{
+		{Name: "no_keymap", Value: 0},
+		{Name: "xkb_v1", Value: 1},
+	},
+}
+
+func (v KeyboardKeymapFormat) String() string { return KeyboardKeymapFormatEnum.Format(uint32(v)) }
+
 // Describes the physical state of a key that produced the key event.
 type KeyboardKeyState uint32
 
@@ -2868,6 +3215,16 @@ const (
 	KeyboardKeyStatePressed KeyboardKeyState = 1
 )
 
+var KeyboardKeyStateEnum = &wlproto.Enum{
+	Name: "wl_keyboard.key_state",
+	Entries: []wlproto.EnumEntry{
+		{Name: "released", Value: 0},
+		{Name: "pressed", Value: 1},
+	},
+}
+
+func (v KeyboardKeyState) String() string { return KeyboardKeyStateEnum.Format(uint32(v)) }
+
 var KeyboardInterface = &wlproto.Interface{
 	Name:    "wl_keyboard",
 	Version: 7,
@@ -2884,7 +3241,7 @@ var KeyboardInterface = &wlproto.Interface{
 			Name:  "keymap",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeymapFormat(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeymapFormat(0)), Enum: KeyboardKeymapFormatEnum},
 				{Type: wlproto.ArgTypeFd},
 				{Type: wlproto.ArgTypeUint},
 			},
@@ -2913,7 +3270,7 @@ var KeyboardInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeyState(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeyState(0)), Enum: KeyboardKeyStateEnum},
 			},
 		},
 		{
@@ -3101,6 +3458,20 @@ const (
 	OutputSubpixelVerticalBgr OutputSubpixel = 5
 )
 
+var OutputSubpixelEnum = &wlproto.Enum{
+	Name: "wl_output.subpixel",
+	Entries: []wlproto.EnumEntry{
+		{Name: "unknown", Value: 0},
+		{Name: "none", Value: 1},
+		{Name: "horizontal_rgb", Value: 2},
+		{Name: "horizontal_bgr", Value: 3},
+		{Name: "vertical_rgb", Value: 4},
+		{Name: "vertical_bgr", Value: 5},
+	},
+}
+
+func (v OutputSubpixel) String() string { return OutputSubpixelEnum.Format(uint32(v)) }
+
 // This describes the transform that a compositor will apply to a
 // surface to compensate for the rotation or mirroring of an
 // output device.
@@ -3133,6 +3504,22 @@ const (
 	OutputTransformFlipped270 OutputTransform = 7
 )
 
+var OutputTransformEnum = &wlproto.Enum{
+	Name: "wl_output.transform",
+	Entries: []wlproto.EnumEntry{
+		{Name: "normal", Value: 0},
+		{Name: "90", Value: 1},
+		{Name: "180", Value: 2},
+		{Name: "270", Value: 3},
+		{Name: "flipped", Value: 4},
+		{Name: "flipped_90", Value: 5},
+		{Name: "flipped_180", Value: 6},
+		{Name: "flipped_270", Value: 7},
+	},
+}
+
+func (v OutputTransform) String() string { return OutputTransformEnum.Format(uint32(v)) }
+
 // These flags describe properties of an output mode.
 // They are used in the flags bitfield of the mode event.
 type OutputMode uint32
@@ -3144,6 +3531,17 @@ const (
 	OutputModePreferred OutputMode = 0x2
 )
 
+var OutputModeEnum = &wlproto.Enum{
+	Name:     "wl_output.mode",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "current", Value: 0x1},
+		{Name: "preferred", Value: 0x2},
+	},
+}
+
+func (v OutputMode) String() string { return OutputModeEnum.Format(uint32(v)) }
+
 var OutputInterface = &wlproto.Interface{
 	Name:    "wl_output",
 	Version: 4,
@@ -3164,17 +3562,17 @@ var OutputInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputSubpixel(0))},
+				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputSubpixel(0)), Enum: OutputSubpixelEnum},
 				{Type: wlproto.ArgTypeString},
 				{Type: wlproto.ArgTypeString},
-				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0))},
+				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0)), Enum: OutputTransformEnum},
 			},
 		},
 		{
 			Name:  "mode",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(OutputMode(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(OutputMode(0)), Enum: OutputModeEnum},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
@@ -3325,6 +3723,15 @@ const (
 	SubcompositorErrorBadSurface SubcompositorError = 0
 )
 
+var SubcompositorErrorEnum = &wlproto.Enum{
+	Name: "wl_subcompositor.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "bad_surface", Value: 0},
+	},
+}
+
+func (v SubcompositorError) String() string { return SubcompositorErrorEnum.Format(uint32(v)) }
+
 var SubcompositorInterface = &wlproto.Interface{
 	Name:    "wl_subcompositor",
 	Version: 1,
@@ -3421,6 +3828,15 @@ const (
 	SubsurfaceErrorBadSurface SubsurfaceError = 0
 )
 
+var SubsurfaceErrorEnum = &wlproto.Enum{
+	Name: "wl_subsurface.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "bad_surface", Value: 0},
+	},
+}
+
+func (v SubsurfaceError) String() string { return SubsurfaceErrorEnum.Format(uint32(v)) }
+
 var SubsurfaceInterface = &wlproto.Interface{
 	Name:    "wl_subsurface",
 	Version: 1,
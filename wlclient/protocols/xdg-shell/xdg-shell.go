@@ -79,6 +79,16 @@ var Events = map[string]*wlproto.Event{
 	"xdg_popup_repositioned":        &PopupInterface.Events[2],
 }
 
+func init() {
+	wlproto.ClientRegistry.Register(
+		WmBaseInterface,
+		PositionerInterface,
+		SurfaceInterface,
+		ToplevelInterface,
+		PopupInterface,
+	)
+}
+
 type WmBaseError uint32
 
 const (
@@ -96,6 +106,20 @@ const (
 	WmBaseErrorInvalidPositioner WmBaseError = 5
 )
 
+var WmBaseErrorEnum = &wlproto.Enum{
+	Name: "xdg_wm_base.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+		{Name: "defunct_surfaces", Value: 1},
+		{Name: "not_the_topmost_popup", Value: 2},
+		{Name: "invalid_popup_parent", Value: 3},
+		{Name: "invalid_surface_state", Value: 4},
+		{Name: "invalid_positioner", Value: 5},
+	},
+}
+
+func (v WmBaseError) String() string { return WmBaseErrorEnum.Format(uint32(v)) }
+
 var WmBaseInterface = &wlproto.Interface{
 	Name:    "xdg_wm_base",
 	Version: 4,
@@ -218,6 +242,15 @@ const (
 	PositionerErrorInvalidInput PositionerError = 0
 )
 
+var PositionerErrorEnum = &wlproto.Enum{
+	Name: "xdg_positioner.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_input", Value: 0},
+	},
+}
+
+func (v PositionerError) String() string { return PositionerErrorEnum.Format(uint32(v)) }
+
 type PositionerAnchor uint32
 
 const (
@@ -232,6 +265,23 @@ const (
 	PositionerAnchorBottomRight PositionerAnchor = 8
 )
 
+var PositionerAnchorEnum = &wlproto.Enum{
+	Name: "xdg_positioner.anchor",
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 3},
+		{Name: "right", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "top_right", Value: 7},
+		{Name: "bottom_right", Value: 8},
+	},
+}
+
+func (v PositionerAnchor) String() string { return PositionerAnchorEnum.Format(uint32(v)) }
+
 type PositionerGravity uint32
 
 const (
@@ -246,6 +296,23 @@ const (
 	PositionerGravityBottomRight PositionerGravity = 8
 )
 
+var PositionerGravityEnum = &wlproto.Enum{
+	Name: "xdg_positioner.gravity",
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 3},
+		{Name: "right", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "top_right", Value: 7},
+		{Name: "bottom_right", Value: 8},
+	},
+}
+
+func (v PositionerGravity) String() string { return PositionerGravityEnum.Format(uint32(v)) }
+
 // The constraint adjustment value define ways the compositor will adjust
 // the position of the surface, if the unadjusted position would result
 // in the surface being partly constrained.
@@ -316,6 +383,24 @@ const (
 	PositionerConstraintAdjustmentResizeY PositionerConstraintAdjustment = 32
 )
 
+var PositionerConstraintAdjustmentEnum = &wlproto.Enum{
+	Name:     "xdg_positioner.constraint_adjustment",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "slide_x", Value: 1},
+		{Name: "slide_y", Value: 2},
+		{Name: "flip_x", Value: 4},
+		{Name: "flip_y", Value: 8},
+		{Name: "resize_x", Value: 16},
+		{Name: "resize_y", Value: 32},
+	},
+}
+
+func (v PositionerConstraintAdjustment) String() string {
+	return PositionerConstraintAdjustmentEnum.Format(uint32(v))
+}
+
 var PositionerInterface = &wlproto.Interface{
 	Name:    "xdg_positioner",
 	Version: 4,
@@ -351,7 +436,7 @@ var PositionerInterface = &wlproto.Interface{
 			Type:  "",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerAnchor(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerAnchor(0)), Enum: PositionerAnchorEnum},
 			},
 		},
 		{
@@ -359,7 +444,7 @@ var PositionerInterface = &wlproto.Interface{
 			Type:  "",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerGravity(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerGravity(0)), Enum: PositionerGravityEnum},
 			},
 		},
 		{
@@ -559,6 +644,17 @@ const (
 	SurfaceErrorUnconfiguredBuffer SurfaceError = 3
 )
 
+var SurfaceErrorEnum = &wlproto.Enum{
+	Name: "xdg_surface.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "not_constructed", Value: 1},
+		{Name: "already_constructed", Value: 2},
+		{Name: "unconfigured_buffer", Value: 3},
+	},
+}
+
+func (v SurfaceError) String() string { return SurfaceErrorEnum.Format(uint32(v)) }
+
 var SurfaceInterface = &wlproto.Interface{
 	Name:    "xdg_surface",
 	Version: 4,
@@ -777,6 +873,15 @@ const (
 	ToplevelErrorInvalidResizeEdge ToplevelError = 0
 )
 
+var ToplevelErrorEnum = &wlproto.Enum{
+	Name: "xdg_toplevel.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_resize_edge", Value: 0},
+	},
+}
+
+func (v ToplevelError) String() string { return ToplevelErrorEnum.Format(uint32(v)) }
+
 // These values are used to indicate which edge of a surface
 // is being dragged in a resize operation.
 type ToplevelResizeEdge uint32
@@ -793,6 +898,23 @@ const (
 	ToplevelResizeEdgeBottomRight ToplevelResizeEdge = 10
 )
 
+var ToplevelResizeEdgeEnum = &wlproto.Enum{
+	Name: "xdg_toplevel.resize_edge",
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "right", Value: 8},
+		{Name: "top_right", Value: 9},
+		{Name: "bottom_right", Value: 10},
+	},
+}
+
+func (v ToplevelResizeEdge) String() string { return ToplevelResizeEdgeEnum.Format(uint32(v)) }
+
 // The different state values used on the surface. This is designed for
 // state values like maximized, fullscreen. It is paired with the
 // configure event to ensure that both the client and the compositor
@@ -838,6 +960,22 @@ const (
 	ToplevelStateTiledBottom ToplevelState = 8
 )
 
+var ToplevelStateEnum = &wlproto.Enum{
+	Name: "xdg_toplevel.state",
+	Entries: []wlproto.EnumEntry{
+		{Name: "maximized", Value: 1},
+		{Name: "fullscreen", Value: 2},
+		{Name: "resizing", Value: 3},
+		{Name: "activated", Value: 4},
+		{Name: "tiled_left", Value: 5},
+		{Name: "tiled_right", Value: 6},
+		{Name: "tiled_top", Value: 7},
+		{Name: "tiled_bottom", Value: 8},
+	},
+}
+
+func (v ToplevelState) String() string { return ToplevelStateEnum.Format(uint32(v)) }
+
 var ToplevelInterface = &wlproto.Interface{
 	Name:    "xdg_toplevel",
 	Version: 4,
@@ -899,7 +1037,7 @@ var ToplevelInterface = &wlproto.Interface{
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf((*wayland.Seat)(nil))},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ToplevelResizeEdge(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ToplevelResizeEdge(0)), Enum: ToplevelResizeEdgeEnum},
 			},
 		},
 		{
@@ -1341,6 +1479,15 @@ const (
 	PopupErrorInvalidGrab PopupError = 0
 )
 
+var PopupErrorEnum = &wlproto.Enum{
+	Name: "xdg_popup.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_grab", Value: 0},
+	},
+}
+
+func (v PopupError) String() string { return PopupErrorEnum.Format(uint32(v)) }
+
 var PopupInterface = &wlproto.Interface{
 	Name:    "xdg_popup",
 	Version: 4,
@@ -0,0 +1,330 @@
+// Package registry binds globals advertised by the compositor.
+//
+// Instead of switching on interface names in a wl_registry.global
+// handler, clients declare which globals they want and which versions
+// they support:
+//
+//	reg := registry.New(display)
+//	var compositor *wayland.Compositor
+//	registry.Require(reg, &compositor, 4, 0, nil)
+//	registry.Multiple(reg, 1, 4, func(out *wayland.Output, name, version uint32) { ... }, nil)
+//	if err := reg.Init(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Globals are bound at the highest version supported by both the
+// client and the compositor.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"honnef.co/go/wayland/wlclient"
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+)
+
+// Global describes a global advertised by the compositor.
+type Global struct {
+	Name      uint32
+	Interface string
+	Version   uint32
+}
+
+// Registry tracks the compositor's globals and binds the ones the
+// client is interested in.
+type Registry struct {
+	display  *wayland.Display
+	registry *wayland.Registry
+	globals  map[uint32]Global
+	wants    map[string]*want
+	// bound maps global names to the wants that bound them
+	bound map[uint32]*want
+}
+
+type want struct {
+	iface    string
+	min, max uint32
+	required bool
+	multiple bool
+	// found is set once a singleton has been bound
+	found  bool
+	bind   func(name, version uint32)
+	remove func(name uint32)
+}
+
+// object is the constraint satisfied by pointers to generated
+// interface types, such as *wayland.Compositor.
+type object[E any] interface {
+	*E
+	wlclient.Object
+}
+
+// New returns a registry for the display. The wl_registry object uses
+// the display's queue.
+func New(display *wayland.Display) *Registry {
+	r := &Registry{
+		display: display,
+		globals: map[uint32]Global{},
+		wants:   map[string]*want{},
+		bound:   map[uint32]*want{},
+	}
+	r.registry = display.GetRegistry()
+	r.registry.AddListener(wayland.RegistryEvents{
+		Global:       r.global,
+		GlobalRemove: r.globalRemove,
+	})
+	return r
+}
+
+// Registry returns the underlying wl_registry object.
+func (r *Registry) Registry() *wayland.Registry { return r.registry }
+
+// Globals returns all globals currently advertised by the compositor,
+// sorted by name.
+func (r *Registry) Globals() []Global {
+	out := make([]Global, 0, len(r.globals))
+	for _, g := range r.globals {
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (r *Registry) global(_ *wayland.Registry, name uint32, iface string, version uint32) {
+	r.globals[name] = Global{name, iface, version}
+
+	w, ok := r.wants[iface]
+	if !ok || version < w.min || (w.found && !w.multiple) {
+		return
+	}
+	if version > w.max {
+		version = w.max
+	}
+	w.found = true
+	r.bound[name] = w
+	w.bind(name, version)
+}
+
+func (r *Registry) globalRemove(_ *wayland.Registry, name uint32) {
+	delete(r.globals, name)
+	w, ok := r.bound[name]
+	if !ok {
+		return
+	}
+	delete(r.bound, name)
+	if w.remove != nil {
+		w.remove(name)
+	}
+	if !w.multiple {
+		// Fall back to another instance of the singleton, if the
+		// compositor advertises one.
+		w.found = false
+		for _, g := range r.Globals() {
+			if g.Interface == w.iface {
+				r.global(r.registry, g.Name, g.Interface, g.Version)
+				if w.found {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (r *Registry) add(obj wlclient.Object, w *want) {
+	if w.max == 0 || w.max > obj.Interface().Version {
+		w.max = obj.Interface().Version
+	}
+	if w.min == 0 {
+		w.min = 1
+	}
+	w.iface = obj.Interface().Name
+	if _, ok := r.wants[w.iface]; ok {
+		panic(fmt.Sprintf("interface %s requested more than once", w.iface))
+	}
+	r.wants[w.iface] = w
+}
+
+func singleton[E any, T object[E]](r *Registry, dst *T, min, max uint32, required bool, bound func(obj T, version uint32)) {
+	var boundName uint32
+	w := &want{
+		min:      min,
+		max:      max,
+		required: required,
+		bind: func(name, version uint32) {
+			obj := T(new(E))
+			r.registry.Bind(name, obj, version)
+			boundName = name
+			*dst = obj
+			if bound != nil {
+				bound(obj, version)
+			}
+		},
+		remove: func(name uint32) {
+			if name == boundName {
+				*dst = nil
+			}
+		},
+	}
+	r.add(T(new(E)), w)
+}
+
+// Require registers interest in a singleton global, such as
+// wl_compositor, that the client can't function without. Once bound,
+// dst points to the bound object and bound, if not nil, gets called.
+// Bound is the right place to add event listeners, as the object's
+// initial events may arrive before Init returns.
+//
+// The global gets bound at the highest version that is no larger than
+// max and that the compositor supports. Globals older than min are
+// ignored. If max is zero, the version of the generated bindings is
+// used.
+//
+// If the compositor removes the global, dst is set to nil, and the
+// next global with the same interface gets bound in its place, calling
+// bound again. The removed object should no longer be used.
+//
+// Init returns an error if the global hasn't been advertised.
+func Require[E any, T object[E]](r *Registry, dst *T, min, max uint32, bound func(obj T, version uint32)) {
+	singleton(r, dst, min, max, true, bound)
+}
+
+// Optional is like Require, but the global not being advertised
+// isn't an error. Dst stays nil in that case.
+func Optional[E any, T object[E]](r *Registry, dst *T, min, max uint32, bound func(obj T, version uint32)) {
+	singleton(r, dst, min, max, false, bound)
+}
+
+// Multiple registers interest in globals that may exist more than
+// once and that may come and go at any time, such as wl_output and
+// wl_seat. Added is called for every instance that gets bound, and
+// removed, if not nil, when the compositor removes it. Removed should
+// release the object.
+//
+// See Require for the meaning of min and max.
+func Multiple[E any, T object[E]](r *Registry, min, max uint32, added func(obj T, name, version uint32), removed func(obj T, name uint32)) {
+	objs := map[uint32]T{}
+	w := &want{
+		min:      min,
+		max:      max,
+		multiple: true,
+		bind: func(name, version uint32) {
+			obj := T(new(E))
+			r.registry.Bind(name, obj, version)
+			objs[name] = obj
+			if added != nil {
+				added(obj, name, version)
+			}
+		},
+		remove: func(name uint32) {
+			obj := objs[name]
+			delete(objs, name)
+			if removed != nil {
+				removed(obj, name)
+			}
+		},
+	}
+	r.add(T(new(E)), w)
+}
+
+// MissingGlobalsError is returned by Init when required globals
+// haven't been advertised by the compositor.
+type MissingGlobalsError struct {
+	Globals []MissingGlobal
+}
+
+// MissingGlobal describes a required global that couldn't be bound.
+type MissingGlobal struct {
+	Interface string
+	// MinVersion is the minimum version the client requires.
+	MinVersion uint32
+	// Version is the version advertised by the compositor, or zero if
+	// the compositor doesn't support the interface at all.
+	Version uint32
+}
+
+func (err *MissingGlobalsError) Error() string {
+	var parts []string
+	for _, g := range err.Globals {
+		if g.Version == 0 {
+			parts = append(parts, g.Interface)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (version %d < %d)", g.Interface, g.Version, g.MinVersion))
+		}
+	}
+	return "missing required globals: " + strings.Join(parts, ", ")
+}
+
+// Init waits for the compositor to advertise its globals and binds the
+// requested ones. It also waits for the initial events of the bound
+// objects, such as wl_shm.format, to be dispatched.
+//
+// Init dispatches the display's queue, and thus also events for
+// other objects on that queue.
+//
+// It returns a *MissingGlobalsError if required globals are missing,
+// and the connection's error if the connection is lost while waiting.
+func (r *Registry) Init() error {
+	if err := r.roundtrip(); err != nil {
+		return err
+	}
+	if err := r.roundtrip(); err != nil {
+		return err
+	}
+
+	var missing []MissingGlobal
+	for _, w := range r.wants {
+		if !w.required || w.found {
+			continue
+		}
+		m := MissingGlobal{Interface: w.iface, MinVersion: w.min}
+		for _, g := range r.globals {
+			if g.Interface == w.iface && g.Version > m.Version {
+				m.Version = g.Version
+			}
+		}
+		missing = append(missing, m)
+	}
+	if len(missing) > 0 {
+		sort.Slice(missing, func(i, j int) bool { return missing[i].Interface < missing[j].Interface })
+		return &MissingGlobalsError{missing}
+	}
+	return nil
+}
+
+// roundtrip blocks until the server has processed all requests sent
+// so far and we've dispatched the resulting events. It returns the
+// connection's error if the connection is lost before then.
+func (r *Registry) roundtrip() error {
+	conn := r.display.Conn()
+	queue := r.registry.Queue()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-conn.Done():
+			// wake up Dispatch so that we notice
+			queue.Post(func() {})
+		case <-stop:
+		}
+	}()
+
+	var done bool
+	cb := r.display.Sync()
+	cb.AddListener(wayland.CallbackEvents{
+		Done: func(_ *wayland.Callback, _ uint32) {
+			done = true
+			cb.Destroy()
+		},
+	})
+	for !done {
+		select {
+		case <-conn.Done():
+			return conn.Err()
+		default:
+		}
+		queue.Dispatch()
+	}
+	return nil
+}
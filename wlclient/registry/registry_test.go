@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlclient"
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+)
+
+func TestInitLostConnection(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "")
+	c, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := wlclient.NewConn(c.(*net.UnixConn))
+	defer conn.Close()
+	// The compositor goes away without answering.
+	unix.Close(fds[1])
+
+	var shm *wayland.Shm
+	r := New(wayland.GetDisplay(conn))
+	Require(r, &shm, 1, 1, nil)
+	errs := make(chan error, 1)
+	go func() { errs <- r.Init() }()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Init succeeded on a lost connection")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Init didn't return after the connection was lost")
+	}
+}
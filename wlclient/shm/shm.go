@@ -0,0 +1,432 @@
+// Package shm manages shared memory buffers for clients.
+//
+// A Pool hands out wl_buffers backed by a single, growable memfd.
+// Buffers are tracked as busy from the moment they are attached to a
+// surface until the compositor releases them, so that clients never
+// draw into memory the compositor may be reading from.
+package shm
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+)
+
+// ErrClosed is returned when using a closed pool.
+var ErrClosed = errors.New("shm pool has been closed")
+
+// Pool is a growable pool of shared memory buffers of a single
+// format.
+type Pool struct {
+	shm    *wayland.Shm
+	format wayland.ShmFormat
+
+	fd   int
+	pool *wayland.ShmPool
+	size int
+	data []byte
+	// old mappings, kept alive so that buffers created before the pool
+	// grew stay valid. They map the same file and are unmapped in
+	// Close.
+	mappings [][]byte
+	// free extents of the pool, sorted by offset
+	free []extent
+
+	buffers []*Buffer
+	closed  bool
+}
+
+type extent struct {
+	off  int
+	size int
+}
+
+// NewPool returns a pool that creates buffers of the given format.
+// Memory is allocated lazily. Only 32-bit formats (argb8888,
+// xrgb8888, abgr8888 and xbgr8888) are supported.
+func NewPool(shm *wayland.Shm, format wayland.ShmFormat) (*Pool, error) {
+	switch format {
+	case wayland.ShmFormatArgb8888, wayland.ShmFormatXrgb8888, wayland.ShmFormatAbgr8888, wayland.ShmFormatXbgr8888:
+	default:
+		return nil, fmt.Errorf("unsupported format 0x%x", uint32(format))
+	}
+	return &Pool{
+		shm:    shm,
+		format: format,
+		fd:     -1,
+	}, nil
+}
+
+// Get returns a buffer of the requested size that isn't in use by the
+// compositor, creating one if necessary.
+//
+// Get reclaims idle buffers of other sizes, which makes it suitable
+// for windows that change size: simply request buffers of the new
+// size. Buffers of other sizes that are still busy get destroyed once
+// the compositor releases them.
+func (p *Pool) Get(width, height int32) (*Buffer, error) {
+	if p.closed {
+		return nil, ErrClosed
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid buffer size %dx%d", width, height)
+	}
+
+	var found *Buffer
+	for _, buf := range p.buffers {
+		if buf.pinned {
+			continue
+		}
+		if buf.Width != width || buf.Height != height {
+			if buf.busy {
+				buf.stale = true
+			} else {
+				p.destroyBuffer(buf)
+			}
+			continue
+		}
+		if found == nil && !buf.busy {
+			found = buf
+		}
+	}
+	p.removeDestroyed()
+	if found != nil {
+		return found, nil
+	}
+	return p.newBuffer(width, height)
+}
+
+// Alloc returns a new buffer of the requested size. Unlike buffers
+// returned by Get, the buffer is never reclaimed by the pool, which
+// makes it suitable for images that are drawn once and attached many
+// times, such as cursors. It has to be destroyed explicitly.
+func (p *Pool) Alloc(width, height int32) (*Buffer, error) {
+	if p.closed {
+		return nil, ErrClosed
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid buffer size %dx%d", width, height)
+	}
+	buf, err := p.newBuffer(width, height)
+	if err != nil {
+		return nil, err
+	}
+	buf.pinned = true
+	return buf, nil
+}
+
+func (p *Pool) newBuffer(width, height int32) (*Buffer, error) {
+	stride := width * 4
+	size := int(stride) * int(height)
+	off, err := p.alloc(size)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &Buffer{
+		Buffer: p.pool.CreateBuffer(int32(off), width, height, stride, p.format),
+		Width:  width,
+		Height: height,
+		Stride: stride,
+		Format: p.format,
+		pool:   p,
+		off:    off,
+		data:   p.data[off : off+size],
+	}
+	buf.Buffer.AddListener(wayland.BufferEvents{
+		Release: func(_ *wayland.Buffer) {
+			buf.busy = false
+			if buf.stale && buf.pool != nil {
+				p.destroyBuffer(buf)
+				p.removeDestroyed()
+			}
+		},
+	})
+	p.buffers = append(p.buffers, buf)
+	return buf, nil
+}
+
+// Close destroys all buffers and the pool and releases the shared
+// memory. Buffers still in use by the compositor stay valid on the
+// compositor's side until it releases them.
+func (p *Pool) Close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	for _, buf := range p.buffers {
+		buf.Buffer.Destroy()
+		buf.data = nil
+		buf.pool = nil
+	}
+	p.buffers = nil
+	if p.pool != nil {
+		p.pool.Destroy()
+	}
+
+	var err error
+	for _, m := range append(p.mappings, p.data) {
+		if m == nil {
+			continue
+		}
+		if merr := unix.Munmap(m); merr != nil && err == nil {
+			err = merr
+		}
+	}
+	p.mappings = nil
+	p.data = nil
+	if p.fd != -1 {
+		if cerr := unix.Close(p.fd); cerr != nil && err == nil {
+			err = cerr
+		}
+		p.fd = -1
+	}
+	return err
+}
+
+func (p *Pool) destroyBuffer(buf *Buffer) {
+	buf.Buffer.Destroy()
+	buf.data = nil
+	p.release(extent{buf.off, int(buf.Stride) * int(buf.Height)})
+	buf.pool = nil
+}
+
+func (p *Pool) removeDestroyed() {
+	out := p.buffers[:0]
+	for _, buf := range p.buffers {
+		if buf.pool != nil {
+			out = append(out, buf)
+		}
+	}
+	for i := len(out); i < len(p.buffers); i++ {
+		p.buffers[i] = nil
+	}
+	p.buffers = out
+}
+
+// alloc finds room for size bytes in the pool, growing it if
+// necessary.
+func (p *Pool) alloc(size int) (int, error) {
+	for i, e := range p.free {
+		if e.size >= size {
+			if e.size == size {
+				p.free = append(p.free[:i], p.free[i+1:]...)
+			} else {
+				p.free[i] = extent{e.off + size, e.size - size}
+			}
+			return e.off, nil
+		}
+	}
+
+	// Grow the pool. If the last extent is free, extend it.
+	need := size
+	if n := len(p.free); n > 0 && p.free[n-1].off+p.free[n-1].size == p.size {
+		need -= p.free[n-1].size
+	}
+	newSize := p.size * 2
+	if newSize < p.size+need {
+		newSize = p.size + need
+	}
+	old := p.size
+	if err := p.grow(newSize); err != nil {
+		return 0, err
+	}
+	p.release(extent{old, newSize - old})
+	return p.alloc(size)
+}
+
+// release returns an extent to the free list, coalescing it with its
+// neighbours.
+func (p *Pool) release(e extent) {
+	i := sort.Search(len(p.free), func(i int) bool { return p.free[i].off > e.off })
+	p.free = append(p.free, extent{})
+	copy(p.free[i+1:], p.free[i:])
+	p.free[i] = e
+	if i+1 < len(p.free) && p.free[i].off+p.free[i].size == p.free[i+1].off {
+		p.free[i].size += p.free[i+1].size
+		p.free = append(p.free[:i+1], p.free[i+2:]...)
+	}
+	if i > 0 && p.free[i-1].off+p.free[i-1].size == p.free[i].off {
+		p.free[i-1].size += p.free[i].size
+		p.free = append(p.free[:i], p.free[i+1:]...)
+	}
+}
+
+func (p *Pool) grow(size int) error {
+	if p.fd == -1 {
+		fd, err := unix.MemfdCreate("wayland-shm", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+		if err != nil {
+			return fmt.Errorf("couldn't create memfd: %w", err)
+		}
+		p.fd = fd
+	}
+	if err := unix.Ftruncate(p.fd, int64(size)); err != nil {
+		return fmt.Errorf("couldn't grow pool: %w", err)
+	}
+	// The compositor must be able to rely on the pool not shrinking
+	// underneath it. This is best effort and we don't care if it fails.
+	unix.FcntlInt(uintptr(p.fd), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK)
+
+	data, err := unix.Mmap(p.fd, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("couldn't map pool: %w", err)
+	}
+	if p.data != nil {
+		p.mappings = append(p.mappings, p.data)
+	}
+	p.data = data
+
+	if p.pool == nil {
+		p.pool = p.shm.CreatePool(uintptr(p.fd), int32(size))
+	} else {
+		p.pool.Resize(int32(size))
+	}
+	p.size = size
+	return nil
+}
+
+// Buffer is a wl_buffer backed by a pool's shared memory.
+type Buffer struct {
+	Buffer *wayland.Buffer
+	Width  int32
+	Height int32
+	Stride int32
+	Format wayland.ShmFormat
+
+	pool *Pool
+	off  int
+	data []byte
+	busy bool
+	// stale buffers are destroyed as soon as they are released
+	stale bool
+	// pinned buffers were created by Alloc and aren't reused by Get
+	pinned bool
+}
+
+// Destroy destroys the buffer and returns its memory to the pool. If
+// the buffer is in use by the compositor, this is delayed until the
+// compositor releases it.
+func (buf *Buffer) Destroy() {
+	p := buf.pool
+	if p == nil {
+		return
+	}
+	if buf.busy {
+		buf.stale = true
+		return
+	}
+	p.destroyBuffer(buf)
+	p.removeDestroyed()
+}
+
+// Busy reports whether the buffer is in use by the compositor.
+func (buf *Buffer) Busy() bool { return buf.busy }
+
+// Attach attaches the buffer to a surface and marks it as busy until
+// the compositor releases it.
+func (buf *Buffer) Attach(surface *wayland.Surface, x, y int32) {
+	buf.busy = true
+	surface.Attach(buf.Buffer, x, y)
+}
+
+// Data returns the buffer's memory.
+func (buf *Buffer) Data() []byte { return buf.data }
+
+// Image returns a draw.Image that provides access to the buffer's
+// pixels.
+func (buf *Buffer) Image() *Image {
+	return &Image{
+		Pix:    buf.data,
+		Stride: int(buf.Stride),
+		Rect:   image.Rect(0, 0, int(buf.Width), int(buf.Height)),
+		Format: buf.Format,
+	}
+}
+
+// Image is a draw.Image backed by shared memory. It is laid out like
+// image.RGBA, except for the order of the color channels, which
+// depends on Format. Colors are premultiplied, as required by wl_shm.
+type Image struct {
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+	Format wayland.ShmFormat
+}
+
+func (img *Image) ColorModel() color.Model { return color.RGBAModel }
+func (img *Image) Bounds() image.Rectangle { return img.Rect }
+
+func (img *Image) At(x, y int) color.Color {
+	return img.RGBAAt(x, y)
+}
+
+// PixOffset returns the index of the first element of Pix that
+// corresponds to the pixel at (x, y).
+func (img *Image) PixOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*4
+}
+
+// RGBAAt returns the color of the pixel at (x, y).
+func (img *Image) RGBAAt(x, y int) color.RGBA {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.RGBA{}
+	}
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+4 : i+4]
+	// All formats are little endian
+	switch img.Format {
+	case wayland.ShmFormatArgb8888:
+		return color.RGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+	case wayland.ShmFormatXrgb8888:
+		return color.RGBA{R: p[2], G: p[1], B: p[0], A: 0xFF}
+	case wayland.ShmFormatAbgr8888:
+		return color.RGBA{R: p[0], G: p[1], B: p[2], A: p[3]}
+	case wayland.ShmFormatXbgr8888:
+		return color.RGBA{R: p[0], G: p[1], B: p[2], A: 0xFF}
+	default:
+		return color.RGBA{}
+	}
+}
+
+func (img *Image) Set(x, y int, c color.Color) {
+	img.SetRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+// SetRGBA sets the color of the pixel at (x, y).
+func (img *Image) SetRGBA(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+4 : i+4]
+	switch img.Format {
+	case wayland.ShmFormatArgb8888:
+		p[0], p[1], p[2], p[3] = c.B, c.G, c.R, c.A
+	case wayland.ShmFormatXrgb8888:
+		p[0], p[1], p[2], p[3] = c.B, c.G, c.R, 0xFF
+	case wayland.ShmFormatAbgr8888:
+		p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+	case wayland.ShmFormatXbgr8888:
+		p[0], p[1], p[2], p[3] = c.R, c.G, c.B, 0xFF
+	}
+}
+
+// SubImage returns an image representing the portion of img visible
+// through r. The returned image shares pixels with img.
+func (img *Image) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
+		return &Image{Format: img.Format}
+	}
+	return &Image{
+		Pix:    img.Pix[img.PixOffset(r.Min.X, r.Min.Y):],
+		Stride: img.Stride,
+		Rect:   r,
+		Format: img.Format,
+	}
+}
@@ -0,0 +1,341 @@
+package window
+
+import (
+	"errors"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	xdgShell "honnef.co/go/wayland/wlclient/protocols/xdg-shell"
+)
+
+var (
+	// ErrInvalidGrab is returned by Popup.Grab if the popup can't take
+	// an explicit grab.
+	ErrInvalidGrab = errors.New("popup can't take an explicit grab")
+	// ErrUnsupported is returned for operations that the compositor's
+	// version of xdg_wm_base doesn't support.
+	ErrUnsupported = errors.New("operation not supported by xdg_wm_base version")
+)
+
+// Placement describes the position of a popup relative to its parent.
+// The compositor places the popup according to it, adjusting the
+// position if the popup would otherwise extend beyond the edges of the
+// output.
+//
+// Coordinates are relative to the parent's window geometry.
+type Placement struct {
+	// Width and Height are the size of the popup. They must be
+	// positive.
+	Width, Height int32
+
+	// The anchor rectangle is the area of the parent the popup is
+	// attached to, such as the button that opened a menu.
+	AnchorX, AnchorY          int32
+	AnchorWidth, AnchorHeight int32
+	// Anchor is the point of the anchor rectangle the popup is
+	// attached to, such as its bottom left corner. The default is its
+	// center.
+	Anchor xdgShell.PositionerAnchor
+	// Gravity is the direction the popup extends in from the anchor
+	// point. The default is to center the popup on the anchor point.
+	Gravity xdgShell.PositionerGravity
+	// OffsetX and OffsetY move the popup from the position derived
+	// from the anchor point and gravity.
+	OffsetX, OffsetY int32
+
+	// Adjust specifies how the compositor may move the popup if it
+	// doesn't fit: it may flip it to the opposite side of the anchor
+	// rectangle, slide it along an axis or resize it. It is a
+	// combination of xdgShell.PositionerConstraintAdjustment values.
+	Adjust xdgShell.PositionerConstraintAdjustment
+	// Reactive makes the compositor reposition the popup when its
+	// parent moves or resizes. It requires version 3 of xdg_wm_base.
+	Reactive bool
+}
+
+// DropDown returns the placement of a drop-down menu opened from a
+// widget, such as a menu bar item. The menu is placed below the
+// widget, aligned with its left edge, and flipped above it or moved
+// sideways if there isn't enough space.
+func DropDown(x, y, width, height, menuWidth, menuHeight int32) Placement {
+	return Placement{
+		Width:        menuWidth,
+		Height:       menuHeight,
+		AnchorX:      x,
+		AnchorY:      y,
+		AnchorWidth:  width,
+		AnchorHeight: height,
+		Anchor:       xdgShell.PositionerAnchorBottomLeft,
+		Gravity:      xdgShell.PositionerGravityBottomRight,
+		Adjust: xdgShell.PositionerConstraintAdjustmentFlipY |
+			xdgShell.PositionerConstraintAdjustmentSlideX |
+			xdgShell.PositionerConstraintAdjustmentResizeY,
+	}
+}
+
+// ContextMenu returns the placement of a context menu opened at the
+// point x, y, such as the position of the pointer. The menu extends
+// to the bottom right of the point, and is flipped to the other side
+// of the point in either direction if there isn't enough space.
+func ContextMenu(x, y, menuWidth, menuHeight int32) Placement {
+	return Placement{
+		Width:        menuWidth,
+		Height:       menuHeight,
+		AnchorX:      x,
+		AnchorY:      y,
+		AnchorWidth:  1,
+		AnchorHeight: 1,
+		Anchor:       xdgShell.PositionerAnchorTopLeft,
+		Gravity:      xdgShell.PositionerGravityBottomRight,
+		Adjust: xdgShell.PositionerConstraintAdjustmentFlipX |
+			xdgShell.PositionerConstraintAdjustmentFlipY |
+			xdgShell.PositionerConstraintAdjustmentSlideX |
+			xdgShell.PositionerConstraintAdjustmentSlideY,
+	}
+}
+
+// Parent is a window that popups can be attached to, i.e. a *Toplevel
+// or a *Popup.
+type Parent interface {
+	Surface() *wayland.Surface
+	Size() (width, height int32)
+	parentBase() *base
+}
+
+// PopupEvents are the callbacks of a Popup. All callbacks are
+// optional.
+type PopupEvents struct {
+	// Configure is called when the compositor has placed the popup,
+	// after the configuration has been acknowledged. The client should
+	// draw the popup at the size returned by Popup.Size and commit its
+	// surface.
+	Configure func(p *Popup)
+	// Done is called when the popup has been closed by the compositor,
+	// e.g. because the user clicked outside of it, or because its
+	// parent has been closed. It isn't called for popups closed with
+	// Popup.Close. The popup has already been destroyed when Done is
+	// called.
+	Done func(p *Popup)
+}
+
+// Popup is a short-lived window placed relative to a parent window,
+// such as a menu or a tooltip.
+type Popup struct {
+	base
+	shell  *Shell
+	popup  *xdgShell.Popup
+	parent Parent
+	events PopupEvents
+
+	placement  Placement
+	x, y       int32
+	pendingX   int32
+	pendingY   int32
+	width      int32
+	height     int32
+	pendingW   int32
+	pendingH   int32
+	shown      bool
+	grabbed    bool
+	grabSerial uint32
+	token      uint32
+	closed     bool
+}
+
+// NewPopup turns surface into a popup of parent, placed according to
+// pl. The popup isn't shown until Show is called. Grab has to be
+// called before Show if the popup should take an explicit grab, as is
+// usual for menus.
+func (sh *Shell) NewPopup(surface *wayland.Surface, parent Parent, pl Placement, events PopupEvents) *Popup {
+	p := &Popup{
+		base: base{
+			surface:    surface,
+			xdgSurface: sh.wmBase.GetXdgSurface(surface),
+		},
+		shell:     sh,
+		parent:    parent,
+		events:    events,
+		placement: pl,
+		width:     pl.Width,
+		height:    pl.Height,
+	}
+	p.xdgSurface.AddListener(xdgShell.SurfaceEvents{
+		Configure: p.handleConfigure,
+	})
+	pos := sh.positioner(parent, pl)
+	p.popup = p.xdgSurface.GetPopup(parent.parentBase().xdgSurface, pos)
+	pos.Destroy()
+	p.popup.AddListener(xdgShell.PopupEvents{
+		Configure: p.handlePopupConfigure,
+		PopupDone: p.handleDone,
+	})
+	pb := parent.parentBase()
+	pb.popups = append(pb.popups, p)
+	return p
+}
+
+func (sh *Shell) positioner(parent Parent, pl Placement) *xdgShell.Positioner {
+	pos := sh.wmBase.CreatePositioner()
+	pos.SetSize(pl.Width, pl.Height)
+	pos.SetAnchorRect(pl.AnchorX, pl.AnchorY, pl.AnchorWidth, pl.AnchorHeight)
+	pos.SetAnchor(pl.Anchor)
+	pos.SetGravity(pl.Gravity)
+	pos.SetConstraintAdjustment(uint32(pl.Adjust))
+	pos.SetOffset(pl.OffsetX, pl.OffsetY)
+	if pl.Reactive && sh.version >= 3 {
+		pos.SetReactive()
+		pos.SetParentSize(parent.Size())
+		if pb := parent.parentBase(); pb.configured {
+			pos.SetParentConfigure(pb.serial)
+		}
+	}
+	return pos
+}
+
+func (p *Popup) parentBase() *base { return &p.base }
+
+// Popup returns the underlying xdg_popup.
+func (p *Popup) Popup() *xdgShell.Popup { return p.popup }
+
+// Parent returns the popup's parent.
+func (p *Popup) Parent() Parent { return p.parent }
+
+// Position returns the position of the popup relative to its parent's
+// window geometry, as configured by the compositor.
+func (p *Popup) Position() (x, y int32) { return p.x, p.y }
+
+// Size returns the size the popup should be drawn at. Until the popup
+// has been configured, it is the size of the placement.
+func (p *Popup) Size() (width, height int32) { return p.width, p.height }
+
+// Closed reports whether the popup has been closed.
+func (p *Popup) Closed() bool { return p.closed }
+
+// Grab makes the popup take an explicit grab, which gives it keyboard
+// focus and closes it when the user clicks outside of the client's
+// surfaces. Serial is the serial of the input event that opened the
+// popup, such as a button press.
+//
+// Grabbing popups form a stack: the parent of a grabbing popup must
+// either be a toplevel, if no other popup has a grab, or the topmost
+// grabbing popup. If serial is zero and the parent is a grabbing
+// popup, the serial of the parent's grab is used, which allows opening
+// nested menus in response to events that don't have serials, such as
+// pointer motion.
+//
+// Grab must be called before Show. It returns ErrInvalidGrab if the
+// popup can't take a grab.
+func (p *Popup) Grab(seat *wayland.Seat, serial uint32) error {
+	if p.shown || p.grabbed || p.closed {
+		return ErrInvalidGrab
+	}
+	grabs := p.shell.grabs
+	if len(grabs) > 0 {
+		if parent, ok := p.parent.(*Popup); !ok || parent != grabs[len(grabs)-1] {
+			return ErrInvalidGrab
+		}
+	} else if _, ok := p.parent.(*Popup); ok {
+		// The parent is a popup without a grab.
+		return ErrInvalidGrab
+	}
+	if parent, ok := p.parent.(*Popup); ok && serial == 0 {
+		serial = parent.grabSerial
+	}
+	p.popup.Grab(seat, serial)
+	p.grabbed = true
+	p.grabSerial = serial
+	p.shell.grabs = append(p.shell.grabs, p)
+	return nil
+}
+
+// Show maps the popup by committing its surface without a buffer. Once
+// the compositor has placed the popup, Configure is called and the
+// popup can be drawn.
+func (p *Popup) Show() {
+	if p.shown || p.closed {
+		return
+	}
+	p.shown = true
+	p.surface.Commit()
+}
+
+// Reposition moves the popup according to a new placement. It requires
+// version 3 of xdg_wm_base and returns ErrUnsupported otherwise.
+func (p *Popup) Reposition(pl Placement) error {
+	if p.shell.version < 3 {
+		return ErrUnsupported
+	}
+	if p.closed {
+		return nil
+	}
+	p.placement = pl
+	pos := p.shell.positioner(p.parent, pl)
+	p.token++
+	p.popup.Reposition(pos, p.token)
+	pos.Destroy()
+	return nil
+}
+
+// Close closes the popup, as well as all popups that are attached to
+// it, and destroys its xdg-shell objects. The surface is not
+// destroyed, which remains the responsibility of the caller.
+func (p *Popup) Close() {
+	p.close(false)
+}
+
+func (p *Popup) close(done bool) {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	// Child popups are above us in the stack and have to be destroyed
+	// first.
+	p.closePopups()
+	p.popup.Destroy()
+	p.xdgSurface.Destroy()
+
+	pb := p.parent.parentBase()
+	for i, sib := range pb.popups {
+		if sib == p {
+			pb.popups = append(pb.popups[:i], pb.popups[i+1:]...)
+			break
+		}
+	}
+	if p.grabbed {
+		grabs := p.shell.grabs
+		for i, g := range grabs {
+			if g == p {
+				p.shell.grabs = append(grabs[:i], grabs[i+1:]...)
+				break
+			}
+		}
+	}
+	if done && p.events.Done != nil {
+		p.events.Done(p)
+	}
+}
+
+func (p *Popup) handlePopupConfigure(_ *xdgShell.Popup, x, y, width, height int32) {
+	// applied by the following xdg_surface.configure event
+	p.pendingX, p.pendingY = x, y
+	p.pendingW, p.pendingH = width, height
+}
+
+func (p *Popup) handleConfigure(_ *xdgShell.Surface, serial uint32) {
+	if p.closed {
+		return
+	}
+	p.xdgSurface.AckConfigure(serial)
+	p.configured = true
+	p.serial = serial
+	p.x, p.y = p.pendingX, p.pendingY
+	if p.pendingW > 0 && p.pendingH > 0 {
+		p.width, p.height = p.pendingW, p.pendingH
+	}
+	if p.events.Configure != nil {
+		p.events.Configure(p)
+	}
+}
+
+func (p *Popup) handleDone(*xdgShell.Popup) {
+	p.close(true)
+}
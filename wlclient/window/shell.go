@@ -0,0 +1,100 @@
+// Package window implements windows and popups on top of xdg-shell.
+//
+// A Shell wraps the xdg_wm_base global and answers the compositor's
+// pings, which compositors use to detect unresponsive clients. It
+// creates Toplevels, which are regular application windows, and
+// Popups, such as menus and tooltips.
+//
+// Windows are configured by the compositor, which decides their size
+// and state. A window's surface must not be drawn to before the first
+// configure event; after every configure event, the window should be
+// redrawn to reflect the new state and the surface committed. This
+// package acknowledges configure events on behalf of the client
+// before invoking its callbacks, so that the commit made in response
+// applies the new state.
+package window
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	xdgShell "honnef.co/go/wayland/wlclient/protocols/xdg-shell"
+)
+
+var byteOrder binary.ByteOrder
+
+func init() {
+	var x uint32 = 0x01020304
+	if *(*byte)(unsafe.Pointer(&x)) == 0x01 {
+		byteOrder = binary.BigEndian
+	} else {
+		byteOrder = binary.LittleEndian
+	}
+}
+
+// Shell creates windows using xdg_wm_base.
+type Shell struct {
+	wmBase  *xdgShell.WmBase
+	version uint32
+	// grabs is the stack of open popups with explicit grabs
+	grabs []*Popup
+}
+
+// NewShell returns a shell for wmBase. Version is the version of the
+// xdg_wm_base. NewShell replaces wmBase's listeners to respond to
+// pings.
+func NewShell(wmBase *xdgShell.WmBase, version uint32) *Shell {
+	sh := &Shell{
+		wmBase:  wmBase,
+		version: version,
+	}
+	wmBase.AddListener(xdgShell.WmBaseEvents{
+		Ping: func(obj *xdgShell.WmBase, serial uint32) {
+			obj.Pong(serial)
+		},
+	})
+	return sh
+}
+
+// WmBase returns the underlying xdg_wm_base.
+func (sh *Shell) WmBase() *xdgShell.WmBase { return sh.wmBase }
+
+// base is the state shared by all kinds of windows.
+type base struct {
+	surface    *wayland.Surface
+	xdgSurface *xdgShell.Surface
+	// configured is set once the first configure event has been
+	// received.
+	configured bool
+	// serial is the serial of the most recent configure event
+	serial uint32
+	// popups are the window's child popups that are still open
+	popups []*Popup
+}
+
+// Surface returns the window's wl_surface.
+func (b *base) Surface() *wayland.Surface { return b.surface }
+
+// XdgSurface returns the window's xdg_surface.
+func (b *base) XdgSurface() *xdgShell.Surface { return b.xdgSurface }
+
+// Configured reports whether the window has been configured, which is
+// required before attaching buffers to its surface.
+func (b *base) Configured() bool { return b.configured }
+
+// closePopups closes the window's child popups.
+func (b *base) closePopups() {
+	// Popups have to be destroyed in the reverse order they were
+	// created in.
+	for len(b.popups) > 0 {
+		b.popups[len(b.popups)-1].close(true)
+	}
+}
+
+// SetGeometry sets the window geometry, i.e. the part of the surface
+// that is the window proper, excluding decorations such as shadows.
+// Like all surface state, it is applied on the next commit.
+func (b *base) SetGeometry(x, y, width, height int32) {
+	b.xdgSurface.SetWindowGeometry(x, y, width, height)
+}
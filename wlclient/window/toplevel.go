@@ -0,0 +1,380 @@
+package window
+
+import (
+	"honnef.co/go/wayland/wlclient/protocols/wayland"
+	xdgShell "honnef.co/go/wayland/wlclient/protocols/xdg-shell"
+)
+
+// State is the state of a toplevel window, as configured by the
+// compositor.
+type State struct {
+	// Width and Height are the size suggested by the compositor, in
+	// surface-local coordinates. Zero values leave the size up to the
+	// client.
+	Width, Height int32
+
+	Maximized  bool
+	Fullscreen bool
+	// Resizing is set while the user interactively resizes the window.
+	Resizing  bool
+	Activated bool
+	// The tiled states are set when an edge of the window is adjacent
+	// to another window or to the edge of the output. They are only
+	// sent by compositors that support version 2 of xdg_wm_base.
+	TiledLeft   bool
+	TiledRight  bool
+	TiledTop    bool
+	TiledBottom bool
+}
+
+// floating reports whether the window's size is under the client's
+// control, i.e. whether it is neither maximized, fullscreen nor tiled.
+func (s State) floating() bool {
+	return !s.Maximized && !s.Fullscreen && !s.TiledLeft && !s.TiledRight && !s.TiledTop && !s.TiledBottom
+}
+
+// ToplevelEvents are the callbacks of a Toplevel. All callbacks are
+// optional.
+//
+// When the compositor configures the window, the callbacks for the
+// individual changes are called first, followed by Configure. The
+// configuration has already been acknowledged when they are called.
+type ToplevelEvents struct {
+	// Configure is called for every configuration, including the
+	// initial one. The client should draw the window at the size
+	// returned by Toplevel.Size and commit its surface, e.g. by
+	// calling frame.Loop.Redraw.
+	Configure func(w *Toplevel, state State)
+	// Resize is called when the window's size changes.
+	Resize func(w *Toplevel, width, height int32)
+	// Maximize is called when the window gets maximized or unmaximized.
+	Maximize func(w *Toplevel, maximized bool)
+	// Fullscreen is called when the window enters or leaves fullscreen
+	// mode.
+	Fullscreen func(w *Toplevel, fullscreen bool)
+	// Activate is called when the window gains or loses activation,
+	// which usually means keyboard focus.
+	Activate func(w *Toplevel, activated bool)
+	// Bounds is called with the size of the area windows should fit
+	// into, such as the size of the output minus panels. It is only
+	// sent by compositors that support version 4 of xdg_wm_base.
+	Bounds func(w *Toplevel, width, height int32)
+	// Close is called when the user asked to close the window. The
+	// window isn't closed automatically.
+	Close func(w *Toplevel)
+}
+
+// Toplevel is an application window.
+type Toplevel struct {
+	base
+	toplevel *xdgShell.Toplevel
+	events   ToplevelEvents
+
+	title      string
+	appID      string
+	parent     *Toplevel
+	minW, minH int32
+	maxW, maxH int32
+	shown      bool
+	state      State
+	pending    State
+	width      int32
+	height     int32
+	floatW     int32
+	floatH     int32
+	boundsW    int32
+	boundsH    int32
+	destroyed  bool
+}
+
+// NewToplevel turns surface into a toplevel window. Width and height
+// are the size of the window when the compositor leaves the choice to
+// the client. The window isn't shown until Show is called, which
+// allows setting its title and other properties first.
+func (sh *Shell) NewToplevel(surface *wayland.Surface, width, height int32, events ToplevelEvents) *Toplevel {
+	w := &Toplevel{
+		base: base{
+			surface:    surface,
+			xdgSurface: sh.wmBase.GetXdgSurface(surface),
+		},
+		events: events,
+		width:  width,
+		height: height,
+		floatW: width,
+		floatH: height,
+	}
+	w.xdgSurface.AddListener(xdgShell.SurfaceEvents{
+		Configure: w.handleConfigure,
+	})
+	w.toplevel = w.xdgSurface.GetToplevel()
+	w.toplevel.AddListener(xdgShell.ToplevelEvents{
+		Configure:       w.handleToplevelConfigure,
+		Close:           w.handleClose,
+		ConfigureBounds: w.handleBounds,
+	})
+	return w
+}
+
+func (w *Toplevel) parentBase() *base { return &w.base }
+
+// Toplevel returns the underlying xdg_toplevel.
+func (w *Toplevel) Toplevel() *xdgShell.Toplevel { return w.toplevel }
+
+// State returns the most recently configured state.
+func (w *Toplevel) State() State { return w.state }
+
+// Size returns the size the window should be drawn at. It is the size
+// requested by the compositor, limited to the window's minimum and
+// maximum sizes, or the size chosen by the client if the compositor
+// doesn't request a particular one. When a maximized or fullscreen
+// window is restored, it returns to its previous size.
+func (w *Toplevel) Size() (width, height int32) { return w.width, w.height }
+
+// Bounds returns the bounds most recently sent by the compositor, or
+// zero if it hasn't sent any.
+func (w *Toplevel) Bounds() (width, height int32) { return w.boundsW, w.boundsH }
+
+// Show maps the window by committing its surface without a buffer. The
+// surface mustn't have a buffer attached yet. Once the compositor has
+// configured the window, Configure is called and the window can be
+// drawn. Show can also be used to map the window again after Hide.
+func (w *Toplevel) Show() {
+	if w.shown {
+		return
+	}
+	w.shown = true
+	w.surface.Commit()
+}
+
+// Hide unmaps the window by committing its surface without a buffer.
+// All window state is reset by the compositor; the properties set on
+// the Toplevel are sent again when the window is shown again.
+func (w *Toplevel) Hide() {
+	if !w.shown {
+		return
+	}
+	w.shown = false
+	w.closePopups()
+	w.configured = false
+	w.state = State{}
+	w.surface.Attach(nil, 0, 0)
+	w.surface.Commit()
+	// Unmapping discarded all properties, restore them for the next
+	// time the window is mapped.
+	w.resendProperties()
+}
+
+func (w *Toplevel) resendProperties() {
+	if w.title != "" {
+		w.toplevel.SetTitle(w.title)
+	}
+	if w.appID != "" {
+		w.toplevel.SetAppID(w.appID)
+	}
+	if w.parent != nil {
+		w.toplevel.SetParent(w.parent.toplevel)
+	}
+	if w.minW != 0 || w.minH != 0 {
+		w.toplevel.SetMinSize(w.minW, w.minH)
+	}
+	if w.maxW != 0 || w.maxH != 0 {
+		w.toplevel.SetMaxSize(w.maxW, w.maxH)
+	}
+}
+
+// SetTitle sets the window's title.
+func (w *Toplevel) SetTitle(title string) {
+	w.title = title
+	w.toplevel.SetTitle(title)
+}
+
+// SetAppID sets the window's application ID, which should match the
+// basename of the application's .desktop file.
+func (w *Toplevel) SetAppID(appID string) {
+	w.appID = appID
+	w.toplevel.SetAppID(appID)
+}
+
+// SetParent makes the window a child of parent, such as a dialog.
+// Parent may be nil to make the window independent again.
+func (w *Toplevel) SetParent(parent *Toplevel) {
+	w.parent = parent
+	if parent == nil {
+		w.toplevel.SetParent(nil)
+	} else {
+		w.toplevel.SetParent(parent.toplevel)
+	}
+}
+
+// SetMinSize sets the window's minimum size. Zero means no minimum in
+// that dimension. Like all surface state, it is applied on the next
+// commit.
+func (w *Toplevel) SetMinSize(width, height int32) {
+	w.minW, w.minH = width, height
+	w.toplevel.SetMinSize(width, height)
+}
+
+// SetMaxSize sets the window's maximum size. Zero means no maximum in
+// that dimension. Like all surface state, it is applied on the next
+// commit.
+func (w *Toplevel) SetMaxSize(width, height int32) {
+	w.maxW, w.maxH = width, height
+	w.toplevel.SetMaxSize(width, height)
+}
+
+// SetMaximized asks the compositor to maximize or unmaximize the
+// window. The compositor responds with a configure event, if it
+// honors the request.
+func (w *Toplevel) SetMaximized(maximized bool) {
+	if maximized {
+		w.toplevel.SetMaximized()
+	} else {
+		w.toplevel.UnsetMaximized()
+	}
+}
+
+// SetFullscreen asks the compositor to make the window fullscreen on
+// output, or on an output of its choice if output is nil.
+func (w *Toplevel) SetFullscreen(output *wayland.Output) {
+	w.toplevel.SetFullscreen(output)
+}
+
+// UnsetFullscreen asks the compositor to leave fullscreen mode.
+func (w *Toplevel) UnsetFullscreen() {
+	w.toplevel.UnsetFullscreen()
+}
+
+// Minimize asks the compositor to minimize the window. There is no
+// way to tell whether the window is minimized.
+func (w *Toplevel) Minimize() {
+	w.toplevel.SetMinimized()
+}
+
+// Move starts an interactive move of the window, in response to the
+// input event with the given serial, such as a button press on the
+// window's title bar.
+func (w *Toplevel) Move(seat *wayland.Seat, serial uint32) {
+	w.toplevel.Move(seat, serial)
+}
+
+// Resize starts an interactive resize of the window using the given
+// edges, in response to the input event with the given serial.
+func (w *Toplevel) Resize(seat *wayland.Seat, serial uint32, edges xdgShell.ToplevelResizeEdge) {
+	w.toplevel.Resize(seat, serial, edges)
+}
+
+// ShowWindowMenu asks the compositor to show its window menu at the
+// surface-local position x, y.
+func (w *Toplevel) ShowWindowMenu(seat *wayland.Seat, serial uint32, x, y int32) {
+	w.toplevel.ShowWindowMenu(seat, serial, x, y)
+}
+
+// Destroy destroys the window's xdg-shell objects. The surface is
+// unmapped but not destroyed, which remains the responsibility of the
+// caller.
+func (w *Toplevel) Destroy() {
+	if w.destroyed {
+		return
+	}
+	w.destroyed = true
+	w.closePopups()
+	w.toplevel.Destroy()
+	w.xdgSurface.Destroy()
+}
+
+func (w *Toplevel) handleToplevelConfigure(_ *xdgShell.Toplevel, width, height int32, states []byte) {
+	s := State{Width: width, Height: height}
+	for i := 0; i+4 <= len(states); i += 4 {
+		switch xdgShell.ToplevelState(byteOrder.Uint32(states[i:])) {
+		case xdgShell.ToplevelStateMaximized:
+			s.Maximized = true
+		case xdgShell.ToplevelStateFullscreen:
+			s.Fullscreen = true
+		case xdgShell.ToplevelStateResizing:
+			s.Resizing = true
+		case xdgShell.ToplevelStateActivated:
+			s.Activated = true
+		case xdgShell.ToplevelStateTiledLeft:
+			s.TiledLeft = true
+		case xdgShell.ToplevelStateTiledRight:
+			s.TiledRight = true
+		case xdgShell.ToplevelStateTiledTop:
+			s.TiledTop = true
+		case xdgShell.ToplevelStateTiledBottom:
+			s.TiledBottom = true
+		}
+	}
+	// The state is applied once the xdg_surface.configure event
+	// completes the configuration.
+	w.pending = s
+}
+
+func (w *Toplevel) handleBounds(_ *xdgShell.Toplevel, width, height int32) {
+	w.boundsW, w.boundsH = width, height
+	if w.events.Bounds != nil {
+		w.events.Bounds(w, width, height)
+	}
+}
+
+func (w *Toplevel) handleClose(*xdgShell.Toplevel) {
+	if w.events.Close != nil {
+		w.events.Close(w)
+	}
+}
+
+func (w *Toplevel) handleConfigure(_ *xdgShell.Surface, serial uint32) {
+	if w.destroyed {
+		return
+	}
+	w.xdgSurface.AckConfigure(serial)
+	w.configured = true
+	w.serial = serial
+
+	old := w.state
+	s := w.pending
+	w.state = s
+
+	width, height := s.Width, s.Height
+	if width == 0 {
+		width = w.floatW
+	}
+	if height == 0 {
+		height = w.floatH
+	}
+	width = clamp(width, w.minW, w.maxW)
+	height = clamp(height, w.minH, w.maxH)
+	if s.floating() {
+		// Remember the size, to restore it when the window stops
+		// being maximized or fullscreen.
+		w.floatW, w.floatH = width, height
+	}
+	resized := width != w.width || height != w.height
+	w.width, w.height = width, height
+
+	if resized && w.events.Resize != nil {
+		w.events.Resize(w, width, height)
+	}
+	if s.Maximized != old.Maximized && w.events.Maximize != nil {
+		w.events.Maximize(w, s.Maximized)
+	}
+	if s.Fullscreen != old.Fullscreen && w.events.Fullscreen != nil {
+		w.events.Fullscreen(w, s.Fullscreen)
+	}
+	if s.Activated != old.Activated && w.events.Activate != nil {
+		w.events.Activate(w, s.Activated)
+	}
+	if w.events.Configure != nil {
+		w.events.Configure(w, s)
+	}
+}
+
+// clamp limits v to [min, max], where zero means no limit.
+func clamp(v, min, max int32) int32 {
+	if max > 0 && v > max {
+		v = max
+	}
+	if min > 0 && v < min {
+		v = min
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+//go:build ignore
+
+// This program generates keysyms.go from the X11 keysym headers.
+//
+// Usage: go run gen.go /usr/include/X11/keysymdef.h /usr/include/X11/XF86keysym.h
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var re = regexp.MustCompile(`^#define\s+(XF86)?XK_([a-zA-Z_0-9]+)\s+0x([0-9a-fA-F]+)\s*(?:/\*\s*(\()?U\+([0-9A-Fa-f]+))?`)
+
+type keysym struct {
+	name  string
+	value uint32
+	// exact Unicode mapping, or -1
+	r int64
+}
+
+func main() {
+	var syms []keysym
+	for _, path := range os.Args[1:] {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			m := re.FindStringSubmatch(sc.Text())
+			if m == nil {
+				continue
+			}
+			v, err := strconv.ParseUint(m[3], 16, 32)
+			if err != nil {
+				log.Fatal(err)
+			}
+			sym := keysym{name: m[1] + m[2], value: uint32(v), r: -1}
+			if m[5] != "" && m[4] == "" {
+				r, err := strconv.ParseInt(m[5], 16, 32)
+				if err != nil {
+					log.Fatal(err)
+				}
+				sym.r = r
+			}
+			syms = append(syms, sym)
+		}
+		if err := sc.Err(); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by gen.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package xkb")
+	fmt.Fprintln(&buf)
+
+	fmt.Fprintln(&buf, "// Keysyms, as defined by keysymdef.h and XF86keysym.h.")
+	fmt.Fprintln(&buf, "const (")
+	for _, sym := range syms {
+		fmt.Fprintf(&buf, "Key%s Keysym = 0x%x\n", sym.name, sym.value)
+	}
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+
+	fmt.Fprintln(&buf, "var keysymsByName = map[string]Keysym{")
+	for _, sym := range syms {
+		fmt.Fprintf(&buf, "%q: 0x%x,\n", sym.name, sym.value)
+	}
+	fmt.Fprintln(&buf, "}")
+	fmt.Fprintln(&buf)
+
+	// The first name defined for a keysym is its canonical name
+	seen := map[uint32]bool{}
+	fmt.Fprintln(&buf, "var keysymNames = map[Keysym]string{")
+	for _, sym := range syms {
+		if seen[sym.value] {
+			continue
+		}
+		seen[sym.value] = true
+		fmt.Fprintf(&buf, "0x%x: %q,\n", sym.value, sym.name)
+	}
+	fmt.Fprintln(&buf, "}")
+	fmt.Fprintln(&buf)
+
+	seen = map[uint32]bool{}
+	fmt.Fprintln(&buf, "// keysymRunes maps keysyms to Unicode code points, excluding")
+	fmt.Fprintln(&buf, "// keysyms that map to code points algorithmically.")
+	fmt.Fprintln(&buf, "var keysymRunes = map[Keysym]rune{")
+	for _, sym := range syms {
+		if sym.r == -1 || seen[sym.value] {
+			continue
+		}
+		if sym.value < 0x100 || (sym.value >= 0x01000000 && sym.value <= 0x0110ffff) {
+			continue
+		}
+		seen[sym.value] = true
+		fmt.Fprintf(&buf, "0x%x: 0x%x,\n", sym.value, sym.r)
+	}
+	fmt.Fprintln(&buf, "}")
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("keysyms.go", out, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,475 @@
+// Package xkb implements keymaps in the XKB text format and the
+// translation of key presses to keysyms and text.
+//
+// Wayland compositors send keymaps in the XKB v1 format produced by
+// xkbcommon, together with the state of modifiers and the active
+// layout. This package parses such keymaps and, given that state,
+// determines the keysyms and text produced by keys. Because the
+// compositor tracks modifier state, State doesn't process key actions
+// itself; it only needs to be updated with wl_keyboard.modifiers.
+//
+// Keycodes use the XKB numbering, which is the evdev numbering used
+// by wl_keyboard.key plus 8.
+package xkb
+
+import (
+	"sort"
+	"strings"
+)
+
+// Keycode is an XKB keycode.
+type Keycode uint32
+
+// Names of the real modifiers, as used by ModIndex and
+// State.ModActive.
+const (
+	ModShift = "Shift"
+	ModCaps  = "Lock"
+	ModCtrl  = "Control"
+	ModAlt   = "Mod1"
+	ModNum   = "Mod2"
+	ModLogo  = "Mod4"
+)
+
+const numRealMods = 8
+
+var realModNames = [numRealMods]string{"Shift", "Lock", "Control", "Mod1", "Mod2", "Mod3", "Mod4", "Mod5"}
+
+// Keymap is a parsed keymap.
+type Keymap struct {
+	min, max Keycode
+
+	keys     map[Keycode]*key
+	keyNames map[string]Keycode
+
+	// mods contains the names of all modifiers; the first eight are
+	// the real modifiers, followed by the virtual modifiers in the
+	// order of their declaration. Indices into mods are the bit
+	// positions used in modifier masks.
+	mods []string
+	// mapping maps virtual modifiers to the real modifiers they are
+	// bound to. Real modifiers map to themselves.
+	mapping      []uint32
+	vmodExplicit []bool
+
+	types      map[string]*keyType
+	groupNames []string
+}
+
+type key struct {
+	name   string
+	code   Keycode
+	groups []group
+	modmap uint32
+	vmods  uint32
+	repeat bool
+}
+
+type group struct {
+	typ    *keyType
+	levels [][]Keysym
+}
+
+type keyType struct {
+	name string
+	// declared is the mask of modifier indices, including virtual
+	// modifiers, and mask the effective mask of real modifiers.
+	declared  uint32
+	mask      uint32
+	entries   []typeEntry
+	numLevels int
+}
+
+type typeEntry struct {
+	declared         uint32
+	declaredPreserve uint32
+	mask             uint32
+	preserve         uint32
+	level            int
+}
+
+// setEntry modifies the entry for a modifier combination, creating it
+// if necessary.
+func (typ *keyType) setEntry(mods uint32, fn func(e *typeEntry)) {
+	for i := range typ.entries {
+		if typ.entries[i].declared == mods {
+			fn(&typ.entries[i])
+			return
+		}
+	}
+	typ.entries = append(typ.entries, typeEntry{declared: mods})
+	fn(&typ.entries[len(typ.entries)-1])
+}
+
+// active reports whether an entry can match. Entries that only refer
+// to unbound virtual modifiers never match.
+func (e *typeEntry) active() bool {
+	return e.declared == 0 || e.mask != 0
+}
+
+// ParseKeymap parses a keymap in the XKB text format. Errors are of
+// type *SyntaxError.
+func ParseKeymap(text string) (km *Keymap, err error) {
+	toks, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+
+	km = &Keymap{
+		min:      8,
+		max:      255,
+		keys:     map[Keycode]*key{},
+		keyNames: map[string]Keycode{},
+		types:    map[string]*keyType{},
+	}
+	for _, name := range realModNames {
+		km.modIndex(name, true)
+	}
+	p := &parser{
+		toks:    toks,
+		km:      km,
+		aliases: map[string]string{},
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if serr, ok := r.(*SyntaxError); ok {
+				km = nil
+				err = serr
+				return
+			}
+			panic(r)
+		}
+	}()
+	p.parseKeymap()
+	p.compile()
+	return km, nil
+}
+
+// modIndex returns the index of the named modifier. Real modifiers
+// are matched case-insensitively. If create is true, unknown names
+// are declared as virtual modifiers; otherwise, -1 is returned.
+func (km *Keymap) modIndex(name string, create bool) int {
+	for i, mod := range km.mods {
+		if mod == name || (i < numRealMods && strings.EqualFold(mod, name)) {
+			return i
+		}
+	}
+	if !create || len(km.mods) == 32 {
+		return -1
+	}
+	km.mods = append(km.mods, name)
+	var mapping uint32
+	if len(km.mods) <= numRealMods {
+		mapping = 1 << uint(len(km.mods)-1)
+	}
+	km.mapping = append(km.mapping, mapping)
+	km.vmodExplicit = append(km.vmodExplicit, false)
+	return len(km.mods) - 1
+}
+
+func (km *Keymap) addKey(name string, code Keycode) {
+	km.keys[code] = &key{name: name, code: code, repeat: true}
+	km.keyNames[name] = code
+}
+
+// resolve maps a mask of modifier indices to a mask of real
+// modifiers.
+func (km *Keymap) resolve(mask uint32) uint32 {
+	out := mask & 0xFF
+	for i := numRealMods; i < len(km.mods); i++ {
+		if mask&(1<<uint(i)) != 0 {
+			out |= km.mapping[i]
+		}
+	}
+	return out
+}
+
+func (p *parser) lookupKey(name string) *key {
+	if real, ok := p.aliases[name]; ok {
+		name = real
+	}
+	code, ok := p.km.keyNames[name]
+	if !ok {
+		return nil
+	}
+	return p.km.keys[code]
+}
+
+// compile resolves the parsed definitions into the final keymap.
+func (p *parser) compile() {
+	km := p.km
+
+	for _, def := range p.keyDefs {
+		k := p.lookupKey(def.name)
+		if k == nil {
+			// symbols for keys that don't exist are ignored
+			continue
+		}
+		n := 0
+		for g := range def.groups {
+			if g+1 > n {
+				n = g + 1
+			}
+		}
+		k.groups = make([]group, n)
+		for g := range k.groups {
+			levels := def.groups[g]
+			name, ok := def.types[g]
+			if !ok {
+				name, ok = def.types[-1]
+			}
+			var typ *keyType
+			if ok {
+				typ = km.types[name]
+			}
+			if typ == nil {
+				typ = km.automaticType(levels)
+			}
+			k.groups[g] = group{typ: typ, levels: levels}
+		}
+		if def.hasRepeat {
+			k.repeat = def.repeat
+		} else {
+			k.repeat = !(len(k.groups) > 0 && len(k.groups[0].levels) > 0 &&
+				len(k.groups[0].levels[0]) == 1 && k.groups[0].levels[0][0].IsModifier())
+		}
+		if def.hasVmods {
+			k.vmods = def.vmods
+		}
+	}
+
+	for _, def := range p.modmapDefs {
+		if !def.isSym {
+			if k := p.lookupKey(def.keyName); k != nil {
+				k.modmap |= 1 << uint(def.mod)
+			}
+			continue
+		}
+		// A keysym refers to the key with the lowest keycode that
+		// has the keysym in its lowest group and level.
+		for _, k := range km.sortedKeys() {
+			if k.hasKeysym(def.sym) {
+				k.modmap |= 1 << uint(def.mod)
+				break
+			}
+		}
+	}
+
+	p.bindVirtualModifiers()
+
+	for _, typ := range km.types {
+		typ.mask = km.resolve(typ.declared)
+		for i := range typ.entries {
+			e := &typ.entries[i]
+			e.mask = km.resolve(e.declared)
+			e.preserve = km.resolve(e.declaredPreserve)
+		}
+	}
+}
+
+// bindVirtualModifiers determines the real modifiers that virtual
+// modifiers are bound to, using the keys' modifier maps and the
+// virtualModifier fields of the compat map's interpretations.
+func (p *parser) bindVirtualModifiers() {
+	km := p.km
+
+	// More specific interpretations take precedence.
+	sort.SliceStable(p.interps, func(i, j int) bool {
+		a, b := p.interps[i], p.interps[j]
+		if a.any != b.any {
+			return !a.any
+		}
+		return a.match < b.match
+	})
+
+	for _, k := range km.sortedKeys() {
+		if k.vmods == 0 {
+			for _, g := range k.groups {
+				for level, syms := range g.levels {
+					for _, sym := range syms {
+						if in := p.findInterp(k, sym, level); in != nil && in.hasVmod {
+							k.vmods |= 1 << uint(in.vmod)
+						}
+					}
+				}
+			}
+		}
+		if k.modmap == 0 {
+			continue
+		}
+		for i := numRealMods; i < len(km.mods); i++ {
+			if k.vmods&(1<<uint(i)) != 0 && !km.vmodExplicit[i] {
+				km.mapping[i] |= k.modmap
+			}
+		}
+	}
+}
+
+func (p *parser) findInterp(k *key, sym Keysym, level int) *interp {
+	for i := range p.interps {
+		in := &p.interps[i]
+		if !in.any && in.sym != sym {
+			continue
+		}
+		mods := k.modmap
+		if in.level1 && level != 0 {
+			mods = 0
+		}
+		var ok bool
+		switch in.match {
+		case matchNoneOf:
+			ok = mods&in.mods == 0
+		case matchAnyOfOrNone:
+			ok = mods == 0 || mods&in.mods != 0
+		case matchAnyOf:
+			ok = mods&in.mods != 0
+		case matchAllOf:
+			ok = mods&in.mods == in.mods
+		case matchExactly:
+			ok = mods == in.mods
+		}
+		if ok {
+			return in
+		}
+	}
+	return nil
+}
+
+func (km *Keymap) sortedKeys() []*key {
+	keys := make([]*key, 0, len(km.keys))
+	for _, k := range km.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].code < keys[j].code })
+	return keys
+}
+
+func (k *key) hasKeysym(sym Keysym) bool {
+	if len(k.groups) == 0 || len(k.groups[0].levels) == 0 {
+		return false
+	}
+	for _, s := range k.groups[0].levels[0] {
+		if s == sym {
+			return true
+		}
+	}
+	return false
+}
+
+// automaticType picks a key type for keys that don't specify one,
+// using the same heuristics as xkbcomp.
+func (km *Keymap) automaticType(levels [][]Keysym) *keyType {
+	sym := func(i int) Keysym {
+		if i < len(levels) && len(levels[i]) > 0 {
+			return levels[i][0]
+		}
+		return NoSymbol
+	}
+	var name string
+	switch n := len(levels); {
+	case n <= 1:
+		name = "ONE_LEVEL"
+	case n == 2:
+		switch {
+		case sym(0).IsLower() && sym(1).IsUpper():
+			name = "ALPHABETIC"
+		case sym(0).IsKeypad() || sym(1).IsKeypad():
+			name = "KEYPAD"
+		default:
+			name = "TWO_LEVEL"
+		}
+	case n <= 4:
+		switch {
+		case sym(0).IsLower() && sym(1).IsUpper():
+			if sym(2).IsLower() && sym(3).IsUpper() {
+				name = "FOUR_LEVEL_ALPHABETIC"
+			} else {
+				name = "FOUR_LEVEL_SEMIALPHABETIC"
+			}
+		case sym(0).IsKeypad() || sym(1).IsKeypad():
+			name = "FOUR_LEVEL_KEYPAD"
+		default:
+			name = "FOUR_LEVEL"
+		}
+	}
+	if typ, ok := km.types[name]; ok {
+		return typ
+	}
+	// The keymap doesn't define the type we want; fall back to a type
+	// that only ever selects the first level.
+	return &keyType{name: name, numLevels: 1}
+}
+
+// MinKeycode returns the smallest keycode the keymap may use.
+func (km *Keymap) MinKeycode() Keycode { return km.min }
+
+// MaxKeycode returns the largest keycode the keymap may use.
+func (km *Keymap) MaxKeycode() Keycode { return km.max }
+
+// KeyByName returns the keycode of the key with the given name, such
+// as "AE01". Aliases aren't resolved.
+func (km *Keymap) KeyByName(name string) (Keycode, bool) {
+	code, ok := km.keyNames[name]
+	return code, ok
+}
+
+// KeyName returns the name of a key, or the empty string if the key
+// doesn't exist.
+func (km *Keymap) KeyName(code Keycode) string {
+	if k, ok := km.keys[code]; ok {
+		return k.name
+	}
+	return ""
+}
+
+// Repeats reports whether a key should repeat when held down.
+func (km *Keymap) Repeats(code Keycode) bool {
+	k, ok := km.keys[code]
+	return ok && k.repeat
+}
+
+// NumMods returns the number of modifiers, real and virtual, that the
+// keymap defines.
+func (km *Keymap) NumMods() int { return len(km.mods) }
+
+// ModName returns the name of the modifier with the given index.
+func (km *Keymap) ModName(idx int) string { return km.mods[idx] }
+
+// ModIndex returns the index of the named modifier, or -1 if the
+// keymap doesn't define it.
+func (km *Keymap) ModIndex(name string) int { return km.modIndex(name, false) }
+
+// NumGroups returns the number of groups (layouts) of the keymap.
+func (km *Keymap) NumGroups() int {
+	n := len(km.groupNames)
+	for _, k := range km.keys {
+		if len(k.groups) > n {
+			n = len(k.groups)
+		}
+	}
+	return n
+}
+
+// GroupName returns the name of a group, such as "English (US)", or
+// the empty string if the group has no name.
+func (km *Keymap) GroupName(group int) string {
+	if group < 0 || group >= len(km.groupNames) {
+		return ""
+	}
+	return km.groupNames[group]
+}
+
+// KeysymsByLevel returns the keysyms of a key in the given group and
+// level. Both group and level start at zero.
+func (km *Keymap) KeysymsByLevel(code Keycode, group, level int) []Keysym {
+	k, ok := km.keys[code]
+	if !ok || group < 0 || group >= len(k.groups) {
+		return nil
+	}
+	levels := k.groups[group].levels
+	if level < 0 || level >= len(levels) {
+		return nil
+	}
+	return levels[level]
+}
@@ -0,0 +1,231 @@
+package xkb
+
+import (
+	"testing"
+)
+
+// testKeymap is a trimmed down version of the keymaps produced by
+// xkbcommon, with two groups and the usual modifier bindings.
+const testKeymap = `
+xkb_keymap {
+xkb_keycodes "test" {
+	minimum = 8;
+	maximum = 255;
+	<ESC>  = 9;
+	<AE01> = 10;
+	<AE02> = 11;
+	<AE03> = 12;
+	<AD01> = 24;
+	<LCTL> = 37;
+	<AC01> = 38;
+	<AC02> = 39;
+	<LFSH> = 50;
+	<AB10> = 61;
+	<LALT> = 64;
+	<SPCE> = 65;
+	<CAPS> = 66;
+	<NMLK> = 77;
+	<KP7>  = 79;
+	<RALT> = 108;
+	alias <LatQ> = <AD01>;
+};
+
+xkb_types "test" {
+	virtual_modifiers NumLock,Alt,LevelThree;
+
+	type "ONE_LEVEL" {
+		modifiers= none;
+		level_name[Level1]= "Any";
+	};
+	type "TWO_LEVEL" {
+		modifiers= Shift;
+		map[Shift]= Level2;
+		level_name[Level1]= "Base";
+		level_name[Level2]= "Shift";
+	};
+	type "ALPHABETIC" {
+		modifiers= Shift+Lock;
+		map[Shift]= Level2;
+		map[Lock]= Level2;
+	};
+	type "KEYPAD" {
+		modifiers= Shift+NumLock;
+		map[None]= Level1;
+		map[Shift]= Level2;
+		map[NumLock]= Level2;
+		map[Shift+NumLock]= Level1;
+	};
+	type "FOUR_LEVEL" {
+		modifiers= Shift+LevelThree;
+		map[Shift]= Level2;
+		map[LevelThree]= Level3;
+		map[Shift+LevelThree]= Level4;
+	};
+	type "CTRL_LEVEL2" {
+		modifiers= Control;
+		map[Control]= Level2;
+	};
+	type "SHIFT_PRESERVE_CTRL" {
+		modifiers= Shift+Control;
+		map[Shift]= Level2;
+		map[Shift+Control]= Level2;
+		preserve[Shift+Control]= Control;
+	};
+};
+
+xkb_compatibility "test" {
+	virtual_modifiers NumLock,Alt,LevelThree;
+
+	interpret.useModMapMods= AnyLevel;
+	interpret.repeat= False;
+
+	interpret Num_Lock+AnyOf(all) {
+		virtualModifier= NumLock;
+		action= LockMods(modifiers=NumLock);
+	};
+	interpret ISO_Level3_Shift+AnyOf(all) {
+		virtualModifier= LevelThree;
+		useModMapMods=level1;
+		action= SetMods(modifiers=LevelThree,clearLocks);
+	};
+	interpret Alt_L+AnyOf(all) {
+		virtualModifier= Alt;
+		action= SetMods(modifiers=modMapMods,clearLocks);
+	};
+	interpret Any+AnyOf(all) {
+		action= SetMods(modifiers=modMapMods,clearLocks);
+	};
+};
+
+xkb_symbols "test" {
+	name[group1]="English (US)";
+	name[group2]="Russian";
+
+	key <ESC>  { [ Escape ] };
+	key <AE01> { [ 1, exclam ], [ 1, exclam ] };
+	key <AE02> { [ 2, at ] };
+	key <AE03> { type[group1]= "SHIFT_PRESERVE_CTRL", [ 3, numbersign ] };
+	key <AD01> { type= "FOUR_LEVEL", [ q, Q, at, Greek_OMEGA ] };
+	key <LCTL> { [ Control_L ] };
+	key <AC01> { [ a, A ], [ Cyrillic_ef, Cyrillic_EF ] };
+	key <AC02> { type= "CTRL_LEVEL2", [ s, ssharp ] };
+	key <LFSH> { [ Shift_L ] };
+	key <AB10> { [ slash, question ] };
+	key <LALT> { [ Alt_L, Meta_L ] };
+	key <SPCE> { repeat= No, [ space ] };
+	key <CAPS> { [ Caps_Lock ] };
+	key <NMLK> { [ Num_Lock ] };
+	key <KP7>  { [ KP_Home, KP_7 ] };
+	key <RALT> { type= "ONE_LEVEL", symbols[Group1]= [ ISO_Level3_Shift ] };
+
+	modifier_map Shift { <LFSH> };
+	modifier_map Lock { <CAPS> };
+	modifier_map Control { <LCTL> };
+	modifier_map Mod1 { <LALT> };
+	modifier_map Mod2 { Num_Lock };
+	modifier_map Mod5 { <RALT> };
+};
+};
+`
+
+// Masks of the real modifiers.
+const (
+	shift = 1 << 0
+	lock  = 1 << 1
+	ctrl  = 1 << 2
+	mod1  = 1 << 3
+	mod2  = 1 << 4
+	mod5  = 1 << 7
+)
+
+func parseTestKeymap(t *testing.T) *Keymap {
+	t.Helper()
+	km, err := ParseKeymap(testKeymap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+func keycode(t *testing.T, km *Keymap, name string) Keycode {
+	t.Helper()
+	code, ok := km.KeyByName(name)
+	if !ok {
+		t.Fatalf("key %s doesn't exist", name)
+	}
+	return code
+}
+
+func TestParseKeymap(t *testing.T) {
+	km := parseTestKeymap(t)
+
+	if got := keycode(t, km, "AC01"); got != 38 {
+		t.Errorf("KeyByName(AC01) = %d, want 38", got)
+	}
+	if got := km.KeyName(24); got != "AD01" {
+		t.Errorf("KeyName(24) = %q, want AD01", got)
+	}
+	if got := km.NumGroups(); got != 2 {
+		t.Errorf("NumGroups() = %d, want 2", got)
+	}
+	if got := km.GroupName(1); got != "Russian" {
+		t.Errorf("GroupName(1) = %q, want Russian", got)
+	}
+	if !km.Repeats(keycode(t, km, "AC01")) {
+		t.Error("AC01 doesn't repeat")
+	}
+	if km.Repeats(keycode(t, km, "LFSH")) {
+		t.Error("modifier key LFSH repeats")
+	}
+	if km.Repeats(keycode(t, km, "SPCE")) {
+		t.Error("SPCE repeats despite repeat= No")
+	}
+
+	// Virtual modifiers are bound to the real modifiers of the keys
+	// that their interpretations match.
+	for _, tt := range []struct {
+		vmod string
+		mask uint32
+	}{
+		{"NumLock", mod2},
+		{"Alt", mod1},
+		{"LevelThree", mod5},
+	} {
+		idx := km.ModIndex(tt.vmod)
+		if idx < numRealMods {
+			t.Errorf("ModIndex(%s) = %d, want a virtual modifier", tt.vmod, idx)
+			continue
+		}
+		if got := km.resolve(1 << uint(idx)); got != tt.mask {
+			t.Errorf("%s is bound to %#x, want %#x", tt.vmod, got, tt.mask)
+		}
+	}
+
+	levels := []string{"q", "Q", "at", "Greek_OMEGA"}
+	for i, want := range levels {
+		syms := km.KeysymsByLevel(keycode(t, km, "AD01"), 0, i)
+		if len(syms) != 1 || syms[0].String() != want {
+			t.Errorf("KeysymsByLevel(AD01, 0, %d) = %v, want %s", i, syms, want)
+		}
+	}
+	if syms := km.KeysymsByLevel(keycode(t, km, "AD01"), 1, 0); syms != nil {
+		t.Errorf("KeysymsByLevel(AD01, 1, 0) = %v, want none", syms)
+	}
+}
+
+func TestParseKeymapErrors(t *testing.T) {
+	tests := []string{
+		`xkb_keymap {`,
+		`xkb_keymap { xkb_keycodes { <AE01> = ; }; };`,
+		`xkb_keymap { xkb_types { type "X" { map[Shift] = Level0; }; }; };`,
+		`xkb_keymap { xkb_symbols { modifier_map Alt { <LALT> }; }; };`,
+		`xkb_keymap { xkb_symbols { key <AE01> { [ 1 }; }; };`,
+		`xkb_keymap { }; trailing`,
+	}
+	for _, text := range tests {
+		km, err := ParseKeymap(text)
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("ParseKeymap(%q) = %v, %v, want *SyntaxError", text, km, err)
+		}
+	}
+}
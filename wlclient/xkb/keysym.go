@@ -0,0 +1,143 @@
+package xkb
+
+//go:generate go run gen.go /usr/include/X11/keysymdef.h /usr/include/X11/XF86keysym.h
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Keysym is an X11 keysym, identifying the symbol on a key.
+type Keysym uint32
+
+// NoSymbol is the keysym of levels that have no symbol.
+const NoSymbol Keysym = 0
+
+// KeysymFromName returns the keysym with the given name. In addition
+// to the names defined by keysymdef.h, it accepts Unicode keysyms of
+// the form U20AC and numeric keysyms of the form 0x1008ff11. It
+// returns false if the name is unknown.
+func KeysymFromName(name string) (Keysym, bool) {
+	if sym, ok := keysymsByName[name]; ok {
+		return sym, true
+	}
+	if len(name) >= 2 && name[0] == 'U' {
+		cp, err := strconv.ParseUint(name[1:], 16, 32)
+		if err == nil && cp <= unicode.MaxRune {
+			return KeysymFromRune(rune(cp)), true
+		}
+	}
+	if strings.HasPrefix(name, "0x") {
+		v, err := strconv.ParseUint(name[2:], 16, 32)
+		if err == nil {
+			return Keysym(v), true
+		}
+	}
+	return NoSymbol, false
+}
+
+// KeysymFromRune returns the keysym for a Unicode code point.
+func KeysymFromRune(r rune) Keysym {
+	if (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff) {
+		return Keysym(r)
+	}
+	return Keysym(0x01000000 + r)
+}
+
+func (sym Keysym) String() string {
+	if name, ok := keysymNames[sym]; ok {
+		return name
+	}
+	if sym >= 0x01000100 && sym <= 0x0110ffff {
+		return fmt.Sprintf("U%04X", uint32(sym)-0x01000000)
+	}
+	return fmt.Sprintf("0x%08x", uint32(sym))
+}
+
+// Rune returns the Unicode code point that sym represents. It returns
+// false for keysyms without a textual representation, such as
+// function keys.
+func (sym Keysym) Rune() (rune, bool) {
+	// Latin-1 maps 1:1
+	if (sym >= 0x20 && sym <= 0x7e) || (sym >= 0xa0 && sym <= 0xff) {
+		return rune(sym), true
+	}
+	if sym == KeyKP_Space {
+		return ' ', true
+	}
+	if (sym >= KeyBackSpace && sym <= KeyClear) ||
+		(sym >= KeyKP_Multiply && sym <= KeyKP_9) ||
+		sym == KeyReturn || sym == KeyEscape || sym == KeyDelete ||
+		sym == KeyKP_Tab || sym == KeyKP_Enter || sym == KeyKP_Equal {
+		return rune(sym & 0x7f), true
+	}
+	if sym >= 0x01000000 && sym <= 0x0110ffff {
+		return rune(sym - 0x01000000), true
+	}
+	r, ok := keysymRunes[sym]
+	return r, ok
+}
+
+// IsKeypad reports whether sym is on the numeric keypad.
+func (sym Keysym) IsKeypad() bool {
+	return sym >= KeyKP_Space && sym <= KeyKP_Equal
+}
+
+// IsModifier reports whether sym is a modifier key, such as Shift_L.
+func (sym Keysym) IsModifier() bool {
+	return (sym >= KeyShift_L && sym <= KeyHyper_R) ||
+		(sym >= KeyISO_Lock && sym <= KeyISO_Level5_Lock) ||
+		sym == KeyMode_switch || sym == KeyNum_Lock
+}
+
+// IsLower reports whether sym is a lowercase letter.
+func (sym Keysym) IsLower() bool {
+	r, ok := sym.Rune()
+	return ok && unicode.IsLower(r) && unicode.ToUpper(r) != r
+}
+
+// IsUpper reports whether sym is an uppercase letter.
+func (sym Keysym) IsUpper() bool {
+	r, ok := sym.Rune()
+	return ok && unicode.IsUpper(r) && unicode.ToLower(r) != r
+}
+
+// ToUpper returns the uppercase version of sym, or sym itself if it
+// has no uppercase version.
+func (sym Keysym) ToUpper() Keysym {
+	r, ok := sym.Rune()
+	if !ok {
+		return sym
+	}
+	u := unicode.ToUpper(r)
+	if u == r {
+		return sym
+	}
+	// Prefer legacy keysyms, which is what keymaps use, if one exists.
+	if up, ok := runeKeysyms()[u]; ok {
+		return up
+	}
+	return KeysymFromRune(u)
+}
+
+var (
+	runeKeysymsOnce sync.Once
+	runeKeysymsMap  map[rune]Keysym
+)
+
+// runeKeysyms returns the inverse of keysymRunes.
+func runeKeysyms() map[rune]Keysym {
+	runeKeysymsOnce.Do(func() {
+		m := make(map[rune]Keysym, len(keysymRunes))
+		for sym, r := range keysymRunes {
+			if old, ok := m[r]; !ok || sym < old {
+				m[r] = sym
+			}
+		}
+		runeKeysymsMap = m
+	})
+	return runeKeysymsMap
+}
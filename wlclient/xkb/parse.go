@@ -0,0 +1,765 @@
+package xkb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file implements a parser for the XKB text format, as produced
+// by xkbcommon's xkb_keymap_get_as_string and sent by compositors via
+// wl_keyboard.keymap. Such keymaps are fully resolved: they contain
+// no include statements and no merge modes, which greatly simplifies
+// parsing. Statements that don't affect keysym lookup, such as
+// actions and indicators, are parsed only as far as necessary to skip
+// them.
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokKeyName
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  int64
+	line int
+}
+
+func (tok token) String() string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return strconv.Quote(tok.text)
+	case tokKeyName:
+		return "<" + tok.text + ">"
+	default:
+		return tok.text
+	}
+}
+
+// SyntaxError describes a problem with the text of a keymap.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("xkb: line %d: %s", err.Line, err.Msg)
+}
+
+func isIdentStart(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b == '_'
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		b := src[i]
+		switch {
+		case b == '\n':
+			line++
+			i++
+		case b == ' ' || b == '\t' || b == '\r' || b == 0:
+			// keymaps sent by compositors are NUL terminated
+			i++
+		case b == '#' || (b == '/' && i+1 < len(src) && src[i+1] == '/'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case b == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return nil, &SyntaxError{line, "unterminated comment"}
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case isIdentStart(b):
+			j := i
+			for j < len(src) && (isIdentStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], line: line})
+			i = j
+		case isDigit(b):
+			j := i
+			base := 10
+			if b == '0' && i+1 < len(src) && (src[i+1] == 'x' || src[i+1] == 'X') {
+				base = 16
+				j += 2
+			}
+			start := j
+			for j < len(src) && (isDigit(src[j]) || (base == 16 && strings.IndexByte("abcdefABCDEF", src[j]) != -1)) {
+				j++
+			}
+			n, err := strconv.ParseInt(src[start:j], base, 64)
+			if err != nil {
+				return nil, &SyntaxError{line, fmt.Sprintf("invalid number %q", src[i:j])}
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[i:j], num: n, line: line})
+			i = j
+		case b == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[j])
+					}
+					continue
+				}
+				if src[j] == '\n' {
+					line++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j == len(src) {
+				return nil, &SyntaxError{line, "unterminated string"}
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String(), line: line})
+			i = j + 1
+		case b == '<':
+			end := strings.IndexByte(src[i:], '>')
+			if end == -1 {
+				return nil, &SyntaxError{line, "unterminated key name"}
+			}
+			toks = append(toks, token{kind: tokKeyName, text: src[i+1 : i+end], line: line})
+			i += end + 1
+		case strings.IndexByte("{}[]();,=+-!~.*", b) != -1:
+			toks = append(toks, token{kind: tokPunct, text: src[i : i+1], line: line})
+			i++
+		default:
+			return nil, &SyntaxError{line, fmt.Sprintf("unexpected character %q", b)}
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, line: line})
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+	km   *Keymap
+
+	// intermediate state that gets resolved once parsing is done
+	aliases    map[string]string
+	interps    []interp
+	keyDefs    []*keyDef
+	modmapDefs []modmapDef
+}
+
+type interp struct {
+	sym      Keysym
+	any      bool
+	match    matchOp
+	mods     uint32
+	vmod     int
+	level1   bool
+	hasVmod  bool
+	sequence int
+}
+
+type matchOp uint8
+
+const (
+	matchExactly matchOp = iota
+	matchAllOf
+	matchNoneOf
+	matchAnyOf
+	matchAnyOfOrNone
+)
+
+type keyDef struct {
+	name      string
+	types     map[int]string
+	groups    map[int][][]Keysym
+	vmods     uint32
+	hasVmods  bool
+	repeat    bool
+	hasRepeat bool
+	nextGroup int
+	line      int
+}
+
+type modmapDef struct {
+	mod     int
+	keyName string
+	sym     Keysym
+	isSym   bool
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) fail(tok token, format string, args ...interface{}) {
+	panic(&SyntaxError{tok.line, fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) isPunct(s string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.text == s
+}
+
+func (p *parser) accept(s string) bool {
+	if p.isPunct(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) {
+	if tok := p.next(); tok.kind != tokPunct || tok.text != s {
+		p.fail(tok, "expected %q, got %s", s, tok)
+	}
+}
+
+func (p *parser) expectKind(kind tokenKind, what string) token {
+	tok := p.next()
+	if tok.kind != kind {
+		p.fail(tok, "expected %s, got %s", what, tok)
+	}
+	return tok
+}
+
+// skip skips tokens up to and including the next semicolon that isn't
+// nested in brackets.
+func (p *parser) skipStatement() {
+	depth := 0
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == tokEOF:
+			p.fail(tok, "unexpected end of file")
+		case tok.kind != tokPunct:
+		case tok.text == "{" || tok.text == "[" || tok.text == "(":
+			depth++
+		case tok.text == "}" || tok.text == "]" || tok.text == ")":
+			depth--
+			if depth < 0 {
+				p.fail(tok, "unbalanced %q", tok.text)
+			}
+		case tok.text == ";" && depth == 0:
+			return
+		}
+	}
+}
+
+// skipValue skips tokens up to, but not including, the next comma or
+// closing brace that isn't nested in brackets.
+func (p *parser) skipValue() {
+	depth := 0
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == tokEOF:
+			p.fail(tok, "unexpected end of file")
+		case tok.kind != tokPunct:
+		case tok.text == "{" || tok.text == "[" || tok.text == "(":
+			depth++
+		case tok.text == "}" || tok.text == "]" || tok.text == ")":
+			if depth == 0 {
+				return
+			}
+			depth--
+		case tok.text == "," && depth == 0:
+			return
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseKeymap() {
+	tok := p.peek()
+	if tok.kind == tokIdent && tok.text == "xkb_keymap" {
+		p.next()
+		if p.peek().kind == tokString {
+			p.next()
+		}
+		p.expect("{")
+		for !p.accept("}") {
+			p.parseSection()
+		}
+		p.accept(";")
+	} else {
+		// a bare list of sections
+		for p.peek().kind != tokEOF {
+			p.parseSection()
+		}
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		p.fail(tok, "unexpected %s after keymap", tok)
+	}
+}
+
+func (p *parser) parseSection() {
+	// skip flags such as "default" or "partial"
+	var kind token
+	for {
+		kind = p.expectKind(tokIdent, "section")
+		if strings.HasPrefix(kind.text, "xkb_") {
+			break
+		}
+	}
+	if p.peek().kind == tokString {
+		p.next()
+	}
+	p.expect("{")
+	for !p.accept("}") {
+		switch kind.text {
+		case "xkb_keycodes":
+			p.parseKeycodesStatement()
+		case "xkb_types":
+			p.parseTypesStatement()
+		case "xkb_compatibility", "xkb_compatibility_map", "xkb_compat":
+			p.parseCompatStatement()
+		case "xkb_symbols":
+			p.parseSymbolsStatement()
+		default:
+			p.skipStatement()
+		}
+	}
+	p.accept(";")
+}
+
+func (p *parser) parseKeycodesStatement() {
+	tok := p.peek()
+	switch {
+	case tok.kind == tokKeyName:
+		p.next()
+		p.expect("=")
+		code := p.expectKind(tokNumber, "keycode")
+		p.expect(";")
+		p.km.addKey(tok.text, Keycode(code.num))
+	case tok.kind == tokIdent && (tok.text == "minimum" || tok.text == "maximum"):
+		p.next()
+		p.expect("=")
+		n := p.expectKind(tokNumber, "keycode")
+		p.expect(";")
+		if tok.text == "minimum" {
+			p.km.min = Keycode(n.num)
+		} else {
+			p.km.max = Keycode(n.num)
+		}
+	case tok.kind == tokIdent && tok.text == "alias":
+		p.next()
+		alias := p.expectKind(tokKeyName, "key name")
+		p.expect("=")
+		real := p.expectKind(tokKeyName, "key name")
+		p.expect(";")
+		p.aliases[alias.text] = real.text
+	default:
+		p.skipStatement()
+	}
+}
+
+func (p *parser) parseVirtualModifiers() {
+	for {
+		name := p.expectKind(tokIdent, "modifier name")
+		idx := p.km.modIndex(name.text, true)
+		if p.accept("=") {
+			p.km.vmodExplicit[idx] = true
+			p.km.mapping[idx] = p.parseMods() & 0xFF
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+}
+
+// parseMods parses a modifier expression such as Shift+LevelThree and
+// returns the mask of modifier indices.
+func (p *parser) parseMods() uint32 {
+	var mask uint32
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == tokIdent:
+			switch strings.ToLower(tok.text) {
+			case "none":
+			case "all":
+				mask |= 1<<uint(len(p.km.mods)) - 1
+			default:
+				mask |= 1 << uint(p.km.modIndex(tok.text, true))
+			}
+		case tok.kind == tokNumber:
+			mask |= uint32(tok.num)
+		default:
+			p.fail(tok, "expected modifier, got %s", tok)
+		}
+		if !p.accept("+") && !p.accept("|") {
+			return mask
+		}
+	}
+}
+
+func (p *parser) parseLevel() int {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		if tok.num < 1 {
+			p.fail(tok, "invalid level %d", tok.num)
+		}
+		return int(tok.num) - 1
+	case tokIdent:
+		if n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(tok.text), "level")); err == nil && n >= 1 {
+			return n - 1
+		}
+	}
+	p.fail(tok, "expected level, got %s", tok)
+	panic("unreachable")
+}
+
+func (p *parser) parseGroup() int {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		if tok.num >= 1 {
+			return int(tok.num) - 1
+		}
+	case tokIdent:
+		if n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(tok.text), "group")); err == nil && n >= 1 {
+			return n - 1
+		}
+	}
+	p.fail(tok, "expected group, got %s", tok)
+	panic("unreachable")
+}
+
+func (p *parser) parseTypesStatement() {
+	tok := p.peek()
+	if tok.kind != tokIdent {
+		p.skipStatement()
+		return
+	}
+	switch tok.text {
+	case "virtual_modifiers":
+		p.next()
+		p.parseVirtualModifiers()
+	case "type":
+		p.next()
+		name := p.expectKind(tokString, "type name")
+		typ := &keyType{name: name.text}
+		p.expect("{")
+		for !p.accept("}") {
+			p.parseTypeStatement(typ)
+		}
+		p.expect(";")
+		p.km.types[typ.name] = typ
+	default:
+		p.skipStatement()
+	}
+}
+
+func (p *parser) parseTypeStatement(typ *keyType) {
+	tok := p.peek()
+	if tok.kind != tokIdent {
+		p.skipStatement()
+		return
+	}
+	switch strings.ToLower(tok.text) {
+	case "modifiers":
+		p.next()
+		p.expect("=")
+		typ.declared = p.parseMods()
+		p.expect(";")
+	case "map":
+		p.next()
+		p.expect("[")
+		mods := p.parseMods()
+		p.expect("]")
+		p.expect("=")
+		level := p.parseLevel()
+		p.expect(";")
+		typ.setEntry(mods, func(e *typeEntry) { e.level = level })
+		if level+1 > typ.numLevels {
+			typ.numLevels = level + 1
+		}
+	case "preserve":
+		p.next()
+		p.expect("[")
+		mods := p.parseMods()
+		p.expect("]")
+		p.expect("=")
+		preserve := p.parseMods()
+		p.expect(";")
+		typ.setEntry(mods, func(e *typeEntry) { e.declaredPreserve = preserve })
+	default:
+		p.skipStatement()
+	}
+}
+
+func (p *parser) parseCompatStatement() {
+	tok := p.peek()
+	if tok.kind != tokIdent {
+		p.skipStatement()
+		return
+	}
+	switch tok.text {
+	case "virtual_modifiers":
+		p.next()
+		p.parseVirtualModifiers()
+	case "interpret":
+		p.next()
+		if p.isPunct(".") {
+			// default values
+			p.skipStatement()
+			return
+		}
+		p.parseInterpret()
+	default:
+		p.skipStatement()
+	}
+}
+
+func (p *parser) parseInterpret() {
+	in := interp{match: matchAnyOfOrNone, mods: 0xFF, vmod: -1, sequence: len(p.interps)}
+	tok := p.next()
+	switch {
+	case tok.kind == tokIdent && tok.text == "Any":
+		in.any = true
+	case tok.kind == tokIdent || tok.kind == tokNumber:
+		in.sym = p.keysym(tok)
+	default:
+		p.fail(tok, "expected keysym, got %s", tok)
+	}
+	if p.accept("+") {
+		tok := p.peek()
+		ops := map[string]matchOp{
+			"exactly":     matchExactly,
+			"allof":       matchAllOf,
+			"noneof":      matchNoneOf,
+			"anyof":       matchAnyOf,
+			"anyofornone": matchAnyOfOrNone,
+		}
+		if op, ok := ops[strings.ToLower(tok.text)]; ok && tok.kind == tokIdent && p.toks[p.pos+1].text == "(" {
+			p.next()
+			p.expect("(")
+			in.match = op
+			in.mods = p.parseMods() & 0xFF
+			p.expect(")")
+		} else {
+			in.match = matchExactly
+			in.mods = p.parseMods() & 0xFF
+		}
+	}
+	p.expect("{")
+	for !p.accept("}") {
+		tok := p.peek()
+		if tok.kind != tokIdent {
+			p.skipStatement()
+			continue
+		}
+		switch strings.ToLower(tok.text) {
+		case "virtualmodifier", "virtualmod":
+			p.next()
+			p.expect("=")
+			name := p.expectKind(tokIdent, "virtual modifier")
+			p.expect(";")
+			in.vmod = p.km.modIndex(name.text, true)
+			in.hasVmod = true
+		case "usemodmapmods", "usemodmap":
+			p.next()
+			p.expect("=")
+			v := p.expectKind(tokIdent, "level")
+			p.expect(";")
+			in.level1 = strings.EqualFold(v.text, "level1") || strings.EqualFold(v.text, "levelone")
+		default:
+			p.skipStatement()
+		}
+	}
+	p.expect(";")
+	p.interps = append(p.interps, in)
+}
+
+func (p *parser) keysym(tok token) Keysym {
+	switch tok.kind {
+	case tokNumber:
+		if tok.num < 10 && !strings.HasPrefix(tok.text, "0x") {
+			// digits are keysyms, not keysym values
+			return Key0 + Keysym(tok.num)
+		}
+		return Keysym(tok.num)
+	case tokIdent:
+		sym, ok := KeysymFromName(tok.text)
+		if !ok {
+			// Unknown keysyms are treated as NoSymbol, like xkbcommon does.
+			return NoSymbol
+		}
+		return sym
+	default:
+		p.fail(tok, "expected keysym, got %s", tok)
+		panic("unreachable")
+	}
+}
+
+func (p *parser) parseSymbolsStatement() {
+	tok := p.peek()
+	if tok.kind != tokIdent {
+		p.skipStatement()
+		return
+	}
+	switch strings.ToLower(tok.text) {
+	case "name", "groupname":
+		p.next()
+		p.expect("[")
+		g := p.parseGroup()
+		p.expect("]")
+		p.expect("=")
+		name := p.expectKind(tokString, "group name")
+		p.expect(";")
+		for len(p.km.groupNames) <= g {
+			p.km.groupNames = append(p.km.groupNames, "")
+		}
+		p.km.groupNames[g] = name.text
+	case "key":
+		p.next()
+		p.parseKey()
+	case "modifier_map", "modmap", "mod_map":
+		p.next()
+		modTok := p.expectKind(tokIdent, "modifier")
+		mod := p.km.modIndex(modTok.text, false)
+		if mod == -1 || mod >= numRealMods {
+			p.fail(modTok, "%s is not a real modifier", modTok.text)
+		}
+		p.expect("{")
+		for !p.accept("}") {
+			tok := p.next()
+			if tok.kind == tokKeyName {
+				p.modmapDefs = append(p.modmapDefs, modmapDef{mod: mod, keyName: tok.text})
+			} else {
+				p.modmapDefs = append(p.modmapDefs, modmapDef{mod: mod, sym: p.keysym(tok), isSym: true})
+			}
+			if !p.accept(",") {
+				p.expect("}")
+				break
+			}
+		}
+		p.expect(";")
+	default:
+		p.skipStatement()
+	}
+}
+
+func (p *parser) parseKey() {
+	name := p.expectKind(tokKeyName, "key name")
+	def := &keyDef{
+		name:   name.text,
+		types:  map[int]string{},
+		groups: map[int][][]Keysym{},
+		line:   name.line,
+	}
+	p.expect("{")
+	for !p.accept("}") {
+		p.parseKeyItem(def)
+		if !p.accept(",") {
+			p.expect("}")
+			break
+		}
+	}
+	p.expect(";")
+	p.keyDefs = append(p.keyDefs, def)
+}
+
+func (p *parser) parseKeyItem(def *keyDef) {
+	if p.isPunct("[") {
+		def.groups[def.nextGroup] = p.parseSymList()
+		def.nextGroup++
+		return
+	}
+
+	tok := p.expectKind(tokIdent, "key field")
+	group := -1
+	if p.accept("[") {
+		group = p.parseGroup()
+		p.expect("]")
+	}
+	p.expect("=")
+	switch strings.ToLower(tok.text) {
+	case "type":
+		name := p.expectKind(tokString, "type name")
+		def.types[group] = name.text
+	case "symbols":
+		if group == -1 {
+			group = def.nextGroup
+		}
+		def.groups[group] = p.parseSymList()
+		if group >= def.nextGroup {
+			def.nextGroup = group + 1
+		}
+	case "virtualmods", "virtualmodifiers", "vmods":
+		def.vmods = p.parseMods() &^ 0xFF
+		def.hasVmods = true
+	case "repeat", "repeats", "repeating":
+		v := p.expectKind(tokIdent, "boolean")
+		switch strings.ToLower(v.text) {
+		case "yes", "true", "on":
+			def.repeat = true
+		case "no", "false", "off":
+			def.repeat = false
+		default:
+			// "default" and unknown values keep the automatic behaviour
+			return
+		}
+		def.hasRepeat = true
+	default:
+		p.skipValue()
+	}
+}
+
+func (p *parser) parseSymList() [][]Keysym {
+	var levels [][]Keysym
+	p.expect("[")
+	if p.accept("]") {
+		return nil
+	}
+	for {
+		if p.accept("{") {
+			var syms []Keysym
+			for !p.accept("}") {
+				syms = append(syms, p.keysym(p.next()))
+				if !p.accept(",") {
+					p.expect("}")
+					break
+				}
+			}
+			levels = append(levels, syms)
+		} else {
+			sym := p.keysym(p.next())
+			if sym == NoSymbol {
+				levels = append(levels, nil)
+			} else {
+				levels = append(levels, []Keysym{sym})
+			}
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect("]")
+	return levels
+}
@@ -0,0 +1,191 @@
+package xkb
+
+import (
+	"unicode/utf8"
+)
+
+// State combines a keymap with the current state of modifiers and
+// the active group, to determine the keysyms produced by keys.
+type State struct {
+	km *Keymap
+
+	depressed uint32
+	latched   uint32
+	locked    uint32
+	group     uint32
+
+	// effective real modifiers
+	mods uint32
+}
+
+// NewState returns a state for km with no active modifiers and the
+// first group active.
+func NewState(km *Keymap) *State {
+	return &State{km: km}
+}
+
+// Keymap returns the state's keymap.
+func (s *State) Keymap() *Keymap { return s.km }
+
+// UpdateMask updates the state with the modifier masks and effective
+// group, as provided by wl_keyboard.modifiers.
+func (s *State) UpdateMask(depressed, latched, locked, group uint32) {
+	s.depressed = depressed
+	s.latched = latched
+	s.locked = locked
+	s.group = group
+	s.mods = s.km.resolve(depressed | latched | locked)
+}
+
+// Mods returns the mask of active real modifiers.
+func (s *State) Mods() uint32 { return s.mods }
+
+// ModActive reports whether the named modifier is active. For
+// virtual modifiers such as Alt, this is the case if any of the real
+// modifiers it is bound to are active.
+func (s *State) ModActive(name string) bool {
+	idx := s.km.ModIndex(name)
+	if idx == -1 {
+		return false
+	}
+	mask := s.km.mapping[idx]
+	return mask != 0 && s.mods&mask != 0
+}
+
+// ModLocked reports whether the named modifier is locked, such as
+// Lock while Caps Lock is on.
+func (s *State) ModLocked(name string) bool {
+	idx := s.km.ModIndex(name)
+	if idx == -1 {
+		return false
+	}
+	mask := s.km.mapping[idx]
+	return mask != 0 && s.km.resolve(s.locked)&mask != 0
+}
+
+// Group returns the group that is active for a key. Keys that have
+// fewer groups than the active group wrap around.
+func (s *State) Group(code Keycode) int {
+	k, ok := s.km.keys[code]
+	if !ok || len(k.groups) == 0 {
+		return 0
+	}
+	return int(s.group % uint32(len(k.groups)))
+}
+
+// entry returns the type entry that matches the current state, or nil.
+func (s *State) entry(code Keycode) (*group, *typeEntry) {
+	k, ok := s.km.keys[code]
+	if !ok || len(k.groups) == 0 {
+		return nil, nil
+	}
+	g := &k.groups[s.Group(code)]
+	mods := s.mods & g.typ.mask
+	for i := range g.typ.entries {
+		e := &g.typ.entries[i]
+		if e.active() && e.mask == mods {
+			return g, e
+		}
+	}
+	return g, nil
+}
+
+// Level returns the shift level that is active for a key.
+func (s *State) Level(code Keycode) int {
+	_, e := s.entry(code)
+	if e == nil {
+		return 0
+	}
+	return e.level
+}
+
+// ConsumedMods returns the mask of real modifiers that were used to
+// select the key's level. Consumed modifiers shouldn't be interpreted
+// any further, e.g. Shift in Shift+1 when matching keyboard shortcuts.
+func (s *State) ConsumedMods(code Keycode) uint32 {
+	g, e := s.entry(code)
+	if g == nil {
+		return 0
+	}
+	if e == nil {
+		return g.typ.mask
+	}
+	return g.typ.mask &^ e.preserve
+}
+
+// Keysyms returns the keysyms produced by a key. Most keys produce a
+// single keysym, or none at all.
+//
+// If Caps Lock is active and the key's type doesn't consume it, the
+// keysym is converted to uppercase.
+func (s *State) Keysyms(code Keycode) []Keysym {
+	g, e := s.entry(code)
+	if g == nil {
+		return nil
+	}
+	level := 0
+	if e != nil {
+		level = e.level
+	}
+	if level >= len(g.levels) {
+		return nil
+	}
+	syms := g.levels[level]
+	if len(syms) == 1 && s.mods&(1<<1) != 0 && s.ConsumedMods(code)&(1<<1) == 0 {
+		if up := syms[0].ToUpper(); up != syms[0] {
+			return []Keysym{up}
+		}
+	}
+	return syms
+}
+
+// Keysym returns the keysym produced by a key, or NoSymbol if the key
+// produces no keysym or more than one.
+func (s *State) Keysym(code Keycode) Keysym {
+	syms := s.Keysyms(code)
+	if len(syms) != 1 {
+		return NoSymbol
+	}
+	return syms[0]
+}
+
+// Text returns the text produced by a key, in UTF-8. It is empty for
+// keys that don't produce text, such as function keys.
+//
+// If Control is active and not consumed, keys that produce ASCII
+// letters and some punctuation produce the corresponding control
+// characters, like terminals expect.
+func (s *State) Text(code Keycode) string {
+	syms := s.Keysyms(code)
+	ctrl := s.mods&(1<<2) != 0 && s.ConsumedMods(code)&(1<<2) == 0
+	var buf []byte
+	for _, sym := range syms {
+		r, ok := sym.Rune()
+		if !ok {
+			continue
+		}
+		if ctrl && len(syms) == 1 {
+			r = toControl(r)
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return string(buf)
+}
+
+// toControl implements the control character transformation.
+func toControl(r rune) rune {
+	switch {
+	case (r >= '@' && r < '\177') || r == ' ':
+		return r & 0x1f
+	case r == '2':
+		return 0
+	case r >= '3' && r <= '7':
+		return r - ('3' - '\033')
+	case r == '8':
+		return '\177'
+	case r == '/':
+		return '_' & 0x1f
+	default:
+		return r
+	}
+}
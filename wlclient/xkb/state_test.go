@@ -0,0 +1,159 @@
+package xkb
+
+import (
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	km := parseTestKeymap(t)
+	tests := []struct {
+		key    string
+		mods   uint32
+		locked uint32
+		group  uint32
+
+		keysym   string
+		level    int
+		consumed uint32
+		text     string
+	}{
+		// Alphabetic keys consume Shift and Lock, which cancel each
+		// other out.
+		{key: "AC01", keysym: "a", level: 0, consumed: shift | lock, text: "a"},
+		{key: "AC01", mods: shift, keysym: "A", level: 1, consumed: shift | lock, text: "A"},
+		{key: "AC01", locked: lock, keysym: "A", level: 1, consumed: shift | lock, text: "A"},
+		{key: "AC01", mods: shift, locked: lock, keysym: "a", level: 0, consumed: shift | lock, text: "a"},
+
+		// Caps Lock turns keysyms into uppercase when the key's type
+		// doesn't consume Lock.
+		{key: "AD01", locked: lock, keysym: "Q", level: 0, consumed: shift | mod5, text: "Q"},
+		{key: "AD01", mods: shift, locked: lock, keysym: "Q", level: 1, consumed: shift | mod5, text: "Q"},
+		{key: "AE02", locked: lock, keysym: "2", level: 0, consumed: shift, text: "2"},
+		{key: "AB10", mods: shift, locked: lock, keysym: "question", level: 1, consumed: shift, text: "?"},
+
+		// Levels selected by virtual modifiers.
+		{key: "AD01", mods: mod5, keysym: "at", level: 2, consumed: shift | mod5, text: "@"},
+		{key: "AD01", mods: shift | mod5, keysym: "Greek_OMEGA", level: 3, consumed: shift | mod5, text: "Ω"},
+		{key: "KP7", keysym: "KP_Home", level: 0, consumed: shift | mod2, text: ""},
+		{key: "KP7", locked: mod2, keysym: "KP_7", level: 1, consumed: shift | mod2, text: "7"},
+		{key: "KP7", mods: shift, locked: mod2, keysym: "KP_Home", level: 0, consumed: shift | mod2, text: ""},
+
+		// Modifiers that the type doesn't care about don't change
+		// the level and aren't consumed.
+		{key: "AE01", mods: mod1, keysym: "1", level: 0, consumed: shift, text: "1"},
+		{key: "ESC", mods: shift | ctrl, keysym: "Escape", level: 0, consumed: 0, text: "\x1b"},
+
+		// Preserved modifiers aren't consumed.
+		{key: "AE03", mods: shift, keysym: "numbersign", level: 1, consumed: shift | ctrl, text: "#"},
+		{key: "AE03", mods: shift | ctrl, keysym: "numbersign", level: 1, consumed: shift, text: "#"},
+		{key: "AE03", mods: ctrl, keysym: "3", level: 0, consumed: shift | ctrl, text: "3"},
+
+		// Control produces control characters, unless it is consumed.
+		{key: "AC01", mods: ctrl, keysym: "a", level: 0, consumed: shift | lock, text: "\x01"},
+		{key: "AC01", mods: ctrl | shift, keysym: "A", level: 1, consumed: shift | lock, text: "\x01"},
+		{key: "AB10", mods: ctrl, keysym: "slash", level: 0, consumed: shift, text: "\x1f"},
+		{key: "AE02", mods: ctrl, keysym: "2", level: 0, consumed: shift, text: "\x00"},
+		{key: "SPCE", mods: ctrl, keysym: "space", level: 0, consumed: 0, text: "\x00"},
+		{key: "AC02", mods: ctrl, keysym: "ssharp", level: 1, consumed: ctrl, text: "ß"},
+		{key: "AC02", keysym: "s", level: 0, consumed: ctrl, text: "s"},
+
+		// Keys with fewer groups than the active group wrap around.
+		{key: "AC01", group: 1, keysym: "Cyrillic_ef", level: 0, consumed: shift | lock, text: "ф"},
+		{key: "AC01", group: 1, mods: shift, keysym: "Cyrillic_EF", level: 1, consumed: shift | lock, text: "Ф"},
+		{key: "AC01", group: 2, keysym: "a", level: 0, consumed: shift | lock, text: "a"},
+		{key: "AE02", group: 1, mods: shift, keysym: "at", level: 1, consumed: shift, text: "@"},
+		{key: "AE03", group: 1, mods: shift | ctrl, keysym: "numbersign", level: 1, consumed: shift, text: "#"},
+	}
+	for _, tt := range tests {
+		s := NewState(km)
+		s.UpdateMask(tt.mods, 0, tt.locked, tt.group)
+		code := keycode(t, km, tt.key)
+		if got := s.Keysym(code); got.String() != tt.keysym {
+			t.Errorf("%s (mods %#x, locked %#x, group %d): keysym = %s, want %s", tt.key, tt.mods, tt.locked, tt.group, got, tt.keysym)
+		}
+		if got := s.Level(code); got != tt.level {
+			t.Errorf("%s (mods %#x, locked %#x, group %d): level = %d, want %d", tt.key, tt.mods, tt.locked, tt.group, got, tt.level)
+		}
+		if got := s.ConsumedMods(code); got != tt.consumed {
+			t.Errorf("%s (mods %#x, locked %#x, group %d): consumed = %#x, want %#x", tt.key, tt.mods, tt.locked, tt.group, got, tt.consumed)
+		}
+		if got := s.Text(code); got != tt.text {
+			t.Errorf("%s (mods %#x, locked %#x, group %d): text = %q, want %q", tt.key, tt.mods, tt.locked, tt.group, got, tt.text)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	km := parseTestKeymap(t)
+	tests := []struct {
+		key   string
+		group uint32
+		want  int
+	}{
+		{"AC01", 0, 0},
+		{"AC01", 1, 1},
+		{"AC01", 2, 0},
+		{"AC01", 3, 1},
+		{"AE02", 1, 0},
+		{"AE03", 1, 0},
+	}
+	for _, tt := range tests {
+		s := NewState(km)
+		s.UpdateMask(0, 0, 0, tt.group)
+		if got := s.Group(keycode(t, km, tt.key)); got != tt.want {
+			t.Errorf("Group(%s) with group %d = %d, want %d", tt.key, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestModActive(t *testing.T) {
+	km := parseTestKeymap(t)
+	s := NewState(km)
+	s.UpdateMask(mod1, 0, lock|mod2, 0)
+	for _, tt := range []struct {
+		name           string
+		active, locked bool
+	}{
+		{ModShift, false, false},
+		{ModCaps, true, true},
+		{ModAlt, true, false},
+		{"Alt", true, false},
+		{"NumLock", true, true},
+		{"LevelThree", false, false},
+		{"NoSuchModifier", false, false},
+	} {
+		if got := s.ModActive(tt.name); got != tt.active {
+			t.Errorf("ModActive(%s) = %t, want %t", tt.name, got, tt.active)
+		}
+		if got := s.ModLocked(tt.name); got != tt.locked {
+			t.Errorf("ModLocked(%s) = %t, want %t", tt.name, got, tt.locked)
+		}
+	}
+}
+
+func TestToControl(t *testing.T) {
+	tests := []struct {
+		in, want rune
+	}{
+		{'@', 0},
+		{'a', 1},
+		{'A', 1},
+		{'z', 26},
+		{'[', 27},
+		{'_', 31},
+		{' ', 0},
+		{'2', 0},
+		{'3', 27},
+		{'7', 31},
+		{'8', 127},
+		{'/', 31},
+		{'1', '1'},
+		{'9', '9'},
+		{'ä', 'ä'},
+	}
+	for _, tt := range tests {
+		if got := toControl(tt.in); got != tt.want {
+			t.Errorf("toControl(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package wlproto
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry is a set of interfaces, keyed by name. It allows looking up
+// interfaces that are only known by their names at runtime, such as the
+// interfaces announced by wl_registry.global.
+//
+// Several interfaces may be registered under the same name, for example
+// when different packages have been generated from different versions
+// of a protocol.
+type Registry struct {
+	mu     sync.RWMutex
+	ifaces map[string][]*Interface
+}
+
+// ClientRegistry and ServerRegistry contain the interfaces of all
+// client and server protocol packages, respectively, that are linked
+// into the program. Generated packages register their interfaces when
+// they get initialized. The registries are separate because the client
+// and server representations of an interface differ.
+var (
+	ClientRegistry = new(Registry)
+	ServerRegistry = new(Registry)
+)
+
+// Register adds interfaces to the registry. Registering an interface
+// more than once has no effect.
+func (r *Registry) Register(ifaces ...*Interface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ifaces == nil {
+		r.ifaces = make(map[string][]*Interface)
+	}
+outer:
+	for _, iface := range ifaces {
+		for _, other := range r.ifaces[iface.Name] {
+			if other == iface {
+				continue outer
+			}
+		}
+		r.ifaces[iface.Name] = append(r.ifaces[iface.Name], iface)
+	}
+}
+
+// Lookup returns the interface with the given name that supports at
+// least the given version. If several interfaces match, the one with
+// the highest version is returned. It returns false if no interface
+// matches.
+func (r *Registry) Lookup(name string, version uint32) (*Interface, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookupLocked(name, version)
+}
+
+func (r *Registry) lookupLocked(name string, version uint32) (*Interface, bool) {
+	var best *Interface
+	for _, iface := range r.ifaces[name] {
+		if iface.Version >= version && (best == nil || iface.Version > best.Version) {
+			best = iface
+		}
+	}
+	return best, best != nil
+}
+
+// Interfaces returns the interfaces with the highest version for each
+// name in the registry, sorted by name.
+func (r *Registry) Interfaces() []*Interface {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*Interface, 0, len(r.ifaces))
+	for name := range r.ifaces {
+		iface, _ := r.lookupLocked(name, 0)
+		out = append(out, iface)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -1,6 +1,11 @@
 package wlproto
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
 type Interface struct {
 	Name     string
@@ -13,6 +18,13 @@ type Interface struct {
 type Arg struct {
 	Type ArgType
 	Aux  reflect.Type
+	// AllowNull is set for string arguments that may be null. Null
+	// strings are represented by the empty string. Nullable objects
+	// don't need to be marked, as they are represented by nil.
+	AllowNull bool
+	// Enum describes the values of int and uint arguments that refer
+	// to an enum. It is used for printing messages.
+	Enum *Enum
 }
 
 type ArgType byte
@@ -41,3 +53,44 @@ type Event struct {
 	Since uint32
 	Args  []Arg
 }
+
+// Enum describes an enum of a protocol, for printing its values.
+type Enum struct {
+	// Name is the name of the enum, qualified with the name of its
+	// interface, e.g. "wl_output.transform".
+	Name     string
+	Bitfield bool
+	Entries  []EnumEntry
+}
+
+type EnumEntry struct {
+	Name  string
+	Value uint32
+}
+
+// Format returns the name of the entry with the value v. Values of
+// bitfields are formatted as the names of their flags, separated by
+// "|". Values that don't correspond to any entries are formatted as
+// numbers.
+func (e *Enum) Format(v uint32) string {
+	if !e.Bitfield || v == 0 {
+		for _, entry := range e.Entries {
+			if entry.Value == v {
+				return entry.Name
+			}
+		}
+		return strconv.FormatUint(uint64(v), 10)
+	}
+	var names []string
+	rest := v
+	for _, entry := range e.Entries {
+		if entry.Value != 0 && rest&entry.Value == entry.Value {
+			names = append(names, entry.Name)
+			rest &^= entry.Value
+		}
+	}
+	if rest != 0 {
+		names = append(names, fmt.Sprintf("%#x", rest))
+	}
+	return strings.Join(names, "|")
+}
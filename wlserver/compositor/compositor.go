@@ -0,0 +1,141 @@
+// Package compositor implements the wl_compositor global, which
+// creates surfaces and regions, and the wl_subcompositor global, which
+// arranges surfaces in trees of sub-surfaces.
+//
+// Surface state is double-buffered: requests such as wl_surface.attach
+// and wl_surface.damage modify the pending state, which is applied
+// atomically by wl_surface.commit. Roles, such as sub-surfaces or
+// xdg-shell windows, hook into commits to enforce their rules and to
+// react to new state.
+package compositor
+
+import (
+	"image"
+
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlshared/region"
+)
+
+// Events are the callbacks of a Compositor. All callbacks are
+// optional.
+type Events struct {
+	// NewSurface is called when a client creates a surface.
+	NewSurface func(s *Surface)
+	// Commit is called after a surface's pending state has been
+	// applied, and after the surface's role has processed the commit.
+	// Compositors use it to schedule redraws.
+	Commit func(s *Surface)
+	// DestroySurface is called when a surface gets destroyed, either
+	// by the client or because the client disconnected.
+	DestroySurface func(s *Surface)
+}
+
+// Compositor is an implementation of the wl_compositor global.
+type Compositor struct {
+	dsp    *wlserver.Display
+	events Events
+	// buffers contains the buffers that have been attached to
+	// surfaces and that have a destroy listener.
+	buffers map[wayland.Buffer]struct{}
+	// surfaces contains all surfaces that haven't been destroyed
+	surfaces map[*Surface]struct{}
+}
+
+// AddGlobal adds a wl_compositor global to the display.
+func AddGlobal(dsp *wlserver.Display, events Events) *Compositor {
+	comp := &Compositor{
+		dsp:      dsp,
+		events:   events,
+		buffers:  map[wayland.Buffer]struct{}{},
+		surfaces: map[*Surface]struct{}{},
+	}
+	wayland.AddCompositorGlobal(dsp, 5, func(res wayland.Compositor) wayland.CompositorImplementation {
+		return comp
+	})
+	return comp
+}
+
+// Display returns the display the global was added to.
+func (comp *Compositor) Display() *wlserver.Display { return comp.dsp }
+
+// FrameDone sends the done event to the queued frame callbacks of all
+// surfaces. See Surface.FrameDone.
+func (comp *Compositor) FrameDone(time uint32) {
+	for s := range comp.surfaces {
+		s.FrameDone(time)
+	}
+}
+
+func (comp *Compositor) CreateSurface(obj wayland.Compositor, id wayland.Surface) wayland.SurfaceImplementation {
+	s := &Surface{
+		Resource: id,
+		comp:     comp,
+		pending:  newState(),
+		current:  newState(),
+	}
+	comp.surfaces[s] = struct{}{}
+	id.OnDestroy(s.handleDestroy)
+	if comp.events.NewSurface != nil {
+		comp.events.NewSurface(s)
+	}
+	return s
+}
+
+func (comp *Compositor) CreateRegion(obj wayland.Compositor, id wayland.Region) wayland.RegionImplementation {
+	return &Region{Resource: id}
+}
+
+// watchBuffer makes sure that surfaces forget about buf once it gets
+// destroyed.
+func (comp *Compositor) watchBuffer(buf wayland.Buffer) {
+	if _, ok := comp.buffers[buf]; ok {
+		return
+	}
+	comp.buffers[buf] = struct{}{}
+	buf.OnDestroy(func() {
+		delete(comp.buffers, buf)
+		for s := range comp.surfaces {
+			s.forgetBuffer(buf)
+		}
+	})
+}
+
+// Region is a wl_region, an area described by adding and subtracting
+// rectangles.
+type Region struct {
+	Resource wayland.Region
+
+	area region.Region
+}
+
+// RegionFromResource returns the region that implements res. It
+// returns false if res is a null object.
+func RegionFromResource(res wayland.Region) (*Region, bool) {
+	if res.Conn() == nil {
+		return nil, false
+	}
+	r, ok := res.Implementation().(*Region)
+	return r, ok
+}
+
+// Region returns the area described by the region.
+func (r *Region) Region() region.Region {
+	return r.area
+}
+
+func (r *Region) Destroy(obj wayland.Region) {}
+
+func (r *Region) Add(obj wayland.Region, x, y, width, height int32) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	r.area = r.area.UnionRect(image.Rect(int(x), int(y), int(x)+int(width), int(y)+int(height)))
+}
+
+func (r *Region) Subtract(obj wayland.Region, x, y, width, height int32) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	r.area = r.area.SubtractRect(image.Rect(int(x), int(y), int(x)+int(width), int(y)+int(height)))
+}
@@ -0,0 +1,68 @@
+package compositor
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+type rectOp struct {
+	subtract            bool
+	x, y, width, height int32
+}
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []rectOp
+		want []image.Rectangle
+	}{
+		{"add", []rectOp{{false, 0, 0, 10, 10}}, []image.Rectangle{image.Rect(0, 0, 10, 10)}},
+		{"subtract", []rectOp{{false, 0, 0, 10, 10}, {true, 0, 0, 5, 10}}, []image.Rectangle{image.Rect(5, 0, 10, 10)}},
+		// Rectangles with non-positive sizes are ignored, instead of
+		// being mirrored.
+		{"add negative width", []rectOp{{false, 10, 10, -5, 5}}, nil},
+		{"add negative height", []rectOp{{false, 10, 10, 5, -5}}, nil},
+		{"add zero size", []rectOp{{false, 10, 10, 0, 5}}, nil},
+		{"subtract negative size", []rectOp{{false, 0, 0, 10, 10}, {true, 10, 10, -5, -5}}, []image.Rectangle{image.Rect(0, 0, 10, 10)}},
+	}
+	for _, tt := range tests {
+		r := &Region{}
+		for _, op := range tt.ops {
+			if op.subtract {
+				r.Subtract(wayland.Region{}, op.x, op.y, op.width, op.height)
+			} else {
+				r.Add(wayland.Region{}, op.x, op.y, op.width, op.height)
+			}
+		}
+		if got := r.Region().Rects(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDamage(t *testing.T) {
+	tests := []struct {
+		name                string
+		x, y, width, height int32
+		want                []image.Rectangle
+	}{
+		{"positive", 1, 2, 3, 4, []image.Rectangle{image.Rect(1, 2, 4, 6)}},
+		{"negative width", 10, 10, -5, 5, nil},
+		{"negative height", 10, 10, 5, -5, nil},
+		{"empty", 10, 10, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		s := &Surface{}
+		s.Damage(wayland.Surface{}, tt.x, tt.y, tt.width, tt.height)
+		s.DamageBuffer(wayland.Surface{}, tt.x, tt.y, tt.width, tt.height)
+		if got := s.Pending().Damage.Rects(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: damage = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := s.Pending().BufferDamage.Rects(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: buffer damage = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
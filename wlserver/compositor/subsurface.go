@@ -0,0 +1,205 @@
+package compositor
+
+import (
+	"fmt"
+
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+// Subcompositor is an implementation of the wl_subcompositor global.
+type Subcompositor struct {
+	comp *Compositor
+}
+
+// AddSubcompositorGlobal adds a wl_subcompositor global to the
+// display of comp. Sub-surfaces can be created for all surfaces
+// created by comp.
+func AddSubcompositorGlobal(comp *Compositor) *Subcompositor {
+	sc := &Subcompositor{comp: comp}
+	wayland.AddSubcompositorGlobal(comp.dsp, 1, func(res wayland.Subcompositor) wayland.SubcompositorImplementation {
+		return sc
+	})
+	return sc
+}
+
+func (sc *Subcompositor) Destroy(obj wayland.Subcompositor) {}
+
+func (sc *Subcompositor) GetSubsurface(obj wayland.Subcompositor, id wayland.Subsurface, surface, parent wayland.Surface) wayland.SubsurfaceImplementation {
+	sub := &Subsurface{Resource: id, sc: sc, sync: true}
+	s, ok1 := SurfaceFromResource(surface)
+	p, ok2 := SurfaceFromResource(parent)
+	if !ok1 || !ok2 {
+		sc.comp.dsp.Error(obj, uint32(wayland.SubcompositorErrorBadSurface), "invalid surface")
+		return sub
+	}
+	if s == p {
+		sc.comp.dsp.Error(obj, uint32(wayland.SubcompositorErrorBadSurface),
+			fmt.Sprintf("%s can't be its own parent", objectString(surface)))
+		return sub
+	}
+	for anc := p; anc != nil; anc = anc.parent() {
+		if anc == s {
+			sc.comp.dsp.Error(obj, uint32(wayland.SubcompositorErrorBadSurface),
+				fmt.Sprintf("%s is an ancestor of parent %s", objectString(surface), objectString(parent)))
+			return sub
+		}
+	}
+	if !s.SetRole("wl_subsurface", sub) {
+		sc.comp.dsp.Error(obj, uint32(wayland.SubcompositorErrorBadSurface),
+			fmt.Sprintf("%s already has a role", objectString(surface)))
+		return sub
+	}
+
+	sub.surface = s
+	sub.parentSurface = p
+	// New sub-surfaces are placed on top of their siblings right away,
+	// without waiting for the parent's commit.
+	p.initStack()
+	p.stack = append(p.stack, s)
+	p.pendingStack = append(p.pendingStack, s)
+	id.OnDestroy(sub.handleDestroy)
+	return sub
+}
+
+func objectString(obj wlserver.Object) string {
+	return fmt.Sprintf("%s@%d", obj.Interface().Name, obj.ID())
+}
+
+// Subsurface is a wl_subsurface, the role of a surface that is part
+// of another surface, its parent. Sub-surfaces are positioned relative
+// to their parents and are stacked together with their siblings and
+// their parent.
+//
+// The position and stacking order of a sub-surface are state of the
+// parent, and are applied when the parent's state is applied. In
+// synchronized mode, which is the default, the state of a sub-surface
+// is applied together with the state of its parent; in desynchronized
+// mode, it is applied when the sub-surface gets committed.
+type Subsurface struct {
+	Resource wayland.Subsurface
+
+	sc      *Subcompositor
+	surface *Surface
+	// parentSurface is nil once the sub-surface has become inert,
+	// because it or its parent got destroyed
+	parentSurface *Surface
+	x, y          int32
+	pendingX      int32
+	pendingY      int32
+	sync          bool
+}
+
+// Surface returns the surface that has the sub-surface role.
+func (sub *Subsurface) Surface() *Surface { return sub.surface }
+
+// Parent returns the parent surface, or nil if the parent has been
+// destroyed.
+func (sub *Subsurface) Parent() *Surface { return sub.parentSurface }
+
+// Position returns the position of the sub-surface relative to its
+// parent.
+func (sub *Subsurface) Position() (x, y int32) { return sub.x, sub.y }
+
+// Synchronized reports whether the sub-surface's state is applied
+// together with its parent's, either because it is in synchronized
+// mode or because one of its ancestors is.
+func (sub *Subsurface) Synchronized() bool {
+	for ; sub != nil; sub = sub.parentSurface.subsurface() {
+		if sub.sync {
+			return true
+		}
+		if sub.parentSurface == nil {
+			break
+		}
+	}
+	return false
+}
+
+func (sub *Subsurface) Precommit(s *Surface) bool { return true }
+func (sub *Subsurface) Commit(s *Surface)         {}
+
+func (sub *Subsurface) Destroy(obj wayland.Subsurface) {}
+
+func (sub *Subsurface) handleDestroy() {
+	if sub.surface == nil {
+		return
+	}
+	sub.detach()
+	sub.surface.UnsetRole()
+}
+
+// detach removes the sub-surface from its parent, which unmaps it.
+func (sub *Subsurface) detach() {
+	p := sub.parentSurface
+	if p == nil {
+		return
+	}
+	p.stack = removeSurface(p.stack, sub.surface)
+	p.pendingStack = removeSurface(p.pendingStack, sub.surface)
+	sub.parentSurface = nil
+}
+
+func (sub *Subsurface) SetPosition(obj wayland.Subsurface, x, y int32) {
+	sub.pendingX, sub.pendingY = x, y
+}
+
+func (sub *Subsurface) PlaceAbove(obj wayland.Subsurface, sibling wayland.Surface) {
+	sub.place(obj, sibling, 1)
+}
+
+func (sub *Subsurface) PlaceBelow(obj wayland.Subsurface, sibling wayland.Surface) {
+	sub.place(obj, sibling, 0)
+}
+
+// place moves the sub-surface directly above or below sibling in the
+// parent's pending stacking order. Offset is 1 for above and 0 for
+// below.
+func (sub *Subsurface) place(obj wayland.Subsurface, sibling wayland.Surface, offset int) {
+	p := sub.parentSurface
+	if p == nil {
+		return
+	}
+	sib, ok := SurfaceFromResource(sibling)
+	if !ok || sib == sub.surface || (sib != p && sib.parent() != p) {
+		sub.sc.comp.dsp.Error(obj, uint32(wayland.SubsurfaceErrorBadSurface),
+			fmt.Sprintf("%s is not a sibling or the parent", objectString(sibling)))
+		return
+	}
+	stack := removeSurface(p.pendingStack, sub.surface)
+	for i, s := range stack {
+		if s == sib {
+			i += offset
+			stack = append(stack, nil)
+			copy(stack[i+1:], stack[i:])
+			stack[i] = sub.surface
+			break
+		}
+	}
+	p.pendingStack = stack
+}
+
+func (sub *Subsurface) SetSync(obj wayland.Subsurface) {
+	sub.sync = true
+}
+
+func (sub *Subsurface) SetDesync(obj wayland.Subsurface) {
+	if !sub.sync {
+		return
+	}
+	sub.sync = false
+	if !sub.Synchronized() {
+		// Cached state would otherwise only be applied by the next
+		// commit.
+		sub.surface.applyCache()
+	}
+}
+
+func removeSurface(stack []*Surface, s *Surface) []*Surface {
+	for i, ss := range stack {
+		if ss == s {
+			return append(stack[:i], stack[i+1:]...)
+		}
+	}
+	return stack
+}
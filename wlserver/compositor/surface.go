@@ -0,0 +1,559 @@
+package compositor
+
+import (
+	"fmt"
+	"image"
+
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlshared/region"
+)
+
+// infiniteRect stands in for an infinite region. It is large enough to
+// cover any surface.
+var infiniteRect = image.Rect(-1<<30, -1<<30, 1<<30, 1<<30)
+
+// SizedBuffer is implemented by buffer implementations, such as
+// *shm.Buffer, to tell the compositor the size of their buffers.
+// Buffers whose implementation doesn't implement SizedBuffer are
+// treated as having a size of zero.
+type SizedBuffer interface {
+	Size() (width, height int32)
+}
+
+// stateFields records which parts of a State have been set.
+type stateFields uint8
+
+const (
+	fieldBuffer stateFields = 1 << iota
+	fieldTransform
+	fieldScale
+	fieldOpaqueRegion
+	fieldInputRegion
+)
+
+// State is the double-buffered state of a surface.
+type State struct {
+	// Buffer is the attached buffer. Its Conn is nil if no buffer is
+	// attached.
+	Buffer wayland.Buffer
+	// DX and DY are the position of the buffer relative to the
+	// previous one, as set by wl_surface.attach or wl_surface.offset.
+	// In the current state, they are the offset of the latest commit.
+	// Offsets of successive commits add up, until the compositor
+	// applies the state.
+	DX, DY int32
+	// Transform is the transform that the client applied to the
+	// buffer's contents.
+	Transform wayland.OutputTransform
+	// Scale is the scale of the buffer's contents.
+	Scale int32
+	// Damage is the damaged area in surface-local coordinates, and
+	// BufferDamage the damaged area in buffer coordinates. In the
+	// current state, they are the damage of the latest commit.
+	Damage       region.Region
+	BufferDamage region.Region
+	// OpaqueRegion is the part of the surface that the client promised
+	// to be opaque, in surface-local coordinates.
+	OpaqueRegion region.Region
+	// InputRegion is the part of the surface that accepts input, in
+	// surface-local coordinates. By default, it is infinite.
+	InputRegion region.Region
+	// FrameCallbacks are the callbacks requested by wl_surface.frame.
+	FrameCallbacks []wayland.Callback
+
+	fields stateFields
+}
+
+func newState() State {
+	return State{
+		Scale:       1,
+		InputRegion: region.Rect(infiniteRect),
+	}
+}
+
+// merge applies the state that has been set in src to st and resets
+// src. Damage and frame callbacks accumulate.
+func (st *State) merge(src *State) {
+	if src.fields&fieldBuffer != 0 {
+		st.Buffer = src.Buffer
+	}
+	st.DX += src.DX
+	st.DY += src.DY
+	if src.fields&fieldTransform != 0 {
+		st.Transform = src.Transform
+	}
+	if src.fields&fieldScale != 0 {
+		st.Scale = src.Scale
+	}
+	if src.fields&fieldOpaqueRegion != 0 {
+		st.OpaqueRegion = src.OpaqueRegion
+	}
+	if src.fields&fieldInputRegion != 0 {
+		st.InputRegion = src.InputRegion
+	}
+	st.Damage = st.Damage.Union(src.Damage)
+	st.BufferDamage = st.BufferDamage.Union(src.BufferDamage)
+	st.FrameCallbacks = append(st.FrameCallbacks, src.FrameCallbacks...)
+	st.fields |= src.fields
+
+	*src = State{
+		Transform:    src.Transform,
+		Scale:        src.Scale,
+		OpaqueRegion: src.OpaqueRegion,
+		InputRegion:  src.InputRegion,
+	}
+}
+
+// Role is the role of a surface, such as a sub-surface or an
+// xdg_toplevel.
+type Role interface {
+	// Precommit is called when the client commits the surface, before
+	// the pending state is applied. It may inspect the pending state
+	// and reject the commit by posting a protocol error and returning
+	// false.
+	Precommit(s *Surface) bool
+	// Commit is called after the pending state has been applied.
+	Commit(s *Surface)
+}
+
+// Surface is a wl_surface.
+type Surface struct {
+	Resource wayland.Surface
+
+	comp    *Compositor
+	pending State
+	current State
+	// cached is the state committed while the surface was a
+	// synchronized sub-surface, waiting for the parent's state to be
+	// applied
+	cached   State
+	hasCache bool
+	// stack is the stacking order of the surface and its
+	// sub-surfaces, from bottom to top. It is empty if the surface
+	// has never had sub-surfaces.
+	stack        []*Surface
+	pendingStack []*Surface
+	// frames are the frame callbacks of committed state, waiting for
+	// the compositor to present the surface
+	frames        []wayland.Callback
+	width, height int32
+	// damage is the damage accumulated since the last call to
+	// TakeDamage, in buffer coordinates
+	damage    region.Region
+	roleName  string
+	role      Role
+	destroyed bool
+}
+
+// SurfaceFromResource returns the surface that implements res. It
+// returns false if res is a null object.
+func SurfaceFromResource(res wayland.Surface) (*Surface, bool) {
+	if res.Conn() == nil {
+		return nil, false
+	}
+	s, ok := res.Implementation().(*Surface)
+	return s, ok
+}
+
+// Compositor returns the compositor that created the surface.
+func (s *Surface) Compositor() *Compositor { return s.comp }
+
+// Current returns the surface's current state. It must not be
+// modified.
+func (s *Surface) Current() *State { return &s.current }
+
+// Pending returns the surface's pending state, which will be applied
+// by the next commit. It must not be modified.
+func (s *Surface) Pending() *State { return &s.pending }
+
+// Size returns the size of the surface in surface-local coordinates,
+// which is the size of its buffer, adjusted for the buffer's transform
+// and scale. It is zero if the surface has no buffer.
+func (s *Surface) Size() (width, height int32) { return s.width, s.height }
+
+// Stack returns the surface and its sub-surfaces in stacking order,
+// from bottom to top.
+func (s *Surface) Stack() []*Surface {
+	if len(s.stack) == 0 {
+		return []*Surface{s}
+	}
+	return s.stack
+}
+
+// Walk calls fn for the surface and all of its mapped sub-surfaces, in
+// stacking order from bottom to top, with their positions relative to
+// the surface. A sub-surface is mapped if it and all of its ancestors
+// up to the surface have a buffer.
+func (s *Surface) Walk(fn func(s *Surface, x, y int32)) {
+	s.walk(fn, 0, 0)
+}
+
+func (s *Surface) walk(fn func(s *Surface, x, y int32), x, y int32) {
+	for _, ss := range s.Stack() {
+		if ss == s {
+			fn(s, x, y)
+			continue
+		}
+		if ss.current.Buffer.Conn() == nil {
+			continue
+		}
+		sub := ss.subsurface()
+		ss.walk(fn, x+sub.x, y+sub.y)
+	}
+}
+
+// subsurface returns the surface's sub-surface role, or nil if it
+// isn't a sub-surface.
+func (s *Surface) subsurface() *Subsurface {
+	sub, _ := s.role.(*Subsurface)
+	return sub
+}
+
+// parent returns the parent of a sub-surface.
+func (s *Surface) parent() *Surface {
+	if sub := s.subsurface(); sub != nil {
+		return sub.parentSurface
+	}
+	return nil
+}
+
+func (s *Surface) initStack() {
+	if len(s.stack) == 0 {
+		s.stack = []*Surface{s}
+		s.pendingStack = []*Surface{s}
+	}
+}
+
+// Destroyed reports whether the surface has been destroyed.
+func (s *Surface) Destroyed() bool { return s.destroyed }
+
+// SetRole assigns a role to the surface. Name identifies the kind of
+// role, such as "wl_subsurface". A surface's kind of role can't change
+// once it has been set, and a surface can't have two role objects at
+// once, but a role object may replace one that has been removed with
+// UnsetRole.
+//
+// SetRole reports whether the role could be assigned. If it couldn't,
+// the caller should post the role error of its interface.
+func (s *Surface) SetRole(name string, role Role) bool {
+	if (s.roleName != "" && s.roleName != name) || s.role != nil {
+		return false
+	}
+	s.roleName = name
+	s.role = role
+	return true
+}
+
+// UnsetRole removes the surface's role object, for example because
+// the client destroyed it. The surface keeps the kind of its role.
+func (s *Surface) UnsetRole() {
+	s.role = nil
+}
+
+// Role returns the surface's role object, or nil if it has none.
+func (s *Surface) Role() Role { return s.role }
+
+// RoleName returns the kind of the surface's role, or the empty string
+// if it has never had one.
+func (s *Surface) RoleName() string { return s.roleName }
+
+// AcceptsInput reports whether the surface-local point x, y is within
+// the surface's input region.
+func (s *Surface) AcceptsInput(x, y int) bool {
+	p := image.Pt(x, y)
+	return p.In(image.Rect(0, 0, int(s.width), int(s.height))) && s.current.InputRegion.Contains(p)
+}
+
+// BufferPoint returns the position of the pixel of the current buffer
+// that shows the surface-local point x, y, taking the buffer transform
+// and scale into account.
+func (s *Surface) BufferPoint(x, y int) (bx, by int) {
+	r := transformRect(image.Rect(x, y, x+1, y+1), s.current.Transform, int(s.width), int(s.height))
+	scale := int(s.current.Scale)
+	return r.Min.X * scale, r.Min.Y * scale
+}
+
+// TakeDamage returns the damage accumulated by the commits since the
+// last call to TakeDamage, in buffer coordinates, and resets it.
+// Surface damage is converted using the buffer transform and scale in
+// effect when it was committed.
+func (s *Surface) TakeDamage() region.Region {
+	d := s.damage
+	s.damage = region.Region{}
+	return d
+}
+
+// FrameDone sends the done event to the frame callbacks of all
+// committed state, which tells the client that now is a good time to
+// draw a new frame. Compositors call it after presenting the surface,
+// with the current time in milliseconds.
+func (s *Surface) FrameDone(time uint32) {
+	for _, cb := range s.frames {
+		cb.Done(time)
+	}
+	s.frames = s.frames[:0]
+}
+
+func (s *Surface) Destroy(obj wayland.Surface) {}
+
+func (s *Surface) handleDestroy() {
+	s.destroyed = true
+	delete(s.comp.surfaces, s)
+	if sub := s.subsurface(); sub != nil {
+		sub.detach()
+	}
+	// Sub-surfaces of a destroyed parent become inert.
+	for _, child := range s.stack {
+		if child != s {
+			child.subsurface().parentSurface = nil
+		}
+	}
+	s.stack = nil
+	s.pendingStack = nil
+	// Clients don't get notified of unfired callbacks.
+	for _, cb := range s.frames {
+		cb.Destroy()
+	}
+	for _, cb := range s.pending.FrameCallbacks {
+		cb.Destroy()
+	}
+	for _, cb := range s.cached.FrameCallbacks {
+		cb.Destroy()
+	}
+	s.frames = nil
+	s.pending.FrameCallbacks = nil
+	if s.current.Buffer.Conn() != nil {
+		s.current.Buffer.Release()
+	}
+	if s.comp.events.DestroySurface != nil {
+		s.comp.events.DestroySurface(s)
+	}
+}
+
+// forgetBuffer removes buf from the surface's state, after it has
+// been destroyed.
+func (s *Surface) forgetBuffer(buf wayland.Buffer) {
+	if s.pending.Buffer == buf {
+		s.pending.Buffer = wayland.Buffer{}
+	}
+	if s.cached.Buffer == buf {
+		s.cached.Buffer = wayland.Buffer{}
+	}
+	if s.current.Buffer == buf {
+		s.current.Buffer = wayland.Buffer{}
+	}
+}
+
+func (s *Surface) Attach(obj wayland.Surface, buffer wayland.Buffer, x, y int32) {
+	if obj.Version() >= 5 && (x != 0 || y != 0) {
+		s.comp.dsp.Error(obj, uint32(wayland.SurfaceErrorInvalidOffset),
+			fmt.Sprintf("attach with non-zero offset (%d, %d)", x, y))
+		return
+	}
+	if buffer.Conn() != nil {
+		s.comp.watchBuffer(buffer)
+	}
+	s.pending.Buffer = buffer
+	s.pending.DX, s.pending.DY = x, y
+	s.pending.fields |= fieldBuffer
+}
+
+func (s *Surface) Offset(obj wayland.Surface, x, y int32) {
+	s.pending.DX, s.pending.DY = x, y
+}
+
+func (s *Surface) Damage(obj wayland.Surface, x, y, width, height int32) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	s.pending.Damage = s.pending.Damage.UnionRect(image.Rect(int(x), int(y), int(x)+int(width), int(y)+int(height)))
+}
+
+func (s *Surface) DamageBuffer(obj wayland.Surface, x, y, width, height int32) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	s.pending.BufferDamage = s.pending.BufferDamage.UnionRect(image.Rect(int(x), int(y), int(x)+int(width), int(y)+int(height)))
+}
+
+func (s *Surface) Frame(obj wayland.Surface, callback wayland.Callback) wayland.CallbackImplementation {
+	s.pending.FrameCallbacks = append(s.pending.FrameCallbacks, callback)
+	return s
+}
+
+func (s *Surface) SetOpaqueRegion(obj wayland.Surface, reg wayland.Region) {
+	// Regions are values, so the client may modify or destroy the
+	// wl_region afterwards.
+	var area region.Region
+	if r, ok := RegionFromResource(reg); ok {
+		area = r.area
+	}
+	s.pending.OpaqueRegion = area
+	s.pending.fields |= fieldOpaqueRegion
+}
+
+func (s *Surface) SetInputRegion(obj wayland.Surface, reg wayland.Region) {
+	area := region.Rect(infiniteRect)
+	if r, ok := RegionFromResource(reg); ok {
+		area = r.area
+	}
+	s.pending.InputRegion = area
+	s.pending.fields |= fieldInputRegion
+}
+
+func (s *Surface) SetBufferTransform(obj wayland.Surface, transform wayland.OutputTransform) {
+	if transform > wayland.OutputTransformFlipped270 {
+		s.comp.dsp.Error(obj, uint32(wayland.SurfaceErrorInvalidTransform),
+			fmt.Sprintf("invalid transform %d", transform))
+		return
+	}
+	s.pending.Transform = transform
+	s.pending.fields |= fieldTransform
+}
+
+func (s *Surface) SetBufferScale(obj wayland.Surface, scale int32) {
+	if scale <= 0 {
+		s.comp.dsp.Error(obj, uint32(wayland.SurfaceErrorInvalidScale),
+			fmt.Sprintf("invalid scale %d", scale))
+		return
+	}
+	s.pending.Scale = scale
+	s.pending.fields |= fieldScale
+}
+
+func (s *Surface) Commit(obj wayland.Surface) {
+	if s.role != nil && !s.role.Precommit(s) {
+		return
+	}
+	if sub := s.subsurface(); sub != nil && sub.Synchronized() {
+		// The state gets applied together with the parent's state.
+		s.cached.merge(&s.pending)
+		s.hasCache = true
+		return
+	}
+	if s.hasCache {
+		s.cached.merge(&s.pending)
+		s.applyCache()
+		return
+	}
+	s.apply(&s.pending)
+}
+
+// applyCache applies the cached state, if there is any.
+func (s *Surface) applyCache() {
+	if !s.hasCache {
+		return
+	}
+	s.hasCache = false
+	s.apply(&s.cached)
+}
+
+// apply makes st the surface's current state.
+func (s *Surface) apply(st *State) {
+	old := s.current.Buffer
+	// Offsets, damage and frame callbacks of the current state only
+	// describe the latest commit.
+	s.current.DX, s.current.DY = 0, 0
+	s.current.Damage = region.Region{}
+	s.current.BufferDamage = region.Region{}
+	s.current.FrameCallbacks = s.current.FrameCallbacks[:0]
+	s.current.merge(st)
+	cur := &s.current
+
+	if old.Conn() != nil && old != cur.Buffer {
+		// The compositor reads buffers when drawing, so it is done
+		// with a buffer once it has been replaced.
+		old.Release()
+	}
+
+	s.width, s.height = 0, 0
+	var bw, bh int32
+	if cur.Buffer.Conn() != nil {
+		if b, ok := cur.Buffer.Implementation().(SizedBuffer); ok {
+			bw, bh = b.Size()
+		}
+		if transposed(cur.Transform) {
+			s.width, s.height = bh/cur.Scale, bw/cur.Scale
+		} else {
+			s.width, s.height = bw/cur.Scale, bh/cur.Scale
+		}
+	}
+
+	damage := surfaceToBuffer(cur.Damage, cur.Transform, cur.Scale, s.width, s.height).Union(cur.BufferDamage)
+	s.damage = s.damage.Union(damage.IntersectRect(image.Rect(0, 0, int(bw), int(bh))))
+	s.frames = append(s.frames, cur.FrameCallbacks...)
+
+	// The positions and stacking order of sub-surfaces are state of
+	// the parent. Applying the parent's state also applies the cached
+	// state of sub-surfaces, which they accumulated while they were
+	// synchronized.
+	if len(s.stack) > 0 {
+		s.stack = append(s.stack[:0], s.pendingStack...)
+		for _, child := range s.stack {
+			if child == s {
+				continue
+			}
+			sub := child.subsurface()
+			sub.x, sub.y = sub.pendingX, sub.pendingY
+			child.applyCache()
+		}
+	}
+
+	if s.role != nil {
+		s.role.Commit(s)
+	}
+	if s.comp.events.Commit != nil {
+		s.comp.events.Commit(s)
+	}
+}
+
+// transposed reports whether t swaps the width and height of a buffer.
+func transposed(t wayland.OutputTransform) bool {
+	return t&1 != 0
+}
+
+// surfaceToBuffer converts r from the coordinates of a surface of size
+// width, height to buffer coordinates.
+func surfaceToBuffer(r region.Region, t wayland.OutputTransform, scale, width, height int32) region.Region {
+	if t == wayland.OutputTransformNormal {
+		return r.Scale(float64(scale))
+	}
+	rects := make([]image.Rectangle, len(r.Rects()))
+	for i, rect := range r.Rects() {
+		rects[i] = transformRect(rect, t, int(width), int(height))
+	}
+	return region.New(rects...).Scale(float64(scale))
+}
+
+// transformRect converts r from the coordinates of a surface of size
+// w, h to the coordinates of a buffer whose contents have been
+// transformed by t.
+func transformRect(r image.Rectangle, t wayland.OutputTransform, w, h int) image.Rectangle {
+	// The transform describes how the buffer contents have been
+	// transformed already; undoing it maps surface coordinates to
+	// buffer coordinates. Flips are their own inverse.
+	switch t {
+	case wayland.OutputTransform90:
+		t = wayland.OutputTransform270
+	case wayland.OutputTransform270:
+		t = wayland.OutputTransform90
+	}
+	switch t {
+	case wayland.OutputTransform90:
+		return image.Rect(h-r.Max.Y, r.Min.X, h-r.Min.Y, r.Max.X)
+	case wayland.OutputTransform180:
+		return image.Rect(w-r.Max.X, h-r.Max.Y, w-r.Min.X, h-r.Min.Y)
+	case wayland.OutputTransform270:
+		return image.Rect(r.Min.Y, w-r.Max.X, r.Max.Y, w-r.Min.X)
+	case wayland.OutputTransformFlipped:
+		return image.Rect(w-r.Max.X, r.Min.Y, w-r.Min.X, r.Max.Y)
+	case wayland.OutputTransformFlipped90:
+		return image.Rect(r.Min.Y, r.Min.X, r.Max.Y, r.Max.X)
+	case wayland.OutputTransformFlipped180:
+		return image.Rect(r.Min.X, h-r.Max.Y, r.Max.X, h-r.Min.Y)
+	case wayland.OutputTransformFlipped270:
+		return image.Rect(h-r.Max.Y, w-r.Max.X, h-r.Min.Y, w-r.Min.X)
+	default:
+		return r
+	}
+}
@@ -0,0 +1,302 @@
+// Package datadevice implements the wl_data_device_manager global for
+// compositors, which provides copy and paste as well as drag and drop.
+//
+// Data is transferred directly between clients: the compositor
+// forwards the file descriptors of wl_data_offer.receive requests to
+// the client that owns the data source. The compositor's role is to
+// decide which client sees which offers. Each Seat tracks the current
+// selection and sends it to the client with keyboard focus, which the
+// compositor reports via Seat.SetFocus.
+//
+// Drags are started by clients, but driven by the compositor, which
+// has to forward the motion of the pointer or touch point holding the
+// implicit grab to the Drag, and end it with Drag.Drop or Drag.Cancel.
+// Actions are negotiated between the source and the target, subject
+// to an action the compositor may force, e.g. in response to keyboard
+// modifiers.
+package datadevice
+
+import (
+	"fmt"
+
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+// iconRoleName is the compositor role of drag icon surfaces.
+const iconRoleName = "wl_data_device-icon"
+
+// iconRole is the role object of drag icon surfaces.
+type iconRole struct{}
+
+func (*iconRole) Precommit(s *compositor.Surface) bool { return true }
+func (*iconRole) Commit(s *compositor.Surface)         {}
+
+var dragIcon = &iconRole{}
+
+// Manager is an implementation of the wl_data_device_manager global.
+type Manager struct {
+	dsp  *wlserver.Display
+	seat func(res wayland.Seat) *Seat
+}
+
+// AddGlobal adds a wl_data_device_manager global to the display. Seat
+// maps wl_seat resources to the seats created with Manager.NewSeat; it
+// may return nil for unknown seats, whose data devices will be inert.
+func AddGlobal(dsp *wlserver.Display, seat func(res wayland.Seat) *Seat) *Manager {
+	m := &Manager{
+		dsp:  dsp,
+		seat: seat,
+	}
+	wayland.AddDataDeviceManagerGlobal(dsp, 3, func(res wayland.DataDeviceManager) wayland.DataDeviceManagerImplementation {
+		return m
+	})
+	return m
+}
+
+func (m *Manager) CreateDataSource(obj wayland.DataDeviceManager, id wayland.DataSource) wayland.DataSourceImplementation {
+	src := &Source{
+		Resource: id,
+		manager:  m,
+	}
+	if id.Version() < 3 {
+		// Sources that can't set actions implicitly support copying.
+		src.actions = ActionCopy
+	}
+	id.OnDestroy(src.handleDestroy)
+	return src
+}
+
+func (m *Manager) GetDataDevice(obj wayland.DataDeviceManager, id wayland.DataDevice, seat wayland.Seat) wayland.DataDeviceImplementation {
+	dev := &Device{
+		Resource: id,
+		manager:  m,
+		seat:     m.seat(seat),
+	}
+	if s := dev.seat; s != nil {
+		c := id.Conn()
+		s.devices[c] = append(s.devices[c], id)
+		id.OnDestroy(func() { s.removeDevice(id) })
+		if c == s.focus {
+			s.sendSelection(id)
+		}
+	}
+	return dev
+}
+
+// SeatEvents are the callbacks of a Seat. All callbacks are optional.
+type SeatEvents struct {
+	// ValidSerial reports whether serial is the serial of a recent
+	// input event of client, which is required for setting the
+	// selection. If it is nil, all serials are accepted.
+	ValidSerial func(client *wlserver.Client, serial uint32) bool
+	// Selection is called after the selection changed. Source is nil
+	// if the selection has been cleared.
+	Selection func(source *Source)
+	// StartDrag is called when a client requests to start a drag. The
+	// compositor should check that the client has an implicit grab on
+	// drag.Origin that matches drag.Serial, and return false if it
+	// doesn't, which cancels the drag. If it returns true, the
+	// compositor has to drive the drag until it ends. If StartDrag is
+	// nil, all drags are rejected.
+	StartDrag func(drag *Drag) bool
+	// DragEnd is called when a drag has ended, either because the
+	// compositor ended it or because the source has been destroyed.
+	DragEnd func(drag *Drag)
+}
+
+// Seat is the data transfer state of a seat.
+type Seat struct {
+	manager *Manager
+	events  SeatEvents
+
+	devices map[*wlserver.Client][]wayland.DataDevice
+	// focus is the client with keyboard focus
+	focus           *wlserver.Client
+	selection       *Source
+	selectionSerial uint32
+	drag            *Drag
+}
+
+// NewSeat returns the data transfer state for a new seat.
+func (m *Manager) NewSeat(events SeatEvents) *Seat {
+	return &Seat{
+		manager: m,
+		events:  events,
+		devices: make(map[*wlserver.Client][]wayland.DataDevice),
+	}
+}
+
+// Selection returns the source of the current selection, or nil.
+func (s *Seat) Selection() *Source { return s.selection }
+
+// ClearSelection clears the selection, cancelling its source.
+func (s *Seat) ClearSelection() {
+	s.setSelection(nil)
+}
+
+// Drag returns the active drag, or nil.
+func (s *Seat) Drag() *Drag { return s.drag }
+
+// SetFocus sets the client with keyboard focus, which may be nil. The
+// selection is sent to a client whenever it gains focus, and whenever
+// it changes while the client has focus.
+func (s *Seat) SetFocus(client *wlserver.Client) {
+	if client == s.focus {
+		return
+	}
+	s.focus = client
+	if client == nil {
+		return
+	}
+	for _, dev := range s.devices[client] {
+		s.sendSelection(dev)
+	}
+}
+
+func (s *Seat) setSelection(src *Source) {
+	if old := s.selection; old != nil && old != src {
+		old.cancel()
+		old.seat = nil
+	}
+	s.selection = src
+	if src != nil {
+		src.seat = s
+	}
+	if s.focus != nil {
+		for _, dev := range s.devices[s.focus] {
+			s.sendSelection(dev)
+		}
+	}
+	if s.events.Selection != nil {
+		s.events.Selection(src)
+	}
+}
+
+// sendSelection sends a new offer for the selection to dev.
+func (s *Seat) sendSelection(dev wayland.DataDevice) {
+	if s.selection == nil {
+		dev.Selection(wayland.DataOffer{})
+		return
+	}
+	o := s.selection.newOffer(dev, nil)
+	dev.Selection(o.Resource)
+}
+
+func (s *Seat) removeDevice(res wayland.DataDevice) {
+	c := res.Conn()
+	devs := s.devices[c]
+	for i, dev := range devs {
+		if dev == res {
+			devs = append(devs[:i], devs[i+1:]...)
+			break
+		}
+	}
+	if len(devs) == 0 {
+		delete(s.devices, c)
+	} else {
+		s.devices[c] = devs
+	}
+	if s.drag != nil && s.drag.device == res {
+		s.drag.device = wayland.DataDevice{}
+		s.drag.offer = nil
+	}
+}
+
+// Device is a wl_data_device, which belongs to a seat.
+type Device struct {
+	Resource wayland.DataDevice
+
+	manager *Manager
+	// seat is nil for devices of unknown seats
+	seat *Seat
+}
+
+// Seat returns the device's seat, or nil if the device was created for
+// an unknown seat.
+func (dev *Device) Seat() *Seat { return dev.seat }
+
+func (dev *Device) StartDrag(obj wayland.DataDevice, source wayland.DataSource, origin wayland.Surface, icon wayland.Surface, serial uint32) {
+	var src *Source
+	if source.Conn() != nil {
+		src = source.Implementation().(*Source)
+		if src.used {
+			dev.manager.dsp.Error(source, uint32(wayland.DataSourceErrorInvalidSource), "source has already been used")
+			return
+		}
+	}
+	if icon.Conn() != nil {
+		if cs, ok := compositor.SurfaceFromResource(icon); ok && cs.Role() != dragIcon && !cs.SetRole(iconRoleName, dragIcon) {
+			dev.manager.dsp.Error(obj, uint32(wayland.DataDeviceErrorRole),
+				fmt.Sprintf("%s@%d already has a role", icon.Interface().Name, icon.ID()))
+			return
+		}
+	}
+	if src != nil {
+		src.used = true
+	}
+	s := dev.seat
+	if s == nil || s.drag != nil || s.events.StartDrag == nil {
+		if src != nil {
+			src.cancel()
+		}
+		return
+	}
+	drag := &Drag{
+		Source: src,
+		Client: obj.Conn(),
+		Origin: origin,
+		Icon:   icon,
+		Serial: serial,
+		seat:   s,
+	}
+	if !s.events.StartDrag(drag) {
+		if src != nil {
+			src.cancel()
+		}
+		return
+	}
+	s.drag = drag
+	if src != nil {
+		src.drag = drag
+	}
+}
+
+func (dev *Device) SetSelection(obj wayland.DataDevice, source wayland.DataSource, serial uint32) {
+	s := dev.seat
+	var src *Source
+	if source.Conn() != nil {
+		src = source.Implementation().(*Source)
+		if src.actionsSet {
+			dev.manager.dsp.Error(source, uint32(wayland.DataSourceErrorInvalidSource), "cannot set drag-and-drop source as selection")
+			return
+		}
+		if src.used && (s == nil || src != s.selection) {
+			dev.manager.dsp.Error(source, uint32(wayland.DataSourceErrorInvalidSource), "source has already been used")
+			return
+		}
+		src.used = true
+	}
+	if s == nil {
+		if src != nil {
+			src.cancel()
+		}
+		return
+	}
+	// Reject requests that are older than the current selection, as
+	// well as ones the compositor doesn't consider valid.
+	stale := s.selection != nil && serial-s.selectionSerial > 1<<31
+	if stale || (s.events.ValidSerial != nil && !s.events.ValidSerial(obj.Conn(), serial)) {
+		if src != nil {
+			src.cancel()
+		}
+		return
+	}
+	s.selectionSerial = serial
+	s.setSelection(src)
+}
+
+func (dev *Device) Release(obj wayland.DataDevice) {
+	// cleanup is handled by the destroy listener
+}
@@ -0,0 +1,149 @@
+package datadevice
+
+import (
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+// Drag is a drag and drop operation started by a client.
+type Drag struct {
+	// Source is the dragged data. It is nil for drags without data,
+	// which are only visible to the surfaces of the client that
+	// started them.
+	Source *Source
+	// Client is the client that started the drag.
+	Client *wlserver.Client
+	// Origin is the surface the drag started on.
+	Origin wayland.Surface
+	// Icon is the surface to display under the pointer, if its Conn
+	// isn't nil. It has the wl_data_device-icon role. It is the
+	// compositor's responsibility to display it.
+	Icon wayland.Surface
+	// Serial is the serial of the implicit grab that started the drag.
+	Serial uint32
+
+	seat *Seat
+	// focus is the surface the drag is over, if its Conn isn't nil
+	focus wayland.Surface
+	// device is the data device of the focused client, if it has one
+	device wayland.DataDevice
+	offer  *Offer
+	// forced is the action forced by the compositor
+	forced wayland.DataDeviceManagerDndAction
+	done   bool
+}
+
+// Focus returns the surface the drag is over. The surface's Conn is nil
+// if the drag isn't over any surface.
+func (dr *Drag) Focus() wayland.Surface { return dr.focus }
+
+// Done reports whether the drag has ended.
+func (dr *Drag) Done() bool { return dr.done }
+
+// SetFocus moves the drag to a surface, at surface-local coordinates x
+// and y. Passing a surface whose Conn is nil, such as the zero value,
+// moves the drag away from all surfaces.
+func (dr *Drag) SetFocus(surface wayland.Surface, x, y wlshared.Fixed) {
+	if dr.done || surface == dr.focus {
+		return
+	}
+	dr.leave()
+	dr.focus = surface
+	c := surface.Conn()
+	if c == nil || (dr.Source == nil && c != dr.Client) {
+		return
+	}
+	devs := dr.seat.devices[c]
+	if len(devs) == 0 {
+		return
+	}
+	dr.device = devs[0]
+	var offer wayland.DataOffer
+	if dr.Source != nil {
+		dr.offer = dr.Source.newOffer(dr.device, dr)
+		offer = dr.offer.Resource
+	}
+	dr.device.Enter(dr.seat.manager.dsp.NextSerial(), surface, x, y, offer)
+	if dr.offer != nil {
+		dr.offer.updateAction()
+	}
+}
+
+// leave tells the focused client that the drag left its surface.
+func (dr *Drag) leave() {
+	if dr.device.Conn() != nil {
+		dr.device.Leave()
+	}
+	if o := dr.offer; o != nil && !o.dropped && o.accepted != "" && o.source != nil {
+		o.source.Resource.Target("")
+	}
+	dr.focus = wayland.Surface{}
+	dr.device = wayland.DataDevice{}
+	dr.offer = nil
+}
+
+// Motion moves the drag within the focused surface.
+func (dr *Drag) Motion(time uint32, x, y wlshared.Fixed) {
+	if dr.done || dr.device.Conn() == nil {
+		return
+	}
+	dr.device.Motion(time, x, y)
+}
+
+// ForceAction forces the action of the drag, provided that both the
+// source and the target support it. Compositors use this to select
+// actions in response to keyboard modifiers. ActionNone lets the
+// target's preference decide.
+func (dr *Drag) ForceAction(action wayland.DataDeviceManagerDndAction) {
+	dr.forced = action
+	if dr.offer != nil {
+		dr.offer.updateAction()
+	}
+}
+
+// Drop ends the drag by dropping the data on the focused surface. The
+// drop fails, and the source is cancelled, unless the target accepted
+// one of the mime types and an action could be negotiated.
+func (dr *Drag) Drop() {
+	if dr.done {
+		return
+	}
+	if dr.Source == nil {
+		// drags within a client don't need to be accepted
+		if dr.device.Conn() != nil {
+			dr.device.Drop()
+		}
+	} else if o := dr.offer; o != nil && o.accepted != "" && o.action != ActionNone {
+		dr.device.Drop()
+		o.dropped = true
+		if dr.Source.Resource.Version() >= 3 {
+			dr.Source.Resource.DndDropPerformed()
+		}
+	} else {
+		dr.Source.cancel()
+	}
+	dr.end()
+}
+
+// Cancel ends the drag without dropping the data.
+func (dr *Drag) Cancel() {
+	if dr.done {
+		return
+	}
+	if dr.Source != nil {
+		dr.Source.cancel()
+	}
+	dr.end()
+}
+
+func (dr *Drag) end() {
+	dr.leave()
+	dr.done = true
+	if dr.seat.drag == dr {
+		dr.seat.drag = nil
+	}
+	if dr.seat.events.DragEnd != nil {
+		dr.seat.events.DragEnd(dr)
+	}
+}
@@ -0,0 +1,290 @@
+package datadevice
+
+import (
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+// Drag and drop actions.
+const (
+	ActionNone = wayland.DataDeviceManagerDndActionNone
+	ActionCopy = wayland.DataDeviceManagerDndActionCopy
+	ActionMove = wayland.DataDeviceManagerDndActionMove
+	ActionAsk  = wayland.DataDeviceManagerDndActionAsk
+
+	allActions = ActionCopy | ActionMove | ActionAsk
+)
+
+// Source is a wl_data_source, the data offered by a client.
+type Source struct {
+	Resource wayland.DataSource
+
+	manager    *Manager
+	mimeTypes  []string
+	actions    wayland.DataDeviceManagerDndAction
+	actionsSet bool
+	// used is set once the source has been used for the selection or
+	// a drag, after which it can't be used again.
+	used bool
+	// seat is the seat whose selection the source is
+	seat *Seat
+	drag *Drag
+	// offers are the offers of the source's data that the clients
+	// haven't destroyed yet
+	offers    []*Offer
+	destroyed bool
+}
+
+// MimeTypes returns the mime types the data is offered in.
+func (src *Source) MimeTypes() []string { return src.mimeTypes }
+
+// Actions returns the drag and drop actions supported by the source.
+func (src *Source) Actions() wayland.DataDeviceManagerDndAction { return src.actions }
+
+// Send requests the data in the given mime type, which the client will
+// write to fd. The caller retains ownership of fd. This allows
+// compositors to read the selection themselves, for example to
+// implement clipboard persistence.
+func (src *Source) Send(mimeType string, fd uintptr) {
+	if src.destroyed {
+		return
+	}
+	src.Resource.Send(mimeType, fd)
+}
+
+func (src *Source) Offer(obj wayland.DataSource, mimeType string) {
+	src.mimeTypes = append(src.mimeTypes, mimeType)
+}
+
+func (src *Source) Destroy(obj wayland.DataSource) {
+	// cleanup is handled by the destroy listener
+}
+
+func (src *Source) SetActions(obj wayland.DataSource, dndActions wayland.DataDeviceManagerDndAction) {
+	if src.actionsSet {
+		src.manager.dsp.Error(obj, uint32(wayland.DataSourceErrorInvalidActionMask), "cannot set actions more than once")
+		return
+	}
+	if dndActions&^allActions != 0 {
+		src.manager.dsp.Error(obj, uint32(wayland.DataSourceErrorInvalidActionMask), "invalid action mask")
+		return
+	}
+	if src.used {
+		src.manager.dsp.Error(obj, uint32(wayland.DataSourceErrorInvalidSource), "cannot set actions after the source has been used")
+		return
+	}
+	src.actions = dndActions
+	src.actionsSet = true
+}
+
+// cancel tells the client that the source is no longer used.
+func (src *Source) cancel() {
+	if !src.destroyed {
+		src.Resource.Cancelled()
+	}
+}
+
+func (src *Source) handleDestroy() {
+	src.destroyed = true
+	for _, o := range src.offers {
+		o.source = nil
+	}
+	src.offers = nil
+	if s := src.seat; s != nil && s.selection == src {
+		s.setSelection(nil)
+	}
+	if dr := src.drag; dr != nil && !dr.done {
+		dr.Cancel()
+	}
+}
+
+// newOffer creates an offer of the source's data for a data device.
+// Drag is nil for offers of the selection.
+func (src *Source) newOffer(dev wayland.DataDevice, drag *Drag) *Offer {
+	o := &Offer{
+		manager: src.manager,
+		source:  src,
+		drag:    drag,
+	}
+	o.Resource = dev.Conn().NewResource(wayland.DataOfferInterface, dev.Version(), o).(wayland.DataOffer)
+	o.Resource.OnDestroy(o.handleDestroy)
+	src.offers = append(src.offers, o)
+
+	dev.DataOffer(o.Resource)
+	for _, mimeType := range src.mimeTypes {
+		o.Resource.Offer(mimeType)
+	}
+	if drag != nil && o.Resource.Version() >= 3 {
+		o.Resource.SourceActions(src.actions)
+	}
+	return o
+}
+
+// Offer is a wl_data_offer, which represents a source's data to the
+// client receiving it.
+type Offer struct {
+	Resource wayland.DataOffer
+
+	manager *Manager
+	// source is nil once the source has been destroyed
+	source *Source
+	// drag is nil for offers of the selection
+	drag *Drag
+
+	// state of drag and drop offers
+	accepted   string
+	actions    wayland.DataDeviceManagerDndAction
+	preferred  wayland.DataDeviceManagerDndAction
+	action     wayland.DataDeviceManagerDndAction
+	actionSent bool
+	dropped    bool
+	finished   bool
+}
+
+// Source returns the source of the offered data, or nil if it has been
+// destroyed.
+func (o *Offer) Source() *Source { return o.source }
+
+// active reports whether the offer is still part of a drag and drop
+// operation, either as the target of the drag or after being dropped.
+func (o *Offer) active() bool {
+	if o.drag == nil || o.source == nil || o.finished {
+		return false
+	}
+	return o.dropped || o.drag.offer == o
+}
+
+func (o *Offer) Accept(obj wayland.DataOffer, serial uint32, mimeType string) {
+	if !o.active() {
+		return
+	}
+	o.accepted = mimeType
+	o.source.Resource.Target(mimeType)
+}
+
+func (o *Offer) Receive(obj wayland.DataOffer, mimeType string, fd uintptr) {
+	if o.source != nil {
+		o.source.Send(mimeType, fd)
+	}
+	// The client sees EOF once the source closes its copy.
+	unix.Close(int(fd))
+}
+
+func (o *Offer) Destroy(obj wayland.DataOffer) {
+	// cleanup is handled by the destroy listener
+}
+
+func (o *Offer) Finish(obj wayland.DataOffer) {
+	dsp := o.manager.dsp
+	if o.drag == nil || !o.dropped || o.finished {
+		dsp.Error(obj, uint32(wayland.DataOfferErrorInvalidFinish), "premature finish request")
+		return
+	}
+	if o.action == ActionNone || o.action == ActionAsk {
+		dsp.Error(obj, uint32(wayland.DataOfferErrorInvalidFinish), "offer finished with an invalid action")
+		return
+	}
+	o.finish()
+}
+
+func (o *Offer) finish() {
+	o.finished = true
+	if o.source != nil && o.source.Resource.Version() >= 3 {
+		o.source.Resource.DndFinished()
+	}
+}
+
+func (o *Offer) SetActions(obj wayland.DataOffer, dndActions wayland.DataDeviceManagerDndAction, preferredAction wayland.DataDeviceManagerDndAction) {
+	dsp := o.manager.dsp
+	if dndActions&^allActions != 0 {
+		dsp.Error(obj, uint32(wayland.DataOfferErrorInvalidActionMask), "invalid action mask")
+		return
+	}
+	if preferredAction != ActionNone && (preferredAction&(preferredAction-1) != 0 || preferredAction&dndActions == 0) {
+		dsp.Error(obj, uint32(wayland.DataOfferErrorInvalidAction), "invalid preferred action")
+		return
+	}
+	if o.drag == nil {
+		dsp.Error(obj, uint32(wayland.DataOfferErrorInvalidOffer), "set_actions on a selection offer")
+		return
+	}
+	if o.dropped && o.action != ActionAsk {
+		dsp.Error(obj, uint32(wayland.DataOfferErrorInvalidOffer), "set_actions after the drop")
+		return
+	}
+	o.actions = dndActions
+	o.preferred = preferredAction
+	o.updateAction()
+}
+
+// updateAction selects the action of a drag and drop offer and
+// notifies both sides if it changed.
+func (o *Offer) updateAction() {
+	if !o.active() {
+		return
+	}
+	var action wayland.DataDeviceManagerDndAction
+	if o.Resource.Version() < 3 {
+		// Targets that can't set actions implicitly support copying.
+		action = ActionCopy
+	} else {
+		forced := ActionNone
+		if !o.dropped {
+			// the compositor can't influence the action after the drop
+			forced = o.drag.forced
+		}
+		action = chooseAction(o.source.actions&o.actions, o.preferred, forced)
+	}
+	if o.actionSent && action == o.action {
+		return
+	}
+	o.action = action
+	o.actionSent = true
+	if o.Resource.Version() >= 3 {
+		o.Resource.Action(action)
+	}
+	if o.source.Resource.Version() >= 3 {
+		o.source.Resource.Action(action)
+	}
+}
+
+// chooseAction picks one of the available actions, preferring the one
+// forced by the compositor, then the one preferred by the target, and
+// otherwise the first one in bit order.
+func chooseAction(available, preferred, forced wayland.DataDeviceManagerDndAction) wayland.DataDeviceManagerDndAction {
+	if forced&available != 0 {
+		return forced
+	}
+	if preferred&available != 0 {
+		return preferred
+	}
+	for _, a := range []wayland.DataDeviceManagerDndAction{ActionCopy, ActionMove, ActionAsk} {
+		if available&a != 0 {
+			return a
+		}
+	}
+	return ActionNone
+}
+
+func (o *Offer) handleDestroy() {
+	src := o.source
+	if src == nil {
+		return
+	}
+	for i, so := range src.offers {
+		if so == o {
+			src.offers = append(src.offers[:i], src.offers[i+1:]...)
+			break
+		}
+	}
+	if o.drag == nil || !o.dropped || o.finished {
+		return
+	}
+	if o.Resource.Version() < 3 {
+		// Targets that can't finish signal the end of the transfer by
+		// destroying the offer.
+		o.finish()
+	} else {
+		src.cancel()
+	}
+}
@@ -0,0 +1,199 @@
+// Package headless implements an in-process compositor without a
+// display, for testing Wayland clients.
+//
+// A Headless compositor provides the wl_compositor, wl_subcompositor,
+// wl_shm, wl_seat, wl_output and xdg_wm_base globals. It renders the
+// shm buffers of mapped windows into memory, where tests can inspect
+// them, and it lets tests inject input through its Seat.
+//
+// The compositor runs its own event loop. Its state, including the
+// globals' objects such as Seat, may only be accessed from functions
+// passed to Do, which run on the event loop.
+//
+// Window management is deliberately simple: new windows are placed
+// at the origin of the output, on top of all other windows, and get
+// keyboard focus when they are mapped. Maximize and fullscreen
+// requests are honored by resizing windows to the size of the output.
+package headless
+
+import (
+	"image/color"
+	"net"
+	"time"
+
+	"honnef.co/go/wayland/wlclient"
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/output"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlserver/seat"
+	"honnef.co/go/wayland/wlserver/shell"
+	"honnef.co/go/wayland/wlserver/shm"
+)
+
+// Default size of the output, used if Options doesn't specify one.
+const (
+	DefaultWidth  = 1024
+	DefaultHeight = 768
+)
+
+// Options configures a Headless compositor. The zero value is a valid
+// configuration.
+type Options struct {
+	// Width and Height are the size of the output in pixels.
+	Width, Height int32
+	// Background is the color of the output where there are no
+	// windows.
+	Background color.RGBA
+	// Disconnect is called when a client disconnects. Err is the
+	// reason, such as a *wlserver.ProtocolError.
+	Disconnect func(client *wlserver.Client, err error)
+}
+
+// Headless is a compositor that renders into memory.
+type Headless struct {
+	// The globals of the compositor. They may only be accessed from
+	// functions passed to Do.
+	Display       *wlserver.Display
+	Compositor    *compositor.Compositor
+	Subcompositor *compositor.Subcompositor
+	Shm           *shm.Shm
+	Seat          *seat.Seat
+	Shell         *shell.Shell
+	Output        *output.Output
+
+	opts  Options
+	start time.Time
+	do    chan func()
+	done  chan struct{}
+	// clients are the clients that haven't disconnected yet
+	clients map[*wlserver.Client]struct{}
+	closing bool
+
+	// windows are the mapped toplevels, from bottom to top
+	windows []*Window
+	// popups are the mapped popups, from bottom to top
+	popups []*shell.Popup
+	focus  *Window
+
+	frame          *Frame
+	repaintPending bool
+}
+
+// New creates a headless compositor and starts its event loop.
+func New(opts Options) *Headless {
+	if opts.Width <= 0 {
+		opts.Width = DefaultWidth
+	}
+	if opts.Height <= 0 {
+		opts.Height = DefaultHeight
+	}
+	h := &Headless{
+		Display: wlserver.NewDisplay(nil),
+		opts:    opts,
+		start:   time.Now(),
+		do:      make(chan func()),
+		done:    make(chan struct{}),
+		clients: make(map[*wlserver.Client]struct{}),
+	}
+	h.Compositor = compositor.AddGlobal(h.Display, compositor.Events{
+		Commit: func(*compositor.Surface) { h.scheduleRepaint() },
+	})
+	h.Subcompositor = compositor.AddSubcompositorGlobal(h.Compositor)
+	h.Shm = shm.AddGlobal(h.Display)
+	h.Seat = seat.AddGlobal(h.Display, "seat0", seat.Events{
+		SurfaceAt: h.SurfaceAt,
+	})
+	h.Seat.SetCapabilities(wayland.SeatCapabilityPointer | wayland.SeatCapabilityKeyboard | wayland.SeatCapabilityTouch)
+	h.Output = output.AddGlobal(h.Display, "HEADLESS-1", output.Mode{
+		Width:     opts.Width,
+		Height:    opts.Height,
+		Refresh:   60000,
+		Preferred: true,
+	})
+	h.Output.SetGeometry(output.Geometry{Make: "headless", Model: "headless"})
+	h.Output.SetDescription("Headless output")
+	h.Shell = shell.AddGlobal(h.Compositor, shell.Events{
+		Map:               h.mapSurface,
+		Unmap:             h.unmapSurface,
+		RequestMaximize:   h.requestMaximize,
+		RequestFullscreen: h.requestFullscreen,
+		PopupBounds:       h.popupBounds,
+	})
+	go h.run()
+	return h
+}
+
+func (h *Headless) run() {
+	defer close(h.done)
+	dsp := h.Display
+	// After Close, keep processing messages until all clients have
+	// disconnected.
+	for !h.closing || len(h.clients) > 0 {
+		select {
+		case msg := <-dsp.Messages():
+			dsp.ProcessMessage(msg)
+		case <-dsp.Posted():
+			dsp.RunPosted()
+		case fn := <-h.do:
+			fn()
+		case d := <-dsp.Disconnects():
+			h.disconnect(d.Client, d.Err)
+		}
+		dsp.FlushClients()
+	}
+}
+
+func (h *Headless) disconnect(client *wlserver.Client, err error) {
+	h.Display.RemoveClient(client)
+	delete(h.clients, client)
+	if h.opts.Disconnect != nil {
+		h.opts.Disconnect(client, err)
+	}
+}
+
+// Do runs fn on the compositor's event loop and waits for it to
+// return. Events sent by fn are flushed to clients afterwards.
+func (h *Headless) Do(fn func()) {
+	done := make(chan struct{})
+	h.do <- func() {
+		defer close(done)
+		fn()
+	}
+	<-done
+}
+
+// Time returns the current time in milliseconds, as used for the
+// timestamps of input events and frame callbacks.
+func (h *Headless) Time() uint32 {
+	return uint32(time.Since(h.start).Milliseconds())
+}
+
+// Connect connects a new client to the compositor.
+func (h *Headless) Connect() (*wlclient.Conn, error) {
+	var conn *net.UnixConn
+	var err error
+	h.Do(func() {
+		var c *wlserver.Client
+		c, conn, err = h.Display.ConnectPair()
+		if err == nil {
+			h.clients[c] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wlclient.NewConn(conn), nil
+}
+
+// Close disconnects all clients and stops the event loop. The
+// compositor must not be used afterwards.
+func (h *Headless) Close() {
+	h.Do(func() {
+		h.closing = true
+		for c := range h.clients {
+			c.Close()
+		}
+	})
+	<-h.done
+}
@@ -0,0 +1,355 @@
+package headless
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	"honnef.co/go/wayland/wlclient"
+	"honnef.co/go/wayland/wlclient/input"
+	cwl "honnef.co/go/wayland/wlclient/protocols/wayland"
+	xdg "honnef.co/go/wayland/wlclient/protocols/xdg-shell"
+	"honnef.co/go/wayland/wlclient/registry"
+	cshm "honnef.co/go/wayland/wlclient/shm"
+	"honnef.co/go/wayland/wlclient/window"
+	"honnef.co/go/wayland/wlclient/xkb"
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlserver/seat"
+)
+
+var (
+	red  = color.RGBA{0xFF, 0, 0, 0xFF}
+	blue = color.RGBA{0, 0, 0xFF, 0xFF}
+)
+
+// client is a connection to the headless compositor whose default
+// queue is dispatched on its own goroutine. Only the test goroutine
+// may call its methods.
+type client struct {
+	t    *testing.T
+	conn *wlclient.Conn
+	dsp  *cwl.Display
+
+	compositor *cwl.Compositor
+	shm        *cwl.Shm
+	wm         *xdg.WmBase
+	seat       *cwl.Seat
+	// seatVersion is the version of seat, which wl_pointer and
+	// wl_keyboard share.
+	seatVersion uint32
+}
+
+func connect(t *testing.T, h *Headless) *client {
+	t.Helper()
+	conn, err := h.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{t: t, conn: conn, dsp: cwl.GetDisplay(conn)}
+	r := registry.New(c.dsp)
+	registry.Require(r, &c.compositor, 1, 5, nil)
+	registry.Require(r, &c.shm, 1, 1, nil)
+	registry.Require(r, &c.wm, 1, 4, nil)
+	registry.Require(r, &c.seat, 1, 7, func(_ *cwl.Seat, version uint32) { c.seatVersion = version })
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			select {
+			case <-conn.Done():
+				return
+			default:
+			}
+			c.dsp.Queue().Dispatch()
+		}
+	}()
+	t.Cleanup(func() {
+		conn.Close()
+		// wake up the dispatching goroutine so that it notices
+		c.dsp.Queue().Post(func() {})
+	})
+	return c
+}
+
+// do runs fn on the dispatching goroutine and waits for the compositor
+// to have processed all requests sent by fn, and for the client to
+// have dispatched all events sent before. Fn must not call t.Fatal.
+func (c *client) do(fn func()) {
+	c.t.Helper()
+	done := make(chan struct{})
+	c.dsp.Queue().Post(func() {
+		fn()
+		c.dsp.Sync().AddListener(cwl.CallbackEvents{
+			Done: func(*cwl.Callback, uint32) { close(done) },
+		})
+	})
+	select {
+	case <-done:
+	case <-c.conn.Done():
+		c.t.Fatalf("connection lost: %v", c.conn.Err())
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for roundtrip")
+	}
+}
+
+// mapWindow maps a toplevel that is filled with col and waits for the
+// compositor to have processed the commit.
+func (c *client) mapWindow(width, height int32, col color.RGBA) *cwl.Surface {
+	c.t.Helper()
+	var s *cwl.Surface
+	var configured bool
+	var err error
+	c.do(func() {
+		// The pool's memory is shared with the compositor by passing
+		// its file descriptor, which makes the rendered pixels depend
+		// on fd passing working.
+		var pool *cshm.Pool
+		pool, err = cshm.NewPool(c.shm, cwl.ShmFormatArgb8888)
+		if err != nil {
+			return
+		}
+		shell := window.NewShell(c.wm, 4)
+		s = c.compositor.CreateSurface()
+		win := shell.NewToplevel(s, width, height, window.ToplevelEvents{
+			Configure: func(w *window.Toplevel, st window.State) {
+				configured = true
+				ww, wh := w.Size()
+				buf, berr := pool.Get(ww, wh)
+				if berr != nil {
+					err = berr
+					return
+				}
+				img := buf.Image()
+				for y := 0; y < int(wh); y++ {
+					for x := 0; x < int(ww); x++ {
+						img.Set(x, y, col)
+					}
+				}
+				buf.Attach(s, 0, 0)
+				s.Damage(0, 0, ww, wh)
+				s.Commit()
+			},
+		})
+		win.Show()
+	})
+	// The first roundtrip delivers the configure event, the second one
+	// the commit of the buffer that was attached in response.
+	c.do(func() {})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if !configured {
+		c.t.Fatal("toplevel wasn't configured")
+	}
+	return s
+}
+
+// window returns the only window of h, and false if there isn't
+// exactly one. It must be called on the compositor's event loop.
+func (h *Headless) window() (*Window, bool) {
+	if len(h.windows) != 1 {
+		return nil, false
+	}
+	return h.windows[0], true
+}
+
+func TestToplevel(t *testing.T) {
+	h := New(Options{Width: 200, Height: 100, Background: blue})
+	defer h.Close()
+	c := connect(t, h)
+	c.mapWindow(40, 20, red)
+
+	var inside, left, right, below color.RGBA
+	var ok bool
+	h.Do(func() {
+		var w *Window
+		w, ok = h.window()
+		if !ok {
+			return
+		}
+		h.Move(w, 30, 20)
+		f := h.Render()
+		inside = f.Image.RGBAAt(35, 25)
+		left = f.Image.RGBAAt(29, 25)
+		right = f.Image.RGBAAt(70, 25)
+		below = f.Image.RGBAAt(35, 40)
+	})
+	if !ok {
+		t.Fatal("expected exactly one window")
+	}
+	for _, tt := range []struct {
+		name      string
+		got, want color.RGBA
+	}{
+		{"inside the window", inside, red},
+		{"left of the window", left, blue},
+		{"right of the window", right, blue},
+		{"below the window", below, blue},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("pixel %s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestPointer(t *testing.T) {
+	h := New(Options{})
+	defer h.Close()
+	c := connect(t, h)
+	var frames []input.PointerFrame
+	c.do(func() {
+		input.NewPointer(c.seat.GetPointer(), c.seatVersion, func(f input.PointerFrame) {
+			frames = append(frames, f)
+		})
+	})
+	s := c.mapWindow(40, 20, red)
+
+	var ok bool
+	h.Do(func() {
+		var w *Window
+		w, ok = h.window()
+		if !ok {
+			return
+		}
+		h.Move(w, 30, 20)
+		h.Seat.PointerMotion(h.Time(), 40, 25)
+		h.Seat.PointerMotion(h.Time(), 45, 30)
+		h.Seat.PointerButton(h.Time(), 0x110, wayland.PointerButtonStatePressed)
+	})
+	if !ok {
+		t.Fatal("expected exactly one window")
+	}
+	c.do(func() {})
+
+	if len(frames) != 3 {
+		t.Fatalf("got %d pointer frames, want 3: %+v", len(frames), frames)
+	}
+	if f := frames[0]; f.Enter == nil || f.Enter.ID() != s.ID() || f.X != 10 || f.Y != 5 {
+		t.Errorf("first frame = %+v, want enter at 10, 5", f)
+	}
+	if f := frames[1]; !f.Moved || f.X != 15 || f.Y != 10 {
+		t.Errorf("second frame = %+v, want motion to 15, 10", f)
+	}
+	if f := frames[2]; len(f.Buttons) != 1 || f.Buttons[0].Button != 0x110 || f.Buttons[0].State != cwl.PointerButtonStatePressed {
+		t.Errorf("third frame = %+v, want press of button 0x110", f)
+	}
+}
+
+const testKeymap = `xkb_keymap {
+xkb_keycodes "test" {
+	minimum = 8;
+	maximum = 255;
+	<AC01> = 38;
+	<LFSH> = 50;
+};
+xkb_types "test" {
+	type "ONE_LEVEL" {
+		modifiers= none;
+	};
+	type "ALPHABETIC" {
+		modifiers= Shift+Lock;
+		map[Shift]= Level2;
+		map[Lock]= Level2;
+	};
+};
+xkb_compatibility "test" {
+};
+xkb_symbols "test" {
+	key <AC01> { [ a, A ] };
+	key <LFSH> { [ Shift_L ] };
+	modifier_map Shift { <LFSH> };
+};
+};`
+
+func TestKeyboard(t *testing.T) {
+	h := New(Options{})
+	defer h.Close()
+	var err error
+	h.Do(func() {
+		// The keymap is sent to clients as a file descriptor.
+		err = h.Seat.SetKeymap(wayland.KeyboardKeymapFormatXkbV1, []byte(testKeymap))
+		h.Seat.SetRepeatInfo(0, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := connect(t, h)
+	var keymap *xkb.Keymap
+	var keymapErr error
+	var entered *cwl.Surface
+	var keys []input.KeyEvent
+	c.do(func() {
+		input.NewKeyboard(c.seat.GetKeyboard(), input.KeyboardEvents{
+			Keymap: func(km *xkb.Keymap) { keymap = km },
+			Error:  func(err error) { keymapErr = err },
+			Enter:  func(s *cwl.Surface, serial uint32, keys []xkb.Keycode) { entered = s },
+			Key:    func(ev input.KeyEvent) { keys = append(keys, ev) },
+		})
+	})
+	s := c.mapWindow(40, 20, red)
+	if keymapErr != nil {
+		t.Fatal(keymapErr)
+	}
+	if keymap == nil {
+		t.Fatal("didn't receive a keymap")
+	}
+	if entered == nil || entered.ID() != s.ID() {
+		t.Fatalf("keyboard entered %v, want the window's surface", entered)
+	}
+
+	const keyA = 30 // evdev KEY_A
+	h.Do(func() {
+		h.Seat.KeyboardKey(h.Time(), keyA, wayland.KeyboardKeyStatePressed)
+		h.Seat.KeyboardKey(h.Time(), keyA, wayland.KeyboardKeyStateReleased)
+		h.Seat.KeyboardModifiers(seat.Modifiers{Depressed: 1 << 0})
+		h.Seat.KeyboardKey(h.Time(), keyA, wayland.KeyboardKeyStatePressed)
+	})
+	c.do(func() {})
+
+	want := []struct {
+		state cwl.KeyboardKeyState
+		sym   xkb.Keysym
+		text  string
+	}{
+		{cwl.KeyboardKeyStatePressed, xkb.Keya, "a"},
+		{cwl.KeyboardKeyStateReleased, xkb.Keya, ""},
+		{cwl.KeyboardKeyStatePressed, xkb.KeyA, "A"},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("got %d key events, want %d: %+v", len(keys), len(want), keys)
+	}
+	for i, w := range want {
+		ev := keys[i]
+		if ev.Keycode != keyA+8 || ev.State != w.state || ev.Keysym != w.sym || ev.Text != w.text {
+			t.Errorf("key event %d = %+v, want keycode %d, state %d, keysym %s, text %q", i, ev, keyA+8, w.state, w.sym, w.text)
+		}
+	}
+}
+
+func TestDisconnectWithFrameCallbacks(t *testing.T) {
+	// Removing a client destroys its objects, including frame callbacks
+	// that were never committed. That must not send delete_id to the
+	// client that is going away.
+	errs := make(chan error, 1)
+	h := New(Options{Disconnect: func(_ *wlserver.Client, err error) { errs <- err }})
+	defer h.Close()
+	c := connect(t, h)
+	c.do(func() {
+		for i := 0; i < 4; i++ {
+			s := c.compositor.CreateSurface()
+			s.Frame()
+			s.Frame()
+		}
+	})
+	c.conn.Close()
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client wasn't disconnected")
+	}
+	// The compositor must still be serving other clients.
+	connect(t, h).do(func() {})
+}
@@ -0,0 +1,143 @@
+package headless
+
+import (
+	"image"
+	"image/color"
+
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/shm"
+)
+
+// Frame is an image of the output.
+type Frame struct {
+	// Seq is the sequence number of the frame, starting at 1.
+	Seq uint64
+	// Time is the time at which the frame was rendered, in
+	// milliseconds.
+	Time  uint32
+	Image *image.RGBA
+}
+
+// LastFrame returns the most recently rendered frame, or nil if
+// nothing has been rendered yet.
+func (h *Headless) LastFrame() *Frame { return h.frame }
+
+// scheduleRepaint renders a frame once the event loop is done with the
+// current batch of work, such as the client's commits.
+func (h *Headless) scheduleRepaint() {
+	if h.repaintPending {
+		return
+	}
+	h.repaintPending = true
+	h.Display.Post(func() {
+		if h.repaintPending {
+			h.Render()
+		}
+	})
+}
+
+// Render renders the output and tells all surfaces that have requested
+// frame callbacks that they have been presented. Surfaces learn whether
+// they are on the output. Windows are drawn
+// from bottom to top, followed by popups. Buffers that aren't shm
+// buffers are ignored.
+//
+// The compositor renders automatically after surfaces have been
+// committed; tests call Render to get a frame that is up to date with
+// all requests that have been processed.
+func (h *Headless) Render() *Frame {
+	h.repaintPending = false
+	img := image.NewRGBA(image.Rect(0, 0, int(h.opts.Width), int(h.opts.Height)))
+	bg := h.opts.Background
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i+0] = bg.R
+		img.Pix[i+1] = bg.G
+		img.Pix[i+2] = bg.B
+		img.Pix[i+3] = bg.A
+	}
+	visible := make(map[*compositor.Surface]struct{})
+	for _, w := range h.windows {
+		x, y := w.Origin()
+		drawTree(img, w.Surface(), x, y, visible)
+	}
+	for _, p := range h.popups {
+		if xs := p.Surface(); xs != nil {
+			if x, y, ok := h.surfaceOrigin(xs); ok {
+				drawTree(img, xs.Surface(), x, y, visible)
+			}
+		}
+	}
+	for _, s := range h.Output.Surfaces() {
+		if _, ok := visible[s]; !ok {
+			h.Output.Leave(s)
+		}
+	}
+	for s := range visible {
+		h.Output.Enter(s)
+	}
+
+	var seq uint64 = 1
+	if h.frame != nil {
+		seq = h.frame.Seq + 1
+	}
+	h.frame = &Frame{Seq: seq, Time: h.Time(), Image: img}
+	h.Compositor.FrameDone(h.frame.Time)
+	return h.frame
+}
+
+// drawTree draws root and its sub-surfaces, with root's origin at x, y.
+// The surfaces that are at least partially on the output are added to
+// visible.
+func drawTree(dst *image.RGBA, root *compositor.Surface, x, y int32, visible map[*compositor.Surface]struct{}) {
+	root.Walk(func(s *compositor.Surface, sx, sy int32) {
+		if drawSurface(dst, s, int(x+sx), int(y+sy)) {
+			visible[s] = struct{}{}
+		}
+	})
+}
+
+// drawSurface draws the contents of s with its origin at x, y, blending
+// them over dst. It reports whether any part of s is within dst.
+func drawSurface(dst *image.RGBA, s *compositor.Surface, x, y int) bool {
+	w, h := s.Size()
+	r := image.Rect(x, y, x+int(w), y+int(h)).Intersect(dst.Rect)
+	if r.Empty() || s.Current().Buffer.Conn() == nil {
+		return false
+	}
+	buf, ok := shm.BufferFromResource(s.Current().Buffer)
+	if !ok {
+		// We can't draw the buffer, but it would be visible.
+		return true
+	}
+	buf.Access(func(src *shm.Image) {
+		for py := r.Min.Y; py < r.Max.Y; py++ {
+			for px := r.Min.X; px < r.Max.X; px++ {
+				bx, by := s.BufferPoint(px-x, py-y)
+				dst.SetRGBA(px, py, over(src.RGBAAt(bx, by), dst.RGBAAt(px, py)))
+			}
+		}
+	})
+	return true
+}
+
+// over composites the premultiplied colors src over dst.
+func over(src, dst color.RGBA) color.RGBA {
+	if src.A == 0xFF {
+		return src
+	}
+	a := 0xFF - uint32(src.A)
+	blend := func(s, d uint8) uint8 {
+		v := uint32(s) + (uint32(d)*a+0x7F)/0xFF
+		if v > 0xFF {
+			// The source wasn't properly premultiplied.
+			v = 0xFF
+		}
+		return uint8(v)
+	}
+	return color.RGBA{
+		R: blend(src.R, dst.R),
+		G: blend(src.G, dst.G),
+		B: blend(src.B, dst.B),
+		A: blend(src.A, dst.A),
+	}
+}
@@ -0,0 +1,240 @@
+package headless
+
+import (
+	"image"
+	"math"
+
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlserver/shell"
+)
+
+// Window is a mapped toplevel.
+type Window struct {
+	Toplevel *shell.Toplevel
+	// X and Y are the position of the window geometry on the output.
+	X, Y int32
+}
+
+// Surface returns the window's surface.
+func (w *Window) Surface() *compositor.Surface {
+	return w.Toplevel.Surface().Surface()
+}
+
+// Origin returns the position of the window's surface on the output,
+// which differs from the window's position if the window geometry
+// doesn't start at the surface's origin.
+func (w *Window) Origin() (x, y int32) {
+	g := w.Toplevel.Surface().Geometry()
+	return w.X - int32(g.Min.X), w.Y - int32(g.Min.Y)
+}
+
+// Windows returns the mapped toplevels in stacking order, from bottom
+// to top.
+func (h *Headless) Windows() []*Window { return h.windows }
+
+// Popups returns the mapped popups in stacking order, from bottom to
+// top.
+func (h *Headless) Popups() []*shell.Popup { return h.popups }
+
+// Focus returns the window with keyboard focus, or nil.
+func (h *Headless) Focus() *Window { return h.focus }
+
+// FindWindow returns the window of the toplevel, or nil if it isn't
+// mapped.
+func (h *Headless) FindWindow(t *shell.Toplevel) *Window {
+	for _, w := range h.windows {
+		if w.Toplevel == t {
+			return w
+		}
+	}
+	return nil
+}
+
+// Move moves w to x, y. Reactive popups get placed anew.
+func (h *Headless) Move(w *Window, x, y int32) {
+	w.X, w.Y = x, y
+	for _, p := range h.popups {
+		if p.Positioner().Reactive {
+			p.Configure()
+		}
+	}
+	h.scheduleRepaint()
+}
+
+// Activate raises w to the top and gives it keyboard focus. Passing
+// nil removes the keyboard focus.
+func (h *Headless) Activate(w *Window) {
+	if w != nil {
+		h.raise(w)
+	}
+	if w == h.focus {
+		return
+	}
+	if old := h.focus; old != nil {
+		st := old.Toplevel.Pending()
+		st.Activated = false
+		old.Toplevel.Configure(st)
+	}
+	h.focus = w
+	if w == nil {
+		h.Seat.SetKeyboardFocus(nil)
+		return
+	}
+	st := w.Toplevel.Pending()
+	st.Activated = true
+	w.Toplevel.Configure(st)
+	h.Seat.SetKeyboardFocus(w.Surface())
+}
+
+// raise moves w to the top of the stacking order.
+func (h *Headless) raise(w *Window) {
+	for i, ww := range h.windows {
+		if ww == w {
+			h.windows = append(h.windows[:i], h.windows[i+1:]...)
+			break
+		}
+	}
+	h.windows = append(h.windows, w)
+	h.scheduleRepaint()
+}
+
+func (h *Headless) mapSurface(xs *shell.Surface) {
+	if t := xs.Toplevel(); t != nil {
+		w := &Window{Toplevel: t}
+		h.windows = append(h.windows, w)
+		h.Activate(w)
+	}
+	if p := xs.Popup(); p != nil {
+		h.popups = append(h.popups, p)
+	}
+	h.scheduleRepaint()
+}
+
+func (h *Headless) unmapSurface(xs *shell.Surface) {
+	for i, p := range h.popups {
+		if p.Surface() == xs {
+			h.popups = append(h.popups[:i], h.popups[i+1:]...)
+			break
+		}
+	}
+	for i, w := range h.windows {
+		if w.Toplevel.Surface() == xs {
+			h.windows = append(h.windows[:i], h.windows[i+1:]...)
+			if w == h.focus {
+				h.focus = nil
+				var next *Window
+				if len(h.windows) > 0 {
+					next = h.windows[len(h.windows)-1]
+				}
+				h.Activate(next)
+			}
+			break
+		}
+	}
+	h.scheduleRepaint()
+}
+
+func (h *Headless) requestMaximize(t *shell.Toplevel, maximized bool) {
+	st := t.Pending()
+	st.Maximized = maximized
+	h.configureSize(t, st)
+}
+
+func (h *Headless) requestFullscreen(t *shell.Toplevel, fullscreen bool, output wayland.Output) {
+	st := t.Pending()
+	st.Fullscreen = fullscreen
+	h.configureSize(t, st)
+}
+
+// configureSize configures t with st, sized to cover the output if the
+// window is maximized or fullscreen, and moves the window to the
+// output's origin.
+func (h *Headless) configureSize(t *shell.Toplevel, st shell.ToplevelState) {
+	if st.Maximized || st.Fullscreen {
+		st.Width, st.Height = h.opts.Width, h.opts.Height
+		if w := h.FindWindow(t); w != nil {
+			h.Move(w, 0, 0)
+		}
+	} else {
+		st.Width, st.Height = 0, 0
+	}
+	t.Configure(st)
+}
+
+// popupBounds constrains popups to the output.
+func (h *Headless) popupBounds(p *shell.Popup) image.Rectangle {
+	x, y, ok := h.geometryPosition(p.Parent())
+	if !ok {
+		return image.Rectangle{}
+	}
+	return image.Rect(0, 0, int(h.opts.Width), int(h.opts.Height)).Sub(image.Pt(int(x), int(y)))
+}
+
+// geometryPosition returns the position of the window geometry of xs
+// on the output. It returns false if xs isn't mapped.
+func (h *Headless) geometryPosition(xs *shell.Surface) (x, y int32, ok bool) {
+	if xs == nil || !xs.Mapped() {
+		return 0, 0, false
+	}
+	if t := xs.Toplevel(); t != nil {
+		if w := h.FindWindow(t); w != nil {
+			return w.X, w.Y, true
+		}
+		return 0, 0, false
+	}
+	if p := xs.Popup(); p != nil {
+		px, py, ok := h.geometryPosition(p.Parent())
+		if !ok {
+			return 0, 0, false
+		}
+		g := p.Geometry()
+		return px + int32(g.Min.X), py + int32(g.Min.Y), true
+	}
+	return 0, 0, false
+}
+
+// surfaceOrigin returns the position of the surface of xs on the
+// output.
+func (h *Headless) surfaceOrigin(xs *shell.Surface) (x, y int32, ok bool) {
+	x, y, ok = h.geometryPosition(xs)
+	g := xs.Geometry()
+	return x - int32(g.Min.X), y - int32(g.Min.Y), ok
+}
+
+// SurfaceAt returns the surface at the position x, y of the output, and
+// the position relative to that surface. Popups are above all windows.
+// It returns nil if there is no surface at the position.
+func (h *Headless) SurfaceAt(x, y float64) (s *compositor.Surface, sx, sy float64) {
+	for i := len(h.popups) - 1; i >= 0; i-- {
+		xs := h.popups[i].Surface()
+		if xs == nil {
+			continue
+		}
+		if ox, oy, ok := h.surfaceOrigin(xs); ok {
+			if s, sx, sy := surfaceAt(xs.Surface(), x-float64(ox), y-float64(oy)); s != nil {
+				return s, sx, sy
+			}
+		}
+	}
+	for i := len(h.windows) - 1; i >= 0; i-- {
+		ox, oy := h.windows[i].Origin()
+		if s, sx, sy := surfaceAt(h.windows[i].Surface(), x-float64(ox), y-float64(oy)); s != nil {
+			return s, sx, sy
+		}
+	}
+	return nil, 0, 0
+}
+
+// surfaceAt returns the topmost surface of the tree of sub-surfaces of
+// root that accepts input at x, y, relative to root.
+func surfaceAt(root *compositor.Surface, x, y float64) (s *compositor.Surface, sx, sy float64) {
+	root.Walk(func(ss *compositor.Surface, ox, oy int32) {
+		lx, ly := x-float64(ox), y-float64(oy)
+		// Later surfaces are above earlier ones.
+		if ss.AcceptsInput(int(math.Floor(lx)), int(math.Floor(ly))) {
+			s, sx, sy = ss, lx, ly
+		}
+	})
+	return s, sx, sy
+}
@@ -155,5 +155,5 @@ func (callbackResource) Interface() *wlproto.Interface { return callbackInterfac
 type callbackImplementation interface{}
 
 func (obj callbackResource) Done(callbackData uint32) {
-	obj.Conn().SendEvent(obj, 0, callbackData)
+	obj.Conn().SendDestructor(obj, 0, callbackData)
 }
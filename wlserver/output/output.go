@@ -0,0 +1,315 @@
+// Package output implements wl_output globals for compositors.
+//
+// An Output describes a monitor, or another area that displays part
+// of the compositor's space. Its properties, such as its mode and
+// scale, are set through methods, which send the corresponding events
+// to all clients that have bound the output. Changes made during one
+// iteration of the display's event loop are followed by a single done
+// event, so that clients apply them atomically; the event loop has to
+// run the functions scheduled with wlserver.Display.Post for this to
+// work.
+//
+// Outputs also track the surfaces that are shown on them, which get
+// told about entering and leaving outputs.
+package output
+
+import (
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+// Mode is a video mode of an output.
+type Mode struct {
+	// Width and Height are the size of the mode in physical pixels.
+	Width, Height int32
+	// Refresh is the vertical refresh rate in mHz, or zero if it
+	// isn't known.
+	Refresh int32
+	// Preferred is set if this is the output's preferred mode.
+	Preferred bool
+}
+
+// Geometry are the geometric properties of an output.
+type Geometry struct {
+	// X and Y are the position of the output in the compositor's
+	// space.
+	X, Y int32
+	// PhysicalWidth and PhysicalHeight are the size of the output in
+	// millimeters, or zero if it isn't known or doesn't make sense,
+	// e.g. for projectors.
+	PhysicalWidth, PhysicalHeight int32
+	Subpixel                      wayland.OutputSubpixel
+	Make, Model                   string
+	// Transform is the transform that the compositor applies when
+	// displaying the output's contents, e.g. for rotated monitors.
+	Transform wayland.OutputTransform
+}
+
+// Output is an implementation of a wl_output global.
+type Output struct {
+	dsp    *wlserver.Display
+	global uint32
+
+	name        string
+	description string
+	geometry    Geometry
+	mode        Mode
+	scale       int32
+
+	resources []wayland.Output
+	surfaces  map[*compositor.Surface]struct{}
+	// watched are the surfaces whose destruction we listen for
+	watched map[*compositor.Surface]struct{}
+	// donePending is set if a done event has been scheduled
+	donePending bool
+	removed     bool
+}
+
+// AddGlobal adds a wl_output global to the display. Name uniquely
+// identifies the output, such as "DP-1", and must not be reused for
+// other outputs while the compositor is running. The output has a
+// scale of 1 and the given mode; other properties have to be set
+// before the display processes further messages.
+func AddGlobal(dsp *wlserver.Display, name string, mode Mode) *Output {
+	out := &Output{
+		dsp:      dsp,
+		name:     name,
+		mode:     mode,
+		scale:    1,
+		surfaces: make(map[*compositor.Surface]struct{}),
+		watched:  make(map[*compositor.Surface]struct{}),
+	}
+	out.global = wayland.AddOutputGlobal(dsp, 4, func(res wayland.Output) wayland.OutputImplementation {
+		out.bind(res)
+		return out
+	})
+	return out
+}
+
+// OutputFromResource returns the output that implements res. It
+// returns false if res is a null object.
+func OutputFromResource(res wayland.Output) (*Output, bool) {
+	if res.Conn() == nil {
+		return nil, false
+	}
+	out, ok := res.Implementation().(*Output)
+	return out, ok
+}
+
+func (out *Output) bind(res wayland.Output) {
+	if out.removed {
+		// The client raced with the removal of the output and will
+		// learn about it shortly. Leave the resource inert.
+		return
+	}
+	out.resources = append(out.resources, res)
+	res.OnDestroy(func() {
+		for i, r := range out.resources {
+			if r == res {
+				out.resources = append(out.resources[:i], out.resources[i+1:]...)
+				break
+			}
+		}
+	})
+	out.sendGeometry(res)
+	out.sendMode(res)
+	if res.Version() >= 2 {
+		res.Scale(out.scale)
+	}
+	if res.Version() >= 4 {
+		res.Name(out.name)
+		res.Description(out.description)
+	}
+	if res.Version() >= 2 {
+		res.Done()
+	}
+	// Surfaces that the client created before binding the output
+	// still have to learn that they are on it.
+	for s := range out.surfaces {
+		if s.Resource.Conn() == res.Conn() {
+			s.Resource.Enter(res)
+		}
+	}
+}
+
+func (out *Output) Release(obj wayland.Output) {}
+
+// Name returns the name of the output.
+func (out *Output) Name() string { return out.name }
+
+// Description returns the human-readable description of the output.
+func (out *Output) Description() string { return out.description }
+
+// Geometry returns the geometric properties of the output.
+func (out *Output) Geometry() Geometry { return out.geometry }
+
+// Mode returns the current mode of the output.
+func (out *Output) Mode() Mode { return out.mode }
+
+// Scale returns the scale factor of the output.
+func (out *Output) Scale() int32 { return out.scale }
+
+// Removed reports whether the output has been removed.
+func (out *Output) Removed() bool { return out.removed }
+
+// Resources returns the client's resources for the output.
+func (out *Output) Resources(client *wlserver.Client) []wayland.Output {
+	var ress []wayland.Output
+	for _, res := range out.resources {
+		if res.Conn() == client {
+			ress = append(ress, res)
+		}
+	}
+	return ress
+}
+
+// SetDescription sets the human-readable description of the output,
+// such as "Foocorp 11" Display". Only clients that bound version 4 or
+// newer of wl_output get told about it.
+func (out *Output) SetDescription(description string) {
+	if description == out.description {
+		return
+	}
+	out.description = description
+	for _, res := range out.resources {
+		if res.Version() >= 4 {
+			res.Description(description)
+		}
+	}
+	out.scheduleDone()
+}
+
+// SetGeometry sets the geometric properties of the output.
+func (out *Output) SetGeometry(g Geometry) {
+	if g == out.geometry {
+		return
+	}
+	out.geometry = g
+	for _, res := range out.resources {
+		out.sendGeometry(res)
+	}
+	out.scheduleDone()
+}
+
+// SetMode sets the current mode of the output. Only the current mode
+// is advertised to clients.
+func (out *Output) SetMode(m Mode) {
+	if m == out.mode {
+		return
+	}
+	out.mode = m
+	for _, res := range out.resources {
+		out.sendMode(res)
+	}
+	out.scheduleDone()
+}
+
+// SetScale sets the scale factor of the output. Clients render their
+// surfaces at this scale to appear crisp on high density outputs.
+func (out *Output) SetScale(scale int32) {
+	if scale == out.scale {
+		return
+	}
+	out.scale = scale
+	for _, res := range out.resources {
+		if res.Version() >= 2 {
+			res.Scale(scale)
+		}
+	}
+	out.scheduleDone()
+}
+
+func (out *Output) sendGeometry(res wayland.Output) {
+	g := &out.geometry
+	res.Geometry(g.X, g.Y, g.PhysicalWidth, g.PhysicalHeight, g.Subpixel, g.Make, g.Model, g.Transform)
+}
+
+func (out *Output) sendMode(res wayland.Output) {
+	flags := wayland.OutputModeCurrent
+	if out.mode.Preferred {
+		flags |= wayland.OutputModePreferred
+	}
+	res.Mode(flags, out.mode.Width, out.mode.Height, out.mode.Refresh)
+}
+
+// scheduleDone sends the done event once the display's event loop is
+// done with the current batch of work, which allows making several
+// changes that clients apply atomically. Clients that bound version 1
+// of wl_output apply changes as they arrive.
+func (out *Output) scheduleDone() {
+	if out.donePending {
+		return
+	}
+	out.donePending = true
+	out.dsp.Post(func() {
+		out.donePending = false
+		for _, res := range out.resources {
+			if res.Version() >= 2 {
+				res.Done()
+			}
+		}
+	})
+}
+
+// Surfaces returns the surfaces that are on the output.
+func (out *Output) Surfaces() []*compositor.Surface {
+	surfaces := make([]*compositor.Surface, 0, len(out.surfaces))
+	for s := range out.surfaces {
+		surfaces = append(surfaces, s)
+	}
+	return surfaces
+}
+
+// Contains reports whether s is on the output.
+func (out *Output) Contains(s *compositor.Surface) bool {
+	_, ok := out.surfaces[s]
+	return ok
+}
+
+// Enter tells s that it is on the output, because some part of it is
+// shown there. Compositors call it when surfaces get mapped or move.
+// Entering an output that the surface is already on does nothing.
+func (out *Output) Enter(s *compositor.Surface) {
+	if out.removed || out.Contains(s) || s.Destroyed() {
+		return
+	}
+	out.surfaces[s] = struct{}{}
+	if _, ok := out.watched[s]; !ok {
+		out.watched[s] = struct{}{}
+		s.Resource.OnDestroy(func() {
+			delete(out.watched, s)
+			delete(out.surfaces, s)
+		})
+	}
+	for _, res := range out.Resources(s.Resource.Conn()) {
+		s.Resource.Enter(res)
+	}
+}
+
+// Leave tells s that it is no longer on the output.
+func (out *Output) Leave(s *compositor.Surface) {
+	if !out.Contains(s) {
+		return
+	}
+	delete(out.surfaces, s)
+	for _, res := range out.Resources(s.Resource.Conn()) {
+		s.Resource.Leave(res)
+	}
+}
+
+// Remove removes the output, e.g. because the monitor got unplugged.
+// All surfaces leave the output. Clients may keep using their
+// resources until they release them, but don't receive any further
+// events.
+func (out *Output) Remove() {
+	if out.removed {
+		return
+	}
+	for s := range out.surfaces {
+		out.Leave(s)
+	}
+	out.removed = true
+	out.resources = nil
+	out.dsp.RemoveGlobal(out.global)
+}
@@ -1,24 +1,58 @@
+// Package wlserver provides a low-level server and runtime for the
+// Wayland protocol, for implementing compositors.
+//
+// This package provides the types and low-level functions for
+// accepting client connections, as well as runtime support for
+// code-generated protocol implementations.
+//
+// # Event loop
+//
+// A Display isn't safe for concurrent use. Instead, the compositor
+// runs an event loop on a single goroutine, which receives from the
+// channels returned by NewConns, Messages, Disconnects and Posted,
+// handles what it received, and calls FlushClients:
+//
+//	for {
+//		select {
+//		case conn := <-dsp.NewConns():
+//			dsp.AddClient(conn)
+//		case msg := <-dsp.Messages():
+//			dsp.ProcessMessage(msg)
+//		case d := <-dsp.Disconnects():
+//			dsp.RemoveClient(d.Client)
+//		case <-dsp.Posted():
+//			dsp.RunPosted()
+//		}
+//		dsp.FlushClients()
+//	}
+//
+// Functions scheduled with Post must be run by the event loop: the
+// display relies on them for flushing clients that couldn't receive
+// all of their events at once and for removing globals, and so do
+// packages like output and shell, for batching events and for
+// timeouts.
 package wlserver
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net"
+	"os"
 	"reflect"
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 	"unsafe"
 
+	"golang.org/x/sys/unix"
 	"honnef.co/go/wayland/wlproto"
 	"honnef.co/go/wayland/wlshared"
 )
 
-// TODO implement removal of globals, take the race documented in
-// https://gitlab.freedesktop.org/wayland/wayland/-/issues/10 into consideration
-
 var byteOrder binary.ByteOrder
 
 func init() {
@@ -30,17 +64,36 @@ func init() {
 	}
 }
 
+// serverIDStart is the first object ID of the range used for objects
+// created by the server, such as wl_data_offer.
+const serverIDStart = 0xff000000
+
+// DefaultMaxBufferSize is the default maximum number of bytes that
+// may be queued for a client before it is considered unresponsive.
+const DefaultMaxBufferSize = 1 << 16
+
 type Display struct {
 	l        *net.UnixListener
 	clientID uint64
 
+	maxBufferSize int
+
 	clients   map[*Client]struct{}
 	globalsID uint32
+	serial    uint32
 	globals   map[uint32]global
 
 	newConns    chan net.Conn
 	messages    chan Message
 	disconnects chan Disconnect
+
+	// posted are the functions scheduled by Post, in order, and
+	// postedWake has a value while posted isn't empty
+	postedMu   sync.Mutex
+	posted     []func()
+	postedWake chan struct{}
+
+	tracer wlshared.Tracer
 }
 
 type Disconnect struct {
@@ -49,14 +102,74 @@ type Disconnect struct {
 }
 
 func NewDisplay(l *net.UnixListener) *Display {
-	return &Display{
-		l:           l,
-		clients:     make(map[*Client]struct{}),
-		globals:     map[uint32]global{},
-		newConns:    make(chan net.Conn),
-		messages:    make(chan Message),
-		disconnects: make(chan Disconnect),
+	dsp := &Display{
+		l:             l,
+		maxBufferSize: DefaultMaxBufferSize,
+		clients:       make(map[*Client]struct{}),
+		globals:       map[uint32]global{},
+		newConns:      make(chan net.Conn),
+		messages:      make(chan Message),
+		disconnects:   make(chan Disconnect),
+		postedWake:    make(chan struct{}, 1),
+	}
+	if wlshared.DebugEnabled("server") {
+		dsp.tracer = wlshared.NewWriterTracer(os.Stderr)
 	}
+	return dsp
+}
+
+// SetTracer sets the tracer that receives all requests received and
+// all events sent by the display, replacing the one installed for
+// WAYLAND_DEBUG. A nil tracer disables tracing. Like other methods of
+// Display, it must be called from the compositor's event loop.
+func (dsp *Display) SetTracer(t wlshared.Tracer) {
+	dsp.tracer = t
+}
+
+func (dsp *Display) trace(sent bool, obj Object, opcode int, args []interface{}) {
+	iface := obj.Interface()
+	var name string
+	var sig []wlproto.Arg
+	if sent {
+		name, sig = iface.Events[opcode].Name, iface.Events[opcode].Args
+	} else {
+		name, sig = iface.Requests[opcode].Name, iface.Requests[opcode].Args
+	}
+	dsp.tracer.Trace(&wlshared.TraceMessage{
+		Time:      time.Now(),
+		Sent:      sent,
+		Interface: iface,
+		Object:    obj.ID(),
+		Opcode:    opcode,
+		Name:      name,
+		Args:      wlshared.TraceArgs(sig, args),
+	})
+}
+
+// SetMaxBufferSize sets the maximum number of bytes of outgoing events
+// that may be buffered per client. Clients that don't read their
+// events fast enough for their buffer to stay below this size get
+// disconnected with ErrBufferOverflow. The limit applies to buffers
+// after their next flush.
+func (dsp *Display) SetMaxBufferSize(n int) {
+	dsp.maxBufferSize = n
+}
+
+// FlushClients tries to send all buffered events to all clients. It
+// doesn't block; events that can't be sent yet remain buffered until
+// the next call. Compositors should call it once per iteration of
+// their event loop, after processing messages.
+func (dsp *Display) FlushClients() {
+	for c := range dsp.clients {
+		c.Flush()
+	}
+}
+
+// NextSerial returns a new serial, for use in events that carry one,
+// such as input events and configure events.
+func (dsp *Display) NextSerial() uint32 {
+	dsp.serial++
+	return dsp.serial
 }
 
 func (dsp *Display) NewConns() <-chan net.Conn {
@@ -71,6 +184,41 @@ func (dsp *Display) Disconnects() <-chan Disconnect {
 	return dsp.disconnects
 }
 
+// Post schedules fn to be run by the compositor's event loop, which
+// has to call RunPosted whenever the channel returned by Posted
+// becomes ready. Functions run in the order in which they were
+// posted. It is safe to call Post from any goroutine, which makes it
+// suitable for timers and other asynchronous work that needs to
+// access the display's state.
+func (dsp *Display) Post(fn func()) {
+	dsp.postedMu.Lock()
+	dsp.posted = append(dsp.posted, fn)
+	dsp.postedMu.Unlock()
+	select {
+	case dsp.postedWake <- struct{}{}:
+	default:
+	}
+}
+
+// Posted returns a channel that is ready when functions have been
+// scheduled by Post. The event loop should call RunPosted when it
+// receives from the channel.
+func (dsp *Display) Posted() <-chan struct{} {
+	return dsp.postedWake
+}
+
+// RunPosted runs the functions that have been scheduled by Post.
+// Functions that they post in turn run during the next call.
+func (dsp *Display) RunPosted() {
+	dsp.postedMu.Lock()
+	fns := dsp.posted
+	dsp.posted = nil
+	dsp.postedMu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
 type ProtocolError struct {
 	Object  Object
 	Code    uint32
@@ -81,19 +229,38 @@ func (err *ProtocolError) Error() string {
 	return fmt.Sprintf("protocol error with code %d for object %s: %s", err.Code, objectString(err.Object), err.Message)
 }
 
+// ErrBufferOverflow is the error with which clients get disconnected
+// when they don't read events fast enough.
+var ErrBufferOverflow = errors.New("client's event buffer overflowed")
+
 func (dsp *Display) Error(obj Object, code uint32, message string) {
-	obj.Conn().objects[1].(displayResource).Error(obj, code, message)
+	c := obj.Conn()
+	c.objects[1].(displayResource).Error(obj, code, message)
+	// Make a best effort at telling the client why it is being
+	// disconnected.
+	c.Flush()
 	err := error(&ProtocolError{obj, code, message})
-	obj.Conn().err.CompareAndSwap(nil, &err)
-	obj.Conn().rw.Close()
+	c.fail(err)
 }
 
 type global struct {
 	iface   *wlproto.Interface
 	version int
 	bind    func(Object) ResourceImplementation
+	// removed is set for tombstones, globals that have been removed
+	// but can still be bound
+	removed bool
 }
 
+// GlobalTombstoneTime is how long removed globals can still be bound.
+//
+// Clients may try to bind a global before they have received the
+// event announcing its removal. To avoid disconnecting them for this
+// race, RemoveGlobal keeps the global around as a tombstone for a
+// while. Implementations of removed globals have to accept new
+// resources, which they typically leave inert.
+const GlobalTombstoneTime = 10 * time.Second
+
 func (dsp *Display) AddGlobal(iface *wlproto.Interface, version int, bind func(Object) ResourceImplementation) uint32 {
 	dsp.globalsID++
 	name := dsp.globalsID
@@ -101,7 +268,7 @@ func (dsp *Display) AddGlobal(iface *wlproto.Interface, version int, bind func(O
 		// XXX reclaim names used by deleted globals
 		panic("global counter overflow")
 	}
-	dsp.globals[name] = global{iface, version, bind}
+	dsp.globals[name] = global{iface: iface, version: version, bind: bind}
 
 	for c := range dsp.clients {
 		for _, obj := range c.registries {
@@ -112,14 +279,27 @@ func (dsp *Display) AddGlobal(iface *wlproto.Interface, version int, bind func(O
 	return name
 }
 
+// RemoveGlobal announces the removal of a global to all clients. The
+// global can still be bound for GlobalTombstoneTime, after which it
+// gets destroyed; the display's event loop has to process posted
+// functions for this to happen.
 func (dsp *Display) RemoveGlobal(name uint32) {
-	delete(dsp.globals, name)
+	g, ok := dsp.globals[name]
+	if !ok || g.removed {
+		return
+	}
+	g.removed = true
+	dsp.globals[name] = g
 
 	for c := range dsp.clients {
 		for _, obj := range c.registries {
 			obj.GlobalRemove(name)
 		}
 	}
+
+	time.AfterFunc(GlobalTombstoneTime, func() {
+		dsp.Post(func() { delete(dsp.globals, name) })
+	})
 }
 
 type Message struct {
@@ -131,12 +311,14 @@ type Message struct {
 
 func (dsp *Display) AddClient(conn net.Conn) *Client {
 	client := &Client{
-		dsp:             dsp,
-		id:              dsp.clientID,
-		rw:              conn.(*net.UnixConn),
-		objects:         map[wlshared.ObjectID]Object{},
-		implementations: map[wlshared.ObjectID]ResourceImplementation{},
-		registries:      map[wlshared.ObjectID]registryResource{},
+		dsp:              dsp,
+		id:               dsp.clientID,
+		rw:               conn.(*net.UnixConn),
+		objects:          map[wlshared.ObjectID]Object{},
+		implementations:  map[wlshared.ObjectID]ResourceImplementation{},
+		registries:       map[wlshared.ObjectID]registryResource{},
+		destroyListeners: map[wlshared.ObjectID][]func(){},
+		nextServerID:     serverIDStart,
 	}
 
 	client.objects[1] = displayResource{
@@ -163,9 +345,56 @@ func (dsp *Display) AddClient(conn net.Conn) *Client {
 	return client
 }
 
+// ConnectPair connects a new client over a pair of sockets created by
+// socketpair(2), which allows running clients in the same process as
+// the compositor, e.g. in tests, without creating a socket in the file
+// system. It returns the client and the client's end of the
+// connection, for use with wlclient.NewConn. Like AddClient, it must
+// be called from the goroutine that processes the display's messages.
+func (dsp *Display) ConnectPair() (*Client, *net.UnixConn, error) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't create socket pair: %w", err)
+	}
+	server, err := fileConn(fds[0])
+	if err != nil {
+		unix.Close(fds[1])
+		return nil, nil, err
+	}
+	client, err := fileConn(fds[1])
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+	return dsp.AddClient(server), client, nil
+}
+
+// fileConn turns a socket into a *net.UnixConn, taking ownership of
+// fd.
+func fileConn(fd int) (*net.UnixConn, error) {
+	f := os.NewFile(uintptr(fd), "")
+	defer f.Close()
+	conn, err := net.FileConn(f)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create connection: %w", err)
+	}
+	return conn.(*net.UnixConn), nil
+}
+
+// RemoveClient removes a client from the display. All of the client's
+// resources are destroyed, which runs their destroy listeners.
 func (dsp *Display) RemoveClient(client *Client) {
 	// XXX properly disconnect the client if it isn't already disconnected
 	delete(dsp.clients, client)
+	client.removed = true
+	// Destroy listeners may destroy further objects, which requires
+	// wl_display to still exist.
+	for id := range client.objects {
+		if id != 1 {
+			client.destroy(id)
+		}
+	}
+	client.destroy(1)
 }
 
 type buf []byte
@@ -193,13 +422,14 @@ type displaySingleton struct {
 func (dsp displaySingleton) GetRegistry(obj displayResource, registry registryResource) registryImplementation {
 	obj.conn.registries[registry.ID()] = registry
 	for name, g := range dsp.dsp.globals {
-		registry.Global(name, g.iface.Name, uint32(g.version))
+		if !g.removed {
+			registry.Global(name, g.iface.Name, uint32(g.version))
+		}
 	}
 	return dsp
 }
 
 func (dsp displaySingleton) Sync(obj displayResource, cb callbackResource) callbackImplementation {
-	// XXX how… how do we destroy the callback after calling done? answer: by fixing wayland-scanner; the event has a destructor type
 	// XXX "The callback_data passed in the callback is the event serial."
 	cb.Done(0)
 	// wl_callback has no requests, it doesn't matter what we return here, except that it has to be non-nil
@@ -209,17 +439,20 @@ func (dsp displaySingleton) Sync(obj displayResource, cb callbackResource) callb
 func (dsp displaySingleton) Bind(reg registryResource, name uint32, idName string, idVersion uint32, id wlshared.ObjectID) ResourceImplementation {
 	g, ok := dsp.dsp.globals[name]
 	if !ok {
-		if name > dsp.dsp.globalsID {
-			// XXX the client tried to bind to a global that has never existed and we should kill the client
-		} else {
-			// XXX the global has been removed and the client's bind raced with the removal. we should set up a
-			// tombstone that later gets destroyed by the client.
-		}
+		// The global never existed, or it was removed long enough ago
+		// that the client can't have missed it.
+		dsp.dsp.Error(reg.conn.objects[1], uint32(displayErrorInvalidObject),
+			fmt.Sprintf("invalid global %s (%d)", idName, name))
+		// Bind didn't create a resource, the implementation will be
+		// ignored.
+		return dsp
 	}
 
+	// XXX kill the client if it tries to bind a version newer than the global's
 	res := Resource{
-		conn: reg.conn,
-		id:   id,
+		conn:    reg.conn,
+		id:      id,
+		version: idVersion,
 	}
 	rv := reflect.New(g.iface.Type).Elem()
 	rv.Field(0).Set(reflect.ValueOf(res))
@@ -269,11 +502,25 @@ func (dsp *Display) ProcessMessage(msg Message) {
 		switch arg.Type {
 		case wlproto.ArgTypeObject:
 			// XXX guard against invalid object id
-			args[i] = reflect.ValueOf(c.objects[argv.(wlshared.ObjectID)])
+			// XXX guard against the object having the wrong type
+			if argObj, ok := c.objects[argv.(wlshared.ObjectID)]; ok {
+				args[i] = reflect.ValueOf(argObj)
+			} else if arg.Aux != nil {
+				// null object
+				args[i] = reflect.Zero(arg.Aux)
+			} else {
+				args[i] = reflect.Zero(objectType)
+			}
 		case wlproto.ArgTypeFd:
+			// The fds for a message are received no later than the
+			// message itself, so the queue can't be empty here unless
+			// the client is misbehaving.
+			c.fdsMu.Lock()
+			// XXX kill the client if it didn't send enough fds
 			fd := c.fds[0]
 			copy(c.fds, c.fds[1:])
 			c.fds = c.fds[:len(c.fds)-1]
+			c.fdsMu.Unlock()
 			args[i] = reflect.ValueOf(uintptr(fd))
 		case wlproto.ArgTypeNewID:
 			// XXX verify that the new ID isn't already in use
@@ -283,10 +530,11 @@ func (dsp *Display) ProcessMessage(msg Message) {
 			if arg.Aux == nil {
 				args[i] = reflect.ValueOf(argv)
 			} else {
-				// XXX set the resource's version. it will be tied to the version of the resource to which the request is being sent
+				// New objects inherit the version of the object that created them.
 				res := Resource{
-					conn: c,
-					id:   wlshared.ObjectID(num),
+					conn:    c,
+					id:      wlshared.ObjectID(num),
+					version: obj.GetResource().Version(),
 				}
 				rv := reflect.New(arg.Aux).Elem()
 				rv.Field(0).Set(reflect.ValueOf(res))
@@ -299,6 +547,14 @@ func (dsp *Display) ProcessMessage(msg Message) {
 		}
 	}
 
+	if dsp.tracer != nil {
+		vals := make([]interface{}, len(args))
+		for i := range args {
+			vals[i] = args[i].Interface()
+		}
+		dsp.trace(false, obj, int(opcode), vals)
+	}
+
 	// XXX guard against opcodes that don't exist in our version of the protocol
 	meth := obj.Interface().Requests[opcode].Method
 	results := meth.Call(allArgs)
@@ -306,22 +562,23 @@ func (dsp *Display) ProcessMessage(msg Message) {
 	n := 0
 	for i, arg := range sig {
 		if arg.Type == wlproto.ArgTypeNewID {
-			var obj Object
+			var id wlshared.ObjectID
 			if arg.Aux == nil {
-				id := args[i].Interface().(wlshared.ObjectID)
-				obj = c.objects[id]
+				id = args[i].Interface().(wlshared.ObjectID)
 			} else {
-				obj = args[i].Interface().(Object)
+				id = args[i].Interface().(Object).ID()
+			}
+			// The object may have already been destroyed by the
+			// request, for example by sending wl_callback.done.
+			if obj, ok := c.objects[id]; ok {
+				obj.GetResource().SetImplementation(results[n].Interface().(ResourceImplementation))
 			}
-			obj.GetResource().SetImplementation(results[n].Interface().(ResourceImplementation))
 			n++
 		}
 	}
 
 	if obj.Interface().Requests[opcode].Type == "destructor" {
-		delete(c.objects, obj.ID())
-		delete(c.implementations, obj.ID())
-		c.objects[1].(displayResource).DeleteID(uint32(obj.ID()))
+		c.destroyResource(obj)
 	}
 }
 
@@ -335,7 +592,6 @@ func (dsp *Display) Run() {
 		if err != nil {
 			// XXX
 			panic(err)
-			return
 		}
 		dsp.newConns <- conn
 	}
@@ -352,35 +608,137 @@ type Client struct {
 	// objects map.
 	registries      map[wlshared.ObjectID]registryResource
 	implementations map[wlshared.ObjectID]ResourceImplementation
+	// destroyListeners contains the functions to call when objects get
+	// destroyed.
+	destroyListeners map[wlshared.ObjectID][]func()
+	// removed is set once the client has been removed from the
+	// display, after which its objects get destroyed silently.
+	removed bool
+	// nextServerID is the next candidate for an object ID in the server's
+	// ID range.
+	nextServerID wlshared.ObjectID
 
 	err atomic.Value
 
-	fds []uintptr
+	// fds is written to by the read loop and consumed by ProcessMessage.
+	fdsMu sync.Mutex
+	fds   []uintptr
 
+	// sendBuf and sendFds contain events that have been queued but
+	// not yet flushed to the client. The fds are owned by us and get
+	// closed once they have been sent.
 	sendMu  sync.RWMutex
 	sendBuf []byte
+	sendFds []int
+	// sendFdMsgs contains, for each fd in sendFds, the offset in
+	// sendBuf of the message it belongs to.
+	sendFdMsgs []int
+	// writing is set while a goroutine waits for the socket to become
+	// writable, to send the events that couldn't be sent yet.
+	writing bool
 }
 
 func (c *Client) ID() uint64 { return c.id }
 
+// Close closes the connection to the client. The display reports the
+// disconnect like any other.
+func (c *Client) Close() error { return c.rw.Close() }
+
+// NewResource creates an object in the server's ID range, such as the
+// wl_data_offer introduced by wl_data_device.data_offer. The returned
+// object has the type of iface and should be sent to the client in a
+// new_id argument of an event.
+func (c *Client) NewResource(iface *wlproto.Interface, version uint32, impl ResourceImplementation) Object {
+	id := c.nextServerID
+	for {
+		if _, ok := c.objects[id]; !ok {
+			break
+		}
+		id++
+		if id == 0 {
+			id = serverIDStart
+		}
+		if id == c.nextServerID {
+			// XXX handle running out of IDs more gracefully
+			panic("out of server object IDs")
+		}
+	}
+	c.nextServerID = id + 1
+	if c.nextServerID == 0 {
+		c.nextServerID = serverIDStart
+	}
+
+	res := Resource{
+		conn:    c,
+		id:      id,
+		version: version,
+	}
+	rv := reflect.New(iface.Type).Elem()
+	rv.Field(0).Set(reflect.ValueOf(res))
+	v := rv.Interface().(Object)
+	c.objects[id] = v
+	c.implementations[id] = impl
+	return v
+}
+
+// SendDestructor sends an event that destroys the object it is sent
+// to, such as wl_callback.done, and destroys the object.
+func (c *Client) SendDestructor(source Object, event int, args ...interface{}) {
+	c.SendEvent(source, event, args...)
+	c.destroyResource(source)
+}
+
+// destroyResource destroys an object and tells the client that its ID
+// may be reused.
+func (c *Client) destroyResource(obj Object) {
+	if _, ok := c.objects[obj.ID()]; !ok {
+		return
+	}
+	_, failed := c.err.Load().(*error)
+	if obj.ID() < serverIDStart && !c.removed && !failed {
+		// Like libwayland, we don't acknowledge the deletion of
+		// objects in the server's ID range.
+		if dsp, ok := c.objects[1].(displayResource); ok {
+			dsp.DeleteID(uint32(obj.ID()))
+		}
+	}
+	c.destroy(obj.ID())
+}
+
+// destroy removes an object and runs its destroy listeners.
+func (c *Client) destroy(id wlshared.ObjectID) {
+	if _, ok := c.objects[id]; !ok {
+		return
+	}
+	delete(c.objects, id)
+	delete(c.implementations, id)
+	delete(c.registries, id)
+	fns := c.destroyListeners[id]
+	delete(c.destroyListeners, id)
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Display returns the display the client is connected to.
+func (c *Client) Display() *Display { return c.dsp }
+
 func (c *Client) read(b []byte) (int, error) {
-	// XXX can there be more than one SCM per message?
-	// XXX in general, be more robust in handling SCM
-	oob := make([]byte, 24)
+	oob := make([]byte, wlshared.OOBSize)
 	n, oobn, _, _, err := c.rw.ReadMsgUnix(b, oob)
 	if err != nil {
 		return n, err
 	}
-	if oobn == 24 {
-		scm, err := syscall.ParseSocketControlMessage(oob[:oobn])
-		if err != nil {
-			return n, err
+	fds, err := wlshared.ParseFds(oob[:oobn])
+	if len(fds) > 0 {
+		c.fdsMu.Lock()
+		for _, fd := range fds {
+			c.fds = append(c.fds, uintptr(fd))
 		}
-		fds, err := syscall.ParseUnixRights(&scm[0])
-		if err != nil {
-			return n, err
-		}
-		c.fds = append(c.fds, uintptr(fds[0]))
+		c.fdsMu.Unlock()
+	}
+	if err != nil {
+		return n, err
 	}
 	return n, nil
 }
@@ -441,11 +799,36 @@ func (p Resource) SetImplementation(impl ResourceImplementation) {
 	p.conn.implementations[p.id] = impl
 }
 
+// Implementation returns the implementation of the resource, as
+// returned by the request that created it.
+func (p Resource) Implementation() ResourceImplementation {
+	return p.conn.implementations[p.id]
+}
+
+// OnDestroy registers fn to be called when the resource gets
+// destroyed, either by a destructor request or because the client
+// disconnected.
+func (p Resource) OnDestroy(fn func()) {
+	p.conn.destroyListeners[p.id] = append(p.conn.destroyListeners[p.id], fn)
+}
+
+// Destroy destroys the resource, as if the client had sent a
+// destructor request, and runs its destroy listeners. Compositors use
+// it for objects that the protocol destroys implicitly, such as the
+// frame callbacks of a destroyed surface.
+func (p Resource) Destroy() {
+	if obj, ok := p.conn.objects[p.id]; ok {
+		p.conn.destroyResource(obj)
+	}
+}
+
 func (p Resource) GetResource() Resource { return p }
 func (p Resource) Conn() *Client         { return p.conn }
 func (p Resource) ID() wlshared.ObjectID { return p.id }
 func (p Resource) Version() uint32       { return p.version }
 
+var objectType = reflect.TypeOf((*Object)(nil)).Elem()
+
 type Object interface {
 	ID() wlshared.ObjectID
 	Conn() *Client
@@ -453,19 +836,180 @@ type Object interface {
 	GetResource() Resource
 }
 
+// SendEvent queues an event to be sent to the client. Events are
+// buffered until the next call to Flush or Display.FlushClients. If
+// the buffer would grow beyond the display's maximum buffer size even
+// after trying to flush it, the client gets disconnected.
+//
+// File descriptors passed as arguments are duplicated, and the caller
+// retains ownership of the originals.
 func (c *Client) SendEvent(source wlshared.Object, event int, args ...interface{}) {
 	c.sendMu.Lock()
 	defer c.sendMu.Unlock()
 
-	buf := c.sendBuf[:0]
-	var oob []byte
-	buf, oob = wlshared.EncodeRequest(buf, source.ID(), event, args)
-	_, _, err := c.rw.WriteMsgUnix(buf, oob, nil)
+	if _, ok := c.err.Load().(*error); ok {
+		// don't bother queuing events for clients that have already failed
+		return
+	}
+
+	var sig []wlproto.Arg
+	if obj, ok := source.(Object); ok {
+		sig = obj.Interface().Events[event].Args
+		if c.dsp.tracer != nil {
+			c.dsp.trace(true, obj, event, args)
+		}
+	}
+	n := len(c.sendBuf)
+	buf, fds := wlshared.EncodeMessage(c.sendBuf, source.ID(), event, sig, args)
+	c.sendBuf = buf
+	for _, fd := range fds {
+		dup, err := unix.FcntlInt(uintptr(fd), unix.F_DUPFD_CLOEXEC, 0)
+		if err != nil {
+			c.failLocked(err)
+			return
+		}
+		c.sendFds = append(c.sendFds, dup)
+		c.sendFdMsgs = append(c.sendFdMsgs, n)
+	}
+
+	if len(c.sendBuf) > c.dsp.maxBufferSize && n > 0 {
+		// Try to make room by flushing the events that were queued
+		// before this one.
+		c.flushLocked()
+		if len(c.sendBuf) > c.dsp.maxBufferSize {
+			c.failLocked(ErrBufferOverflow)
+		}
+	}
+}
+
+// Flush tries to send all buffered events to the client without
+// blocking. Events that can't be sent yet remain buffered until the
+// client has made room by reading, at which point a function that
+// flushes them gets posted to the display's event loop.
+func (c *Client) Flush() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.flushLocked()
+}
+
+func (c *Client) flushLocked() {
+	if len(c.sendBuf) == 0 {
+		return
+	}
+	if _, ok := c.err.Load().(*error); ok {
+		return
+	}
+
+	rc, err := c.rw.SyscallConn()
 	if err != nil {
-		// Set c.err if it hasn't been set yet
-		c.err.CompareAndSwap(nil, &err)
-		c.rw.Close()
+		c.failLocked(err)
+		return
+	}
+	var werr error
+	err = rc.Write(func(fd uintptr) bool {
+		werr = c.writeLocked(fd)
+		// never wait for the socket to become writable
+		return true
+	})
+	if err == nil {
+		err = werr
 	}
+	if err == unix.EAGAIN {
+		// The client isn't reading fast enough. Flush again once it
+		// has made room, without blocking the event loop.
+		if !c.writing {
+			c.writing = true
+			go c.waitWritable(rc)
+		}
+		return
+	}
+	if err != nil {
+		c.failLocked(err)
+	}
+}
 
-	c.sendBuf = buf[:0]
+// waitWritable waits for the client's socket to become writable and
+// then has the display's event loop flush the client.
+func (c *Client) waitWritable(rc syscall.RawConn) {
+	err := rc.Write(func(fd uintptr) bool {
+		pfd := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLOUT}}
+		n, err := unix.Poll(pfd, 0)
+		// Wait for the socket to become writable unless it already
+		// is, or polling failed and writing will report the error.
+		return n > 0 || (err != nil && err != unix.EINTR)
+	})
+	if err != nil {
+		// The connection has been closed.
+		return
+	}
+	c.dsp.Post(func() {
+		c.sendMu.Lock()
+		defer c.sendMu.Unlock()
+		c.writing = false
+		c.flushLocked()
+	})
+}
+
+// writeLocked sends as many of the buffered events as possible to fd.
+// It returns unix.EAGAIN if the socket's buffer is full.
+func (c *Client) writeLocked(fd uintptr) error {
+	for len(c.sendBuf) > 0 {
+		data := c.sendBuf
+		fds := c.sendFds
+		if len(fds) > wlshared.MaxFds {
+			// The kernel refuses to pass more fds with a single
+			// message. Only send the messages whose fds fit, so that
+			// no message arrives before its fds.
+			fds = fds[:wlshared.MaxFds]
+			if end := c.sendFdMsgs[wlshared.MaxFds]; end > 0 {
+				data = data[:end]
+			}
+		}
+		var oob []byte
+		if len(fds) > 0 {
+			oob = unix.UnixRights(fds...)
+		}
+		n, err := unix.SendmsgN(int(fd), data, oob, nil, unix.MSG_DONTWAIT|unix.MSG_NOSIGNAL)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// The file descriptors have been sent along with the first
+		// byte of the written data.
+		for _, fd := range fds {
+			unix.Close(fd)
+		}
+		c.sendFds = c.sendFds[:copy(c.sendFds, c.sendFds[len(fds):])]
+		c.sendFdMsgs = c.sendFdMsgs[:copy(c.sendFdMsgs, c.sendFdMsgs[len(fds):])]
+		for i := range c.sendFdMsgs {
+			if c.sendFdMsgs[i] -= n; c.sendFdMsgs[i] < 0 {
+				// a single message with too many fds
+				c.sendFdMsgs[i] = 0
+			}
+		}
+		c.sendBuf = c.sendBuf[:copy(c.sendBuf, c.sendBuf[n:])]
+	}
+	return nil
+}
+
+// fail marks the client as failed with err, unless it has already
+// failed, and closes its connection.
+func (c *Client) fail(err error) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.failLocked(err)
+}
+
+func (c *Client) failLocked(err error) {
+	// Set c.err if it hasn't been set yet
+	c.err.CompareAndSwap(nil, &err)
+	c.rw.Close()
+	for _, fd := range c.sendFds {
+		unix.Close(fd)
+	}
+	c.sendFds = nil
+	c.sendFdMsgs = nil
+	c.sendBuf = nil
 }
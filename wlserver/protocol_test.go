@@ -0,0 +1,53 @@
+package wlserver
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPost(t *testing.T) {
+	dsp := NewDisplay(nil)
+	before := runtime.NumGoroutine()
+	var got []int
+	for i := 0; i < 100; i++ {
+		i := i
+		dsp.Post(func() {
+			got = append(got, i)
+			if i == 0 {
+				dsp.Post(func() { got = append(got, 100) })
+			}
+		})
+	}
+	// Posting doesn't start goroutines that would leak if the event
+	// loop stopped.
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Post started %d goroutines", n-before)
+	}
+
+	<-dsp.Posted()
+	dsp.RunPosted()
+	if len(got) != 100 {
+		t.Fatalf("ran %d functions, want 100", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("functions ran out of order: %v", got)
+		}
+	}
+
+	// The function posted while running the others runs next time.
+	select {
+	case <-dsp.Posted():
+	default:
+		t.Fatal("Posted isn't ready after posting from a posted function")
+	}
+	dsp.RunPosted()
+	if len(got) != 101 || got[100] != 100 {
+		t.Errorf("got %v, want the function posted during RunPosted to run last", got[100:])
+	}
+	select {
+	case <-dsp.Posted():
+		t.Error("Posted is ready without posted functions")
+	default:
+	}
+}
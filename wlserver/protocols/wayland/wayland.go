@@ -190,6 +190,33 @@ var Events = map[string]*wlproto.Event{
 	"wl_output_description":             &OutputInterface.Events[5],
 }
 
+func init() {
+	wlproto.ServerRegistry.Register(
+		DisplayInterface,
+		RegistryInterface,
+		CallbackInterface,
+		CompositorInterface,
+		ShmPoolInterface,
+		ShmInterface,
+		BufferInterface,
+		DataOfferInterface,
+		DataSourceInterface,
+		DataDeviceInterface,
+		DataDeviceManagerInterface,
+		ShellInterface,
+		ShellSurfaceInterface,
+		SurfaceInterface,
+		SeatInterface,
+		PointerInterface,
+		KeyboardInterface,
+		TouchInterface,
+		OutputInterface,
+		RegionInterface,
+		SubcompositorInterface,
+		SubsurfaceInterface,
+	)
+}
+
 // These errors are global and can be emitted in response to any
 // server request.
 type DisplayError uint32
@@ -205,6 +232,18 @@ const (
 	DisplayErrorImplementation DisplayError = 3
 )
 
+var DisplayErrorEnum = &wlproto.Enum{
+	Name: "wl_display.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_object", Value: 0},
+		{Name: "invalid_method", Value: 1},
+		{Name: "no_memory", Value: 2},
+		{Name: "implementation", Value: 3},
+	},
+}
+
+func (v DisplayError) String() string { return DisplayErrorEnum.Format(uint32(v)) }
+
 var DisplayInterface = &wlproto.Interface{
 	Name:    "wl_display",
 	Version: 1,
@@ -260,8 +299,8 @@ type DisplayImplementation interface {
 	GetRegistry(obj Display, registry Registry) RegistryImplementation
 }
 
-func AddDisplayGlobal(dsp *wlserver.Display, version int, bind func(res Display) DisplayImplementation) {
-	dsp.AddGlobal(DisplayInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Display)) })
+func AddDisplayGlobal(dsp *wlserver.Display, version int, bind func(res Display) DisplayImplementation) uint32 {
+	return dsp.AddGlobal(DisplayInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Display)) })
 }
 
 // The error event is sent out when a fatal (non-recoverable)
@@ -348,8 +387,8 @@ type RegistryImplementation interface {
 	Bind(obj Registry, name uint32, idName string, idVersion uint32, id wlshared.ObjectID) wlserver.ResourceImplementation
 }
 
-func AddRegistryGlobal(dsp *wlserver.Display, version int, bind func(res Registry) RegistryImplementation) {
-	dsp.AddGlobal(RegistryInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Registry)) })
+func AddRegistryGlobal(dsp *wlserver.Display, version int, bind func(res Registry) RegistryImplementation) uint32 {
+	return dsp.AddGlobal(RegistryInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Registry)) })
 }
 
 // Notify the client of global objects.
@@ -400,13 +439,13 @@ func (Callback) Interface() *wlproto.Interface { return CallbackInterface }
 type CallbackImplementation interface {
 }
 
-func AddCallbackGlobal(dsp *wlserver.Display, version int, bind func(res Callback) CallbackImplementation) {
-	dsp.AddGlobal(CallbackInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Callback)) })
+func AddCallbackGlobal(dsp *wlserver.Display, version int, bind func(res Callback) CallbackImplementation) uint32 {
+	return dsp.AddGlobal(CallbackInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Callback)) })
 }
 
 // Notify the client when the related request is done.
 func (obj Callback) Done(callbackData uint32) {
-	obj.Conn().SendEvent(obj, 0, callbackData)
+	obj.Conn().SendDestructor(obj, 0, callbackData)
 }
 
 var CompositorInterface = &wlproto.Interface{
@@ -448,8 +487,8 @@ type CompositorImplementation interface {
 	CreateRegion(obj Compositor, id Region) RegionImplementation
 }
 
-func AddCompositorGlobal(dsp *wlserver.Display, version int, bind func(res Compositor) CompositorImplementation) {
-	dsp.AddGlobal(CompositorInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Compositor)) })
+func AddCompositorGlobal(dsp *wlserver.Display, version int, bind func(res Compositor) CompositorImplementation) uint32 {
+	return dsp.AddGlobal(CompositorInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Compositor)) })
 }
 
 var ShmPoolInterface = &wlproto.Interface{
@@ -468,7 +507,7 @@ var ShmPoolInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0)), Enum: ShmFormatEnum},
 			},
 		},
 		{
@@ -508,8 +547,8 @@ type ShmPoolImplementation interface {
 	Resize(obj ShmPool, size int32)
 }
 
-func AddShmPoolGlobal(dsp *wlserver.Display, version int, bind func(res ShmPool) ShmPoolImplementation) {
-	dsp.AddGlobal(ShmPoolInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(ShmPool)) })
+func AddShmPoolGlobal(dsp *wlserver.Display, version int, bind func(res ShmPool) ShmPoolImplementation) uint32 {
+	return dsp.AddGlobal(ShmPoolInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(ShmPool)) })
 }
 
 // These errors can be emitted in response to wl_shm requests.
@@ -524,6 +563,17 @@ const (
 	ShmErrorInvalidFd ShmError = 2
 )
 
+var ShmErrorEnum = &wlproto.Enum{
+	Name: "wl_shm.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_format", Value: 0},
+		{Name: "invalid_stride", Value: 1},
+		{Name: "invalid_fd", Value: 2},
+	},
+}
+
+func (v ShmError) String() string { return ShmErrorEnum.Format(uint32(v)) }
+
 // This describes the memory layout of an individual pixel.
 //
 // All renderers should support argb8888 and xrgb8888 but any other
@@ -745,6 +795,122 @@ const (
 	ShmFormatAbgr16161616 ShmFormat = 0x38344241
 )
 
+var ShmFormatEnum = &wlproto.Enum{
+	Name: "wl_shm.format",
+	Entries: []wlproto.EnumEntry{
+		{Name: "argb8888", Value: 0},
+		{Name: "xrgb8888", Value: 1},
+		{Name: "c8", Value: 0x20203843},
+		{Name: "rgb332", Value: 0x38424752},
+		{Name: "bgr233", Value: 0x38524742},
+		{Name: "xrgb4444", Value: 0x32315258},
+		{Name: "xbgr4444", Value: 0x32314258},
+		{Name: "rgbx4444", Value: 0x32315852},
+		{Name: "bgrx4444", Value: 0x32315842},
+		{Name: "argb4444", Value: 0x32315241},
+		{Name: "abgr4444", Value: 0x32314241},
+		{Name: "rgba4444", Value: 0x32314152},
+		{Name: "bgra4444", Value: 0x32314142},
+		{Name: "xrgb1555", Value: 0x35315258},
+		{Name: "xbgr1555", Value: 0x35314258},
+		{Name: "rgbx5551", Value: 0x35315852},
+		{Name: "bgrx5551", Value: 0x35315842},
+		{Name: "argb1555", Value: 0x35315241},
+		{Name: "abgr1555", Value: 0x35314241},
+		{Name: "rgba5551", Value: 0x35314152},
+		{Name: "bgra5551", Value: 0x35314142},
+		{Name: "rgb565", Value: 0x36314752},
+		{Name: "bgr565", Value: 0x36314742},
+		{Name: "rgb888", Value: 0x34324752},
+		{Name: "bgr888", Value: 0x34324742},
+		{Name: "xbgr8888", Value: 0x34324258},
+		{Name: "rgbx8888", Value: 0x34325852},
+		{Name: "bgrx8888", Value: 0x34325842},
+		{Name: "abgr8888", Value: 0x34324241},
+		{Name: "rgba8888", Value: 0x34324152},
+		{Name: "bgra8888", Value: 0x34324142},
+		{Name: "xrgb2101010", Value: 0x30335258},
+		{Name: "xbgr2101010", Value: 0x30334258},
+		{Name: "rgbx1010102", Value: 0x30335852},
+		{Name: "bgrx1010102", Value: 0x30335842},
+		{Name: "argb2101010", Value: 0x30335241},
+		{Name: "abgr2101010", Value: 0x30334241},
+		{Name: "rgba1010102", Value: 0x30334152},
+		{Name: "bgra1010102", Value: 0x30334142},
+		{Name: "yuyv", Value: 0x56595559},
+		{Name: "yvyu", Value: 0x55595659},
+		{Name: "uyvy", Value: 0x59565955},
+		{Name: "vyuy", Value: 0x59555956},
+		{Name: "ayuv", Value: 0x56555941},
+		{Name: "nv12", Value: 0x3231564e},
+		{Name: "nv21", Value: 0x3132564e},
+		{Name: "nv16", Value: 0x3631564e},
+		{Name: "nv61", Value: 0x3136564e},
+		{Name: "yuv410", Value: 0x39565559},
+		{Name: "yvu410", Value: 0x39555659},
+		{Name: "yuv411", Value: 0x31315559},
+		{Name: "yvu411", Value: 0x31315659},
+		{Name: "yuv420", Value: 0x32315559},
+		{Name: "yvu420", Value: 0x32315659},
+		{Name: "yuv422", Value: 0x36315559},
+		{Name: "yvu422", Value: 0x36315659},
+		{Name: "yuv444", Value: 0x34325559},
+		{Name: "yvu444", Value: 0x34325659},
+		{Name: "r8", Value: 0x20203852},
+		{Name: "r16", Value: 0x20363152},
+		{Name: "rg88", Value: 0x38384752},
+		{Name: "gr88", Value: 0x38385247},
+		{Name: "rg1616", Value: 0x32334752},
+		{Name: "gr1616", Value: 0x32335247},
+		{Name: "xrgb16161616f", Value: 0x48345258},
+		{Name: "xbgr16161616f", Value: 0x48344258},
+		{Name: "argb16161616f", Value: 0x48345241},
+		{Name: "abgr16161616f", Value: 0x48344241},
+		{Name: "xyuv8888", Value: 0x56555958},
+		{Name: "vuy888", Value: 0x34325556},
+		{Name: "vuy101010", Value: 0x30335556},
+		{Name: "y210", Value: 0x30313259},
+		{Name: "y212", Value: 0x32313259},
+		{Name: "y216", Value: 0x36313259},
+		{Name: "y410", Value: 0x30313459},
+		{Name: "y412", Value: 0x32313459},
+		{Name: "y416", Value: 0x36313459},
+		{Name: "xvyu2101010", Value: 0x30335658},
+		{Name: "xvyu12_16161616", Value: 0x36335658},
+		{Name: "xvyu16161616", Value: 0x38345658},
+		{Name: "y0l0", Value: 0x304c3059},
+		{Name: "x0l0", Value: 0x304c3058},
+		{Name: "y0l2", Value: 0x324c3059},
+		{Name: "x0l2", Value: 0x324c3058},
+		{Name: "yuv420_8bit", Value: 0x38305559},
+		{Name: "yuv420_10bit", Value: 0x30315559},
+		{Name: "xrgb8888_a8", Value: 0x38415258},
+		{Name: "xbgr8888_a8", Value: 0x38414258},
+		{Name: "rgbx8888_a8", Value: 0x38415852},
+		{Name: "bgrx8888_a8", Value: 0x38415842},
+		{Name: "rgb888_a8", Value: 0x38413852},
+		{Name: "bgr888_a8", Value: 0x38413842},
+		{Name: "rgb565_a8", Value: 0x38413552},
+		{Name: "bgr565_a8", Value: 0x38413542},
+		{Name: "nv24", Value: 0x3432564e},
+		{Name: "nv42", Value: 0x3234564e},
+		{Name: "p210", Value: 0x30313250},
+		{Name: "p010", Value: 0x30313050},
+		{Name: "p012", Value: 0x32313050},
+		{Name: "p016", Value: 0x36313050},
+		{Name: "axbxgxrx106106106106", Value: 0x30314241},
+		{Name: "nv15", Value: 0x3531564e},
+		{Name: "q410", Value: 0x30313451},
+		{Name: "q401", Value: 0x31303451},
+		{Name: "xrgb16161616", Value: 0x38345258},
+		{Name: "xbgr16161616", Value: 0x38344258},
+		{Name: "argb16161616", Value: 0x38345241},
+		{Name: "abgr16161616", Value: 0x38344241},
+	},
+}
+
+func (v ShmFormat) String() string { return ShmFormatEnum.Format(uint32(v)) }
+
 var ShmInterface = &wlproto.Interface{
 	Name:    "wl_shm",
 	Version: 1,
@@ -767,7 +933,7 @@ var ShmInterface = &wlproto.Interface{
 			Name:  "format",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShmFormat(0)), Enum: ShmFormatEnum},
 			},
 		},
 	},
@@ -790,8 +956,8 @@ type ShmImplementation interface {
 	CreatePool(obj Shm, id ShmPool, fd uintptr, size int32) ShmPoolImplementation
 }
 
-func AddShmGlobal(dsp *wlserver.Display, version int, bind func(res Shm) ShmImplementation) {
-	dsp.AddGlobal(ShmInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Shm)) })
+func AddShmGlobal(dsp *wlserver.Display, version int, bind func(res Shm) ShmImplementation) uint32 {
+	return dsp.AddGlobal(ShmInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Shm)) })
 }
 
 // Informs the client about a valid pixel format that
@@ -841,8 +1007,8 @@ type BufferImplementation interface {
 	Destroy(obj Buffer)
 }
 
-func AddBufferGlobal(dsp *wlserver.Display, version int, bind func(res Buffer) BufferImplementation) {
-	dsp.AddGlobal(BufferInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Buffer)) })
+func AddBufferGlobal(dsp *wlserver.Display, version int, bind func(res Buffer) BufferImplementation) uint32 {
+	return dsp.AddGlobal(BufferInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Buffer)) })
 }
 
 // Sent when this wl_buffer is no longer used by the compositor.
@@ -874,6 +1040,18 @@ const (
 	DataOfferErrorInvalidOffer DataOfferError = 3
 )
 
+var DataOfferErrorEnum = &wlproto.Enum{
+	Name: "wl_data_offer.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_finish", Value: 0},
+		{Name: "invalid_action_mask", Value: 1},
+		{Name: "invalid_action", Value: 2},
+		{Name: "invalid_offer", Value: 3},
+	},
+}
+
+func (v DataOfferError) String() string { return DataOfferErrorEnum.Format(uint32(v)) }
+
 var DataOfferInterface = &wlproto.Interface{
 	Name:    "wl_data_offer",
 	Version: 3,
@@ -886,7 +1064,7 @@ var DataOfferInterface = &wlproto.Interface{
 			Method: reflect.ValueOf(DataOfferImplementation.Accept),
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeString},
+				{Type: wlproto.ArgTypeString, AllowNull: true},
 			},
 		},
 		{
@@ -919,8 +1097,8 @@ var DataOfferInterface = &wlproto.Interface{
 			Since:  3,
 			Method: reflect.ValueOf(DataOfferImplementation.SetActions),
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -936,14 +1114,14 @@ var DataOfferInterface = &wlproto.Interface{
 			Name:  "source_actions",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 		{
 			Name:  "action",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -967,8 +1145,8 @@ type DataOfferImplementation interface {
 	SetActions(obj DataOffer, dndActions DataDeviceManagerDndAction, preferredAction DataDeviceManagerDndAction)
 }
 
-func AddDataOfferGlobal(dsp *wlserver.Display, version int, bind func(res DataOffer) DataOfferImplementation) {
-	dsp.AddGlobal(DataOfferInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataOffer)) })
+func AddDataOfferGlobal(dsp *wlserver.Display, version int, bind func(res DataOffer) DataOfferImplementation) uint32 {
+	return dsp.AddGlobal(DataOfferInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataOffer)) })
 }
 
 // Sent immediately after creating the wl_data_offer object.  One
@@ -1032,6 +1210,16 @@ const (
 	DataSourceErrorInvalidSource DataSourceError = 1
 )
 
+var DataSourceErrorEnum = &wlproto.Enum{
+	Name: "wl_data_source.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_action_mask", Value: 0},
+		{Name: "invalid_source", Value: 1},
+	},
+}
+
+func (v DataSourceError) String() string { return DataSourceErrorEnum.Format(uint32(v)) }
+
 var DataSourceInterface = &wlproto.Interface{
 	Name:    "wl_data_source",
 	Version: 3,
@@ -1059,7 +1247,7 @@ var DataSourceInterface = &wlproto.Interface{
 			Since:  3,
 			Method: reflect.ValueOf(DataSourceImplementation.SetActions),
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -1068,7 +1256,7 @@ var DataSourceInterface = &wlproto.Interface{
 			Name:  "target",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeString},
+				{Type: wlproto.ArgTypeString, AllowNull: true},
 			},
 		},
 		{
@@ -1098,7 +1286,7 @@ var DataSourceInterface = &wlproto.Interface{
 			Name:  "action",
 			Since: 3,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(DataDeviceManagerDndAction(0)), Enum: DataDeviceManagerDndActionEnum},
 			},
 		},
 	},
@@ -1118,8 +1306,8 @@ type DataSourceImplementation interface {
 	SetActions(obj DataSource, dndActions DataDeviceManagerDndAction)
 }
 
-func AddDataSourceGlobal(dsp *wlserver.Display, version int, bind func(res DataSource) DataSourceImplementation) {
-	dsp.AddGlobal(DataSourceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataSource)) })
+func AddDataSourceGlobal(dsp *wlserver.Display, version int, bind func(res DataSource) DataSourceImplementation) uint32 {
+	return dsp.AddGlobal(DataSourceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataSource)) })
 }
 
 // Sent when a target accepts pointer_focus or motion events.  If
@@ -1220,6 +1408,15 @@ const (
 	DataDeviceErrorRole DataDeviceError = 0
 )
 
+var DataDeviceErrorEnum = &wlproto.Enum{
+	Name: "wl_data_device.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+	},
+}
+
+func (v DataDeviceError) String() string { return DataDeviceErrorEnum.Format(uint32(v)) }
+
 var DataDeviceInterface = &wlproto.Interface{
 	Name:    "wl_data_device",
 	Version: 3,
@@ -1318,8 +1515,8 @@ type DataDeviceImplementation interface {
 	Release(obj DataDevice)
 }
 
-func AddDataDeviceGlobal(dsp *wlserver.Display, version int, bind func(res DataDevice) DataDeviceImplementation) {
-	dsp.AddGlobal(DataDeviceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataDevice)) })
+func AddDataDeviceGlobal(dsp *wlserver.Display, version int, bind func(res DataDevice) DataDeviceImplementation) uint32 {
+	return dsp.AddGlobal(DataDeviceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataDevice)) })
 }
 
 // The data_offer event introduces a new wl_data_offer object,
@@ -1425,6 +1622,21 @@ const (
 	DataDeviceManagerDndActionAsk DataDeviceManagerDndAction = 4
 )
 
+var DataDeviceManagerDndActionEnum = &wlproto.Enum{
+	Name:     "wl_data_device_manager.dnd_action",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "copy", Value: 1},
+		{Name: "move", Value: 2},
+		{Name: "ask", Value: 4},
+	},
+}
+
+func (v DataDeviceManagerDndAction) String() string {
+	return DataDeviceManagerDndActionEnum.Format(uint32(v))
+}
+
 var DataDeviceManagerInterface = &wlproto.Interface{
 	Name:    "wl_data_device_manager",
 	Version: 3,
@@ -1472,8 +1684,8 @@ type DataDeviceManagerImplementation interface {
 	GetDataDevice(obj DataDeviceManager, id DataDevice, seat Seat) DataDeviceImplementation
 }
 
-func AddDataDeviceManagerGlobal(dsp *wlserver.Display, version int, bind func(res DataDeviceManager) DataDeviceManagerImplementation) {
-	dsp.AddGlobal(DataDeviceManagerInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataDeviceManager)) })
+func AddDataDeviceManagerGlobal(dsp *wlserver.Display, version int, bind func(res DataDeviceManager) DataDeviceManagerImplementation) uint32 {
+	return dsp.AddGlobal(DataDeviceManagerInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(DataDeviceManager)) })
 }
 
 type ShellError uint32
@@ -1483,6 +1695,15 @@ const (
 	ShellErrorRole ShellError = 0
 )
 
+var ShellErrorEnum = &wlproto.Enum{
+	Name: "wl_shell.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+	},
+}
+
+func (v ShellError) String() string { return ShellErrorEnum.Format(uint32(v)) }
+
 var ShellInterface = &wlproto.Interface{
 	Name:    "wl_shell",
 	Version: 1,
@@ -1518,8 +1739,8 @@ type ShellImplementation interface {
 	GetShellSurface(obj Shell, id ShellSurface, surface Surface) ShellSurfaceImplementation
 }
 
-func AddShellGlobal(dsp *wlserver.Display, version int, bind func(res Shell) ShellImplementation) {
-	dsp.AddGlobal(ShellInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Shell)) })
+func AddShellGlobal(dsp *wlserver.Display, version int, bind func(res Shell) ShellImplementation) uint32 {
+	return dsp.AddGlobal(ShellInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Shell)) })
 }
 
 // These values are used to indicate which edge of a surface
@@ -1549,6 +1770,24 @@ const (
 	ShellSurfaceResizeBottomRight ShellSurfaceResize = 10
 )
 
+var ShellSurfaceResizeEnum = &wlproto.Enum{
+	Name:     "wl_shell_surface.resize",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "right", Value: 8},
+		{Name: "top_right", Value: 9},
+		{Name: "bottom_right", Value: 10},
+	},
+}
+
+func (v ShellSurfaceResize) String() string { return ShellSurfaceResizeEnum.Format(uint32(v)) }
+
 // These flags specify details of the expected behaviour
 // of transient surfaces. Used in the set_transient request.
 type ShellSurfaceTransient uint32
@@ -1558,6 +1797,16 @@ const (
 	ShellSurfaceTransientInactive ShellSurfaceTransient = 0x1
 )
 
+var ShellSurfaceTransientEnum = &wlproto.Enum{
+	Name:     "wl_shell_surface.transient",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "inactive", Value: 0x1},
+	},
+}
+
+func (v ShellSurfaceTransient) String() string { return ShellSurfaceTransientEnum.Format(uint32(v)) }
+
 // Hints to indicate to the compositor how to deal with a conflict
 // between the dimensions of the surface and the dimensions of the
 // output. The compositor is free to ignore this parameter.
@@ -1574,6 +1823,20 @@ const (
 	ShellSurfaceFullscreenMethodFill ShellSurfaceFullscreenMethod = 3
 )
 
+var ShellSurfaceFullscreenMethodEnum = &wlproto.Enum{
+	Name: "wl_shell_surface.fullscreen_method",
+	Entries: []wlproto.EnumEntry{
+		{Name: "default", Value: 0},
+		{Name: "scale", Value: 1},
+		{Name: "driver", Value: 2},
+		{Name: "fill", Value: 3},
+	},
+}
+
+func (v ShellSurfaceFullscreenMethod) String() string {
+	return ShellSurfaceFullscreenMethodEnum.Format(uint32(v))
+}
+
 var ShellSurfaceInterface = &wlproto.Interface{
 	Name:    "wl_shell_surface",
 	Version: 1,
@@ -1606,7 +1869,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf(Seat{})},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0)), Enum: ShellSurfaceResizeEnum},
 			},
 		},
 		{
@@ -1625,7 +1888,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf(Surface{})},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0)), Enum: ShellSurfaceTransientEnum},
 			},
 		},
 		{
@@ -1634,7 +1897,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 			Since:  1,
 			Method: reflect.ValueOf(ShellSurfaceImplementation.SetFullscreen),
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceFullscreenMethod(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceFullscreenMethod(0)), Enum: ShellSurfaceFullscreenMethodEnum},
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf(Output{})},
 			},
@@ -1650,7 +1913,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf(Surface{})},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceTransient(0)), Enum: ShellSurfaceTransientEnum},
 			},
 		},
 		{
@@ -1693,7 +1956,7 @@ var ShellSurfaceInterface = &wlproto.Interface{
 			Name:  "configure",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ShellSurfaceResize(0)), Enum: ShellSurfaceResizeEnum},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 			},
@@ -1734,8 +1997,8 @@ type ShellSurfaceImplementation interface {
 	SetClass(obj ShellSurface, class string)
 }
 
-func AddShellSurfaceGlobal(dsp *wlserver.Display, version int, bind func(res ShellSurface) ShellSurfaceImplementation) {
-	dsp.AddGlobal(ShellSurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(ShellSurface)) })
+func AddShellSurfaceGlobal(dsp *wlserver.Display, version int, bind func(res ShellSurface) ShellSurfaceImplementation) uint32 {
+	return dsp.AddGlobal(ShellSurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(ShellSurface)) })
 }
 
 // Ping a client to check if it is receiving events and sending
@@ -1786,6 +2049,18 @@ const (
 	SurfaceErrorInvalidOffset SurfaceError = 3
 )
 
+var SurfaceErrorEnum = &wlproto.Enum{
+	Name: "wl_surface.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_scale", Value: 0},
+		{Name: "invalid_transform", Value: 1},
+		{Name: "invalid_size", Value: 2},
+		{Name: "invalid_offset", Value: 3},
+	},
+}
+
+func (v SurfaceError) String() string { return SurfaceErrorEnum.Format(uint32(v)) }
+
 var SurfaceInterface = &wlproto.Interface{
 	Name:    "wl_surface",
 	Version: 5,
@@ -1861,7 +2136,7 @@ var SurfaceInterface = &wlproto.Interface{
 			Since:  2,
 			Method: reflect.ValueOf(SurfaceImplementation.SetBufferTransform),
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0))},
+				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0)), Enum: OutputTransformEnum},
 			},
 		},
 		{
@@ -1973,8 +2248,8 @@ type SurfaceImplementation interface {
 	Offset(obj Surface, x int32, y int32)
 }
 
-func AddSurfaceGlobal(dsp *wlserver.Display, version int, bind func(res Surface) SurfaceImplementation) {
-	dsp.AddGlobal(SurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Surface)) })
+func AddSurfaceGlobal(dsp *wlserver.Display, version int, bind func(res Surface) SurfaceImplementation) uint32 {
+	return dsp.AddGlobal(SurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Surface)) })
 }
 
 // This is emitted whenever a surface's creation, movement, or resizing
@@ -2012,6 +2287,18 @@ const (
 	SeatCapabilityTouch SeatCapability = 4
 )
 
+var SeatCapabilityEnum = &wlproto.Enum{
+	Name:     "wl_seat.capability",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "pointer", Value: 1},
+		{Name: "keyboard", Value: 2},
+		{Name: "touch", Value: 4},
+	},
+}
+
+func (v SeatCapability) String() string { return SeatCapabilityEnum.Format(uint32(v)) }
+
 // These errors can be emitted in response to wl_seat requests.
 type SeatError uint32
 
@@ -2020,6 +2307,15 @@ const (
 	SeatErrorMissingCapability SeatError = 0
 )
 
+var SeatErrorEnum = &wlproto.Enum{
+	Name: "wl_seat.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "missing_capability", Value: 0},
+	},
+}
+
+func (v SeatError) String() string { return SeatErrorEnum.Format(uint32(v)) }
+
 var SeatInterface = &wlproto.Interface{
 	Name:    "wl_seat",
 	Version: 7,
@@ -2065,7 +2361,7 @@ var SeatInterface = &wlproto.Interface{
 			Name:  "capabilities",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(SeatCapability(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(SeatCapability(0)), Enum: SeatCapabilityEnum},
 			},
 		},
 		{
@@ -2093,8 +2389,8 @@ type SeatImplementation interface {
 	Release(obj Seat)
 }
 
-func AddSeatGlobal(dsp *wlserver.Display, version int, bind func(res Seat) SeatImplementation) {
-	dsp.AddGlobal(SeatInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Seat)) })
+func AddSeatGlobal(dsp *wlserver.Display, version int, bind func(res Seat) SeatImplementation) uint32 {
+	return dsp.AddGlobal(SeatInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Seat)) })
 }
 
 // This is emitted whenever a seat gains or loses the pointer,
@@ -2152,6 +2448,15 @@ const (
 	PointerErrorRole PointerError = 0
 )
 
+var PointerErrorEnum = &wlproto.Enum{
+	Name: "wl_pointer.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+	},
+}
+
+func (v PointerError) String() string { return PointerErrorEnum.Format(uint32(v)) }
+
 // Describes the physical state of a button that produced the button
 // event.
 type PointerButtonState uint32
@@ -2163,6 +2468,16 @@ const (
 	PointerButtonStatePressed PointerButtonState = 1
 )
 
+var PointerButtonStateEnum = &wlproto.Enum{
+	Name: "wl_pointer.button_state",
+	Entries: []wlproto.EnumEntry{
+		{Name: "released", Value: 0},
+		{Name: "pressed", Value: 1},
+	},
+}
+
+func (v PointerButtonState) String() string { return PointerButtonStateEnum.Format(uint32(v)) }
+
 // Describes the axis types of scroll events.
 type PointerAxis uint32
 
@@ -2173,6 +2488,16 @@ const (
 	PointerAxisHorizontalScroll PointerAxis = 1
 )
 
+var PointerAxisEnum = &wlproto.Enum{
+	Name: "wl_pointer.axis",
+	Entries: []wlproto.EnumEntry{
+		{Name: "vertical_scroll", Value: 0},
+		{Name: "horizontal_scroll", Value: 1},
+	},
+}
+
+func (v PointerAxis) String() string { return PointerAxisEnum.Format(uint32(v)) }
+
 // Describes the source types for axis events. This indicates to the
 // client how an axis event was physically generated; a client may
 // adjust the user interface accordingly. For example, scroll events
@@ -2202,6 +2527,18 @@ const (
 	PointerAxisSourceWheelTilt PointerAxisSource = 3
 )
 
+var PointerAxisSourceEnum = &wlproto.Enum{
+	Name: "wl_pointer.axis_source",
+	Entries: []wlproto.EnumEntry{
+		{Name: "wheel", Value: 0},
+		{Name: "finger", Value: 1},
+		{Name: "continuous", Value: 2},
+		{Name: "wheel_tilt", Value: 3},
+	},
+}
+
+func (v PointerAxisSource) String() string { return PointerAxisSourceEnum.Format(uint32(v)) }
+
 var PointerInterface = &wlproto.Interface{
 	Name:    "wl_pointer",
 	Version: 7,
@@ -2262,7 +2599,7 @@ var PointerInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerButtonState(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerButtonState(0)), Enum: PointerButtonStateEnum},
 			},
 		},
 		{
@@ -2270,7 +2607,7 @@ var PointerInterface = &wlproto.Interface{
 			Since: 1,
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0)), Enum: PointerAxisEnum},
 				{Type: wlproto.ArgTypeFixed},
 			},
 		},
@@ -2283,7 +2620,7 @@ var PointerInterface = &wlproto.Interface{
 			Name:  "axis_source",
 			Since: 5,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxisSource(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxisSource(0)), Enum: PointerAxisSourceEnum},
 			},
 		},
 		{
@@ -2291,14 +2628,14 @@ var PointerInterface = &wlproto.Interface{
 			Since: 5,
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0)), Enum: PointerAxisEnum},
 			},
 		},
 		{
 			Name:  "axis_discrete",
 			Since: 5,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PointerAxis(0)), Enum: PointerAxisEnum},
 				{Type: wlproto.ArgTypeInt},
 			},
 		},
@@ -2322,8 +2659,8 @@ type PointerImplementation interface {
 	Release(obj Pointer)
 }
 
-func AddPointerGlobal(dsp *wlserver.Display, version int, bind func(res Pointer) PointerImplementation) {
-	dsp.AddGlobal(PointerInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Pointer)) })
+func AddPointerGlobal(dsp *wlserver.Display, version int, bind func(res Pointer) PointerImplementation) uint32 {
+	return dsp.AddGlobal(PointerInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Pointer)) })
 }
 
 // Notification that this seat's pointer is focused on a certain
@@ -2516,6 +2853,16 @@ const (
 	KeyboardKeymapFormatXkbV1 KeyboardKeymapFormat = 1
 )
 
+var KeyboardKeymapFormatEnum = &wlproto.Enum{
+	Name: "wl_keyboard.keymap_format",
+	Entries: []wlproto.EnumEntry{
+		{Name: "no_keymap", Value: 0},
+		{Name: "xkb_v1", Value: 1},
+	},
+}
+
+func (v KeyboardKeymapFormat) String() string { return KeyboardKeymapFormatEnum.Format(uint32(v)) }
+
 // Describes the physical state of a key that produced the key event.
 type KeyboardKeyState uint32
 
@@ -2526,6 +2873,16 @@ const (
 	KeyboardKeyStatePressed KeyboardKeyState = 1
 )
 
+var KeyboardKeyStateEnum = &wlproto.Enum{
+	Name: "wl_keyboard.key_state",
+	Entries: []wlproto.EnumEntry{
+		{Name: "released", Value: 0},
+		{Name: "pressed", Value: 1},
+	},
+}
+
+func (v KeyboardKeyState) String() string { return KeyboardKeyStateEnum.Format(uint32(v)) }
+
 var KeyboardInterface = &wlproto.Interface{
 	Name:    "wl_keyboard",
 	Version: 7,
@@ -2544,7 +2901,7 @@ var KeyboardInterface = &wlproto.Interface{
 			Name:  "keymap",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeymapFormat(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeymapFormat(0)), Enum: KeyboardKeymapFormatEnum},
 				{Type: wlproto.ArgTypeFd},
 				{Type: wlproto.ArgTypeUint},
 			},
@@ -2573,7 +2930,7 @@ var KeyboardInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeyState(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(KeyboardKeyState(0)), Enum: KeyboardKeyStateEnum},
 			},
 		},
 		{
@@ -2608,8 +2965,8 @@ type KeyboardImplementation interface {
 	Release(obj Keyboard)
 }
 
-func AddKeyboardGlobal(dsp *wlserver.Display, version int, bind func(res Keyboard) KeyboardImplementation) {
-	dsp.AddGlobal(KeyboardInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Keyboard)) })
+func AddKeyboardGlobal(dsp *wlserver.Display, version int, bind func(res Keyboard) KeyboardImplementation) uint32 {
+	return dsp.AddGlobal(KeyboardInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Keyboard)) })
 }
 
 // This event provides a file descriptor to the client which can be
@@ -2769,8 +3126,8 @@ type TouchImplementation interface {
 	Release(obj Touch)
 }
 
-func AddTouchGlobal(dsp *wlserver.Display, version int, bind func(res Touch) TouchImplementation) {
-	dsp.AddGlobal(TouchInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Touch)) })
+func AddTouchGlobal(dsp *wlserver.Display, version int, bind func(res Touch) TouchImplementation) uint32 {
+	return dsp.AddGlobal(TouchInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Touch)) })
 }
 
 // A new touch point has appeared on the surface. This touch point is
@@ -2890,6 +3247,20 @@ const (
 	OutputSubpixelVerticalBgr OutputSubpixel = 5
 )
 
+var OutputSubpixelEnum = &wlproto.Enum{
+	Name: "wl_output.subpixel",
+	Entries: []wlproto.EnumEntry{
+		{Name: "unknown", Value: 0},
+		{Name: "none", Value: 1},
+		{Name: "horizontal_rgb", Value: 2},
+		{Name: "horizontal_bgr", Value: 3},
+		{Name: "vertical_rgb", Value: 4},
+		{Name: "vertical_bgr", Value: 5},
+	},
+}
+
+func (v OutputSubpixel) String() string { return OutputSubpixelEnum.Format(uint32(v)) }
+
 // This describes the transform that a compositor will apply to a
 // surface to compensate for the rotation or mirroring of an
 // output device.
@@ -2922,6 +3293,22 @@ const (
 	OutputTransformFlipped270 OutputTransform = 7
 )
 
+var OutputTransformEnum = &wlproto.Enum{
+	Name: "wl_output.transform",
+	Entries: []wlproto.EnumEntry{
+		{Name: "normal", Value: 0},
+		{Name: "90", Value: 1},
+		{Name: "180", Value: 2},
+		{Name: "270", Value: 3},
+		{Name: "flipped", Value: 4},
+		{Name: "flipped_90", Value: 5},
+		{Name: "flipped_180", Value: 6},
+		{Name: "flipped_270", Value: 7},
+	},
+}
+
+func (v OutputTransform) String() string { return OutputTransformEnum.Format(uint32(v)) }
+
 // These flags describe properties of an output mode.
 // They are used in the flags bitfield of the mode event.
 type OutputMode uint32
@@ -2933,6 +3320,17 @@ const (
 	OutputModePreferred OutputMode = 0x2
 )
 
+var OutputModeEnum = &wlproto.Enum{
+	Name:     "wl_output.mode",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "current", Value: 0x1},
+		{Name: "preferred", Value: 0x2},
+	},
+}
+
+func (v OutputMode) String() string { return OutputModeEnum.Format(uint32(v)) }
+
 var OutputInterface = &wlproto.Interface{
 	Name:    "wl_output",
 	Version: 4,
@@ -2955,17 +3353,17 @@ var OutputInterface = &wlproto.Interface{
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
-				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputSubpixel(0))},
+				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputSubpixel(0)), Enum: OutputSubpixelEnum},
 				{Type: wlproto.ArgTypeString},
 				{Type: wlproto.ArgTypeString},
-				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0))},
+				{Type: wlproto.ArgTypeInt, Aux: reflect.TypeOf(OutputTransform(0)), Enum: OutputTransformEnum},
 			},
 		},
 		{
 			Name:  "mode",
 			Since: 1,
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(OutputMode(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(OutputMode(0)), Enum: OutputModeEnum},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
 				{Type: wlproto.ArgTypeInt},
@@ -3014,8 +3412,8 @@ type OutputImplementation interface {
 	Release(obj Output)
 }
 
-func AddOutputGlobal(dsp *wlserver.Display, version int, bind func(res Output) OutputImplementation) {
-	dsp.AddGlobal(OutputInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Output)) })
+func AddOutputGlobal(dsp *wlserver.Display, version int, bind func(res Output) OutputImplementation) uint32 {
+	return dsp.AddGlobal(OutputInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Output)) })
 }
 
 // The geometry event describes geometric properties of the output.
@@ -3212,8 +3610,8 @@ type RegionImplementation interface {
 	Subtract(obj Region, x int32, y int32, width int32, height int32)
 }
 
-func AddRegionGlobal(dsp *wlserver.Display, version int, bind func(res Region) RegionImplementation) {
-	dsp.AddGlobal(RegionInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Region)) })
+func AddRegionGlobal(dsp *wlserver.Display, version int, bind func(res Region) RegionImplementation) uint32 {
+	return dsp.AddGlobal(RegionInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Region)) })
 }
 
 type SubcompositorError uint32
@@ -3223,6 +3621,15 @@ const (
 	SubcompositorErrorBadSurface SubcompositorError = 0
 )
 
+var SubcompositorErrorEnum = &wlproto.Enum{
+	Name: "wl_subcompositor.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "bad_surface", Value: 0},
+	},
+}
+
+func (v SubcompositorError) String() string { return SubcompositorErrorEnum.Format(uint32(v)) }
+
 var SubcompositorInterface = &wlproto.Interface{
 	Name:    "wl_subcompositor",
 	Version: 1,
@@ -3278,8 +3685,8 @@ type SubcompositorImplementation interface {
 	GetSubsurface(obj Subcompositor, id Subsurface, surface Surface, parent Surface) SubsurfaceImplementation
 }
 
-func AddSubcompositorGlobal(dsp *wlserver.Display, version int, bind func(res Subcompositor) SubcompositorImplementation) {
-	dsp.AddGlobal(SubcompositorInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Subcompositor)) })
+func AddSubcompositorGlobal(dsp *wlserver.Display, version int, bind func(res Subcompositor) SubcompositorImplementation) uint32 {
+	return dsp.AddGlobal(SubcompositorInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Subcompositor)) })
 }
 
 type SubsurfaceError uint32
@@ -3289,6 +3696,15 @@ const (
 	SubsurfaceErrorBadSurface SubsurfaceError = 0
 )
 
+var SubsurfaceErrorEnum = &wlproto.Enum{
+	Name: "wl_subsurface.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "bad_surface", Value: 0},
+	},
+}
+
+func (v SubsurfaceError) String() string { return SubsurfaceErrorEnum.Format(uint32(v)) }
+
 var SubsurfaceInterface = &wlproto.Interface{
 	Name:    "wl_subsurface",
 	Version: 1,
@@ -3409,6 +3825,6 @@ type SubsurfaceImplementation interface {
 	SetDesync(obj Subsurface)
 }
 
-func AddSubsurfaceGlobal(dsp *wlserver.Display, version int, bind func(res Subsurface) SubsurfaceImplementation) {
-	dsp.AddGlobal(SubsurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Subsurface)) })
+func AddSubsurfaceGlobal(dsp *wlserver.Display, version int, bind func(res Subsurface) SubsurfaceImplementation) uint32 {
+	return dsp.AddGlobal(SubsurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Subsurface)) })
 }
@@ -4,9 +4,9 @@
 package xdgShell
 
 import (
-	"honnef.co/go/wayland/wlclient/protocols/wayland"
 	"honnef.co/go/wayland/wlproto"
 	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
 	"honnef.co/go/wayland/wlshared"
 	"reflect"
 )
@@ -79,6 +79,16 @@ var Events = map[string]*wlproto.Event{
 	"xdg_popup_repositioned":        &PopupInterface.Events[2],
 }
 
+func init() {
+	wlproto.ServerRegistry.Register(
+		WmBaseInterface,
+		PositionerInterface,
+		SurfaceInterface,
+		ToplevelInterface,
+		PopupInterface,
+	)
+}
+
 type WmBaseError uint32
 
 const (
@@ -96,6 +106,20 @@ const (
 	WmBaseErrorInvalidPositioner WmBaseError = 5
 )
 
+var WmBaseErrorEnum = &wlproto.Enum{
+	Name: "xdg_wm_base.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "role", Value: 0},
+		{Name: "defunct_surfaces", Value: 1},
+		{Name: "not_the_topmost_popup", Value: 2},
+		{Name: "invalid_popup_parent", Value: 3},
+		{Name: "invalid_surface_state", Value: 4},
+		{Name: "invalid_positioner", Value: 5},
+	},
+}
+
+func (v WmBaseError) String() string { return WmBaseErrorEnum.Format(uint32(v)) }
+
 var WmBaseInterface = &wlproto.Interface{
 	Name:    "xdg_wm_base",
 	Version: 4,
@@ -164,8 +188,8 @@ type WmBaseImplementation interface {
 	Pong(obj WmBase, serial uint32)
 }
 
-func AddWmBaseGlobal(dsp *wlserver.Display, version int, bind func(res WmBase) WmBaseImplementation) {
-	dsp.AddGlobal(WmBaseInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(WmBase)) })
+func AddWmBaseGlobal(dsp *wlserver.Display, version int, bind func(res WmBase) WmBaseImplementation) uint32 {
+	return dsp.AddGlobal(WmBaseInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(WmBase)) })
 }
 
 // The ping event asks the client if it's still alive. Pass the
@@ -190,6 +214,15 @@ const (
 	PositionerErrorInvalidInput PositionerError = 0
 )
 
+var PositionerErrorEnum = &wlproto.Enum{
+	Name: "xdg_positioner.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_input", Value: 0},
+	},
+}
+
+func (v PositionerError) String() string { return PositionerErrorEnum.Format(uint32(v)) }
+
 type PositionerAnchor uint32
 
 const (
@@ -204,6 +237,23 @@ const (
 	PositionerAnchorBottomRight PositionerAnchor = 8
 )
 
+var PositionerAnchorEnum = &wlproto.Enum{
+	Name: "xdg_positioner.anchor",
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 3},
+		{Name: "right", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "top_right", Value: 7},
+		{Name: "bottom_right", Value: 8},
+	},
+}
+
+func (v PositionerAnchor) String() string { return PositionerAnchorEnum.Format(uint32(v)) }
+
 type PositionerGravity uint32
 
 const (
@@ -218,6 +268,23 @@ const (
 	PositionerGravityBottomRight PositionerGravity = 8
 )
 
+var PositionerGravityEnum = &wlproto.Enum{
+	Name: "xdg_positioner.gravity",
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 3},
+		{Name: "right", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "top_right", Value: 7},
+		{Name: "bottom_right", Value: 8},
+	},
+}
+
+func (v PositionerGravity) String() string { return PositionerGravityEnum.Format(uint32(v)) }
+
 // The constraint adjustment value define ways the compositor will adjust
 // the position of the surface, if the unadjusted position would result
 // in the surface being partly constrained.
@@ -288,6 +355,24 @@ const (
 	PositionerConstraintAdjustmentResizeY PositionerConstraintAdjustment = 32
 )
 
+var PositionerConstraintAdjustmentEnum = &wlproto.Enum{
+	Name:     "xdg_positioner.constraint_adjustment",
+	Bitfield: true,
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "slide_x", Value: 1},
+		{Name: "slide_y", Value: 2},
+		{Name: "flip_x", Value: 4},
+		{Name: "flip_y", Value: 8},
+		{Name: "resize_x", Value: 16},
+		{Name: "resize_y", Value: 32},
+	},
+}
+
+func (v PositionerConstraintAdjustment) String() string {
+	return PositionerConstraintAdjustmentEnum.Format(uint32(v))
+}
+
 var PositionerInterface = &wlproto.Interface{
 	Name:    "xdg_positioner",
 	Version: 4,
@@ -328,7 +413,7 @@ var PositionerInterface = &wlproto.Interface{
 			Since:  1,
 			Method: reflect.ValueOf(PositionerImplementation.SetAnchor),
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerAnchor(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerAnchor(0)), Enum: PositionerAnchorEnum},
 			},
 		},
 		{
@@ -337,7 +422,7 @@ var PositionerInterface = &wlproto.Interface{
 			Since:  1,
 			Method: reflect.ValueOf(PositionerImplementation.SetGravity),
 			Args: []wlproto.Arg{
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerGravity(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(PositionerGravity(0)), Enum: PositionerGravityEnum},
 			},
 		},
 		{
@@ -425,8 +510,8 @@ type PositionerImplementation interface {
 	SetParentConfigure(obj Positioner, serial uint32)
 }
 
-func AddPositionerGlobal(dsp *wlserver.Display, version int, bind func(res Positioner) PositionerImplementation) {
-	dsp.AddGlobal(PositionerInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Positioner)) })
+func AddPositionerGlobal(dsp *wlserver.Display, version int, bind func(res Positioner) PositionerImplementation) uint32 {
+	return dsp.AddGlobal(PositionerInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Positioner)) })
 }
 
 type SurfaceError uint32
@@ -437,6 +522,17 @@ const (
 	SurfaceErrorUnconfiguredBuffer SurfaceError = 3
 )
 
+var SurfaceErrorEnum = &wlproto.Enum{
+	Name: "xdg_surface.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "not_constructed", Value: 1},
+		{Name: "already_constructed", Value: 2},
+		{Name: "unconfigured_buffer", Value: 3},
+	},
+}
+
+func (v SurfaceError) String() string { return SurfaceErrorEnum.Format(uint32(v)) }
+
 var SurfaceInterface = &wlproto.Interface{
 	Name:    "xdg_surface",
 	Version: 4,
@@ -559,8 +655,8 @@ type SurfaceImplementation interface {
 	AckConfigure(obj Surface, serial uint32)
 }
 
-func AddSurfaceGlobal(dsp *wlserver.Display, version int, bind func(res Surface) SurfaceImplementation) {
-	dsp.AddGlobal(SurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Surface)) })
+func AddSurfaceGlobal(dsp *wlserver.Display, version int, bind func(res Surface) SurfaceImplementation) uint32 {
+	return dsp.AddGlobal(SurfaceInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Surface)) })
 }
 
 // The configure event marks the end of a configure sequence. A configure
@@ -590,6 +686,15 @@ const (
 	ToplevelErrorInvalidResizeEdge ToplevelError = 0
 )
 
+var ToplevelErrorEnum = &wlproto.Enum{
+	Name: "xdg_toplevel.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_resize_edge", Value: 0},
+	},
+}
+
+func (v ToplevelError) String() string { return ToplevelErrorEnum.Format(uint32(v)) }
+
 // These values are used to indicate which edge of a surface
 // is being dragged in a resize operation.
 type ToplevelResizeEdge uint32
@@ -606,6 +711,23 @@ const (
 	ToplevelResizeEdgeBottomRight ToplevelResizeEdge = 10
 )
 
+var ToplevelResizeEdgeEnum = &wlproto.Enum{
+	Name: "xdg_toplevel.resize_edge",
+	Entries: []wlproto.EnumEntry{
+		{Name: "none", Value: 0},
+		{Name: "top", Value: 1},
+		{Name: "bottom", Value: 2},
+		{Name: "left", Value: 4},
+		{Name: "top_left", Value: 5},
+		{Name: "bottom_left", Value: 6},
+		{Name: "right", Value: 8},
+		{Name: "top_right", Value: 9},
+		{Name: "bottom_right", Value: 10},
+	},
+}
+
+func (v ToplevelResizeEdge) String() string { return ToplevelResizeEdgeEnum.Format(uint32(v)) }
+
 // The different state values used on the surface. This is designed for
 // state values like maximized, fullscreen. It is paired with the
 // configure event to ensure that both the client and the compositor
@@ -651,6 +773,22 @@ const (
 	ToplevelStateTiledBottom ToplevelState = 8
 )
 
+var ToplevelStateEnum = &wlproto.Enum{
+	Name: "xdg_toplevel.state",
+	Entries: []wlproto.EnumEntry{
+		{Name: "maximized", Value: 1},
+		{Name: "fullscreen", Value: 2},
+		{Name: "resizing", Value: 3},
+		{Name: "activated", Value: 4},
+		{Name: "tiled_left", Value: 5},
+		{Name: "tiled_right", Value: 6},
+		{Name: "tiled_top", Value: 7},
+		{Name: "tiled_bottom", Value: 8},
+	},
+}
+
+func (v ToplevelState) String() string { return ToplevelStateEnum.Format(uint32(v)) }
+
 var ToplevelInterface = &wlproto.Interface{
 	Name:    "xdg_toplevel",
 	Version: 4,
@@ -720,7 +858,7 @@ var ToplevelInterface = &wlproto.Interface{
 			Args: []wlproto.Arg{
 				{Type: wlproto.ArgTypeObject, Aux: reflect.TypeOf(wayland.Seat{})},
 				{Type: wlproto.ArgTypeUint},
-				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ToplevelResizeEdge(0))},
+				{Type: wlproto.ArgTypeUint, Aux: reflect.TypeOf(ToplevelResizeEdge(0)), Enum: ToplevelResizeEdgeEnum},
 			},
 		},
 		{
@@ -845,8 +983,8 @@ type ToplevelImplementation interface {
 	SetMinimized(obj Toplevel)
 }
 
-func AddToplevelGlobal(dsp *wlserver.Display, version int, bind func(res Toplevel) ToplevelImplementation) {
-	dsp.AddGlobal(ToplevelInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Toplevel)) })
+func AddToplevelGlobal(dsp *wlserver.Display, version int, bind func(res Toplevel) ToplevelImplementation) uint32 {
+	return dsp.AddGlobal(ToplevelInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Toplevel)) })
 }
 
 // This configure event asks the client to resize its toplevel surface or
@@ -910,6 +1048,15 @@ const (
 	PopupErrorInvalidGrab PopupError = 0
 )
 
+var PopupErrorEnum = &wlproto.Enum{
+	Name: "xdg_popup.error",
+	Entries: []wlproto.EnumEntry{
+		{Name: "invalid_grab", Value: 0},
+	},
+}
+
+func (v PopupError) String() string { return PopupErrorEnum.Format(uint32(v)) }
+
 var PopupInterface = &wlproto.Interface{
 	Name:    "xdg_popup",
 	Version: 4,
@@ -1003,8 +1150,8 @@ type PopupImplementation interface {
 	Reposition(obj Popup, positioner Positioner, token uint32)
 }
 
-func AddPopupGlobal(dsp *wlserver.Display, version int, bind func(res Popup) PopupImplementation) {
-	dsp.AddGlobal(PopupInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Popup)) })
+func AddPopupGlobal(dsp *wlserver.Display, version int, bind func(res Popup) PopupImplementation) uint32 {
+	return dsp.AddGlobal(PopupInterface, version, func(res wlserver.Object) wlserver.ResourceImplementation { return bind(res.(Popup)) })
 }
 
 // This event asks the popup surface to configure itself given the
@@ -0,0 +1,226 @@
+package seat
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+// Modifiers is the state of the keyboard modifiers, as serialized by
+// xkb_state_serialize_mods and xkb_state_serialize_layout.
+type Modifiers struct {
+	Depressed uint32
+	Latched   uint32
+	Locked    uint32
+	Group     uint32
+}
+
+type keyboardState struct {
+	focus       *compositor.Surface
+	enterSerial uint32
+	// keys are the pressed keys
+	keys []uint32
+	mods Modifiers
+
+	keymapFormat wayland.KeyboardKeymapFormat
+	keymapFd     int
+	keymapSize   uint32
+	repeatRate   int32
+	repeatDelay  int32
+}
+
+// Keyboard is a wl_keyboard.
+type Keyboard struct {
+	Resource wayland.Keyboard
+
+	seat *Seat
+}
+
+func (kbd *Keyboard) Release(obj wayland.Keyboard) {}
+
+// SetKeymap sets the keyboard's keymap and sends it to all clients.
+// For the xkb_v1 format, keymap is the text of the keymap, as returned
+// by xkb_keymap_get_as_string; a terminating NUL byte is added if it is
+// missing.
+//
+// The keymap is shared with clients through a sealed memfd, which
+// clients can map but not modify.
+func (s *Seat) SetKeymap(format wayland.KeyboardKeymapFormat, keymap []byte) error {
+	if format == wayland.KeyboardKeymapFormatXkbV1 && (len(keymap) == 0 || keymap[len(keymap)-1] != 0) {
+		keymap = append(keymap[:len(keymap):len(keymap)], 0)
+	}
+	fd, err := unix.MemfdCreate("wayland-keymap", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return fmt.Errorf("couldn't create memfd: %w", err)
+	}
+	for b := keymap; len(b) > 0; {
+		n, err := unix.Write(fd, b)
+		if err != nil {
+			unix.Close(fd)
+			return fmt.Errorf("couldn't write keymap: %w", err)
+		}
+		b = b[n:]
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("couldn't seal keymap: %w", err)
+	}
+
+	k := &s.keyboard
+	if k.keymapFd != -1 {
+		unix.Close(k.keymapFd)
+	}
+	k.keymapFormat = format
+	k.keymapFd = fd
+	k.keymapSize = uint32(len(keymap))
+	for _, cr := range s.clients {
+		for _, res := range cr.keyboards {
+			s.sendKeymap(res)
+		}
+	}
+	return nil
+}
+
+func (s *Seat) sendKeymap(res wayland.Keyboard) {
+	k := &s.keyboard
+	if k.keymapFd == -1 {
+		// Clients must always receive a keymap; tell them that there
+		// is none, yet.
+		fd, err := unix.Open("/dev/null", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return
+		}
+		res.Keymap(wayland.KeyboardKeymapFormatNoKeymap, uintptr(fd), 0)
+		unix.Close(fd)
+		return
+	}
+	res.Keymap(k.keymapFormat, uintptr(k.keymapFd), k.keymapSize)
+}
+
+// SetRepeatInfo sets the key repeat rate in characters per second and
+// the delay before repeating starts in milliseconds. A rate of zero
+// disables key repeat.
+func (s *Seat) SetRepeatInfo(rate, delay int32) {
+	s.keyboard.repeatRate, s.keyboard.repeatDelay = rate, delay
+	for _, cr := range s.clients {
+		for _, res := range cr.keyboards {
+			if res.Version() >= 4 {
+				res.RepeatInfo(rate, delay)
+			}
+		}
+	}
+}
+
+// KeyboardFocus returns the surface with keyboard focus, or nil.
+func (s *Seat) KeyboardFocus() *compositor.Surface { return s.keyboard.focus }
+
+// Keys returns the pressed keys.
+func (s *Seat) Keys() []uint32 { return s.keyboard.keys }
+
+// Modifiers returns the state of the modifiers.
+func (s *Seat) Modifiers() Modifiers { return s.keyboard.mods }
+
+// SetKeyboardFocus moves the keyboard focus to surf, which may be nil.
+// The surface is told about the pressed keys and the modifiers.
+func (s *Seat) SetKeyboardFocus(surf *compositor.Surface) {
+	k := &s.keyboard
+	if surf == k.focus {
+		return
+	}
+	if old := k.focus; old != nil {
+		if cr := s.clientOf(old); cr != nil {
+			serial := s.nextSerial(old.Resource.Conn())
+			for _, res := range cr.keyboards {
+				res.Leave(serial, old.Resource)
+			}
+		}
+	}
+	k.focus = surf
+	if surf != nil {
+		s.watch(surf)
+		k.enterSerial = s.nextSerial(surf.Resource.Conn())
+		if cr := s.clientOf(surf); cr != nil {
+			for _, res := range cr.keyboards {
+				s.sendKeyboardEnter(res, k.enterSerial)
+			}
+		}
+	}
+	if s.events.KeyboardFocus != nil {
+		s.events.KeyboardFocus(surf)
+	}
+}
+
+// sendKeyboardEnter sends the enter event, followed by the modifiers.
+func (s *Seat) sendKeyboardEnter(res wayland.Keyboard, serial uint32) {
+	k := &s.keyboard
+	keys := make([]byte, 4*len(k.keys))
+	for i, key := range k.keys {
+		byteOrder.PutUint32(keys[4*i:], key)
+	}
+	res.Enter(serial, k.focus.Resource, keys)
+	m := k.mods
+	res.Modifiers(s.nextSerial(res.Conn()), m.Depressed, m.Latched, m.Locked, m.Group)
+}
+
+// KeyboardKey presses or releases a key, identified by its Linux input
+// event code, and sends the event to the surface with keyboard focus.
+// It returns the serial of the key event, or zero if no surface has
+// keyboard focus.
+func (s *Seat) KeyboardKey(time, key uint32, state wayland.KeyboardKeyState) uint32 {
+	k := &s.keyboard
+	pressed := -1
+	for i, kk := range k.keys {
+		if kk == key {
+			pressed = i
+			break
+		}
+	}
+	switch state {
+	case wayland.KeyboardKeyStatePressed:
+		if pressed != -1 {
+			return 0
+		}
+		k.keys = append(k.keys, key)
+	case wayland.KeyboardKeyStateReleased:
+		if pressed == -1 {
+			return 0
+		}
+		k.keys = append(k.keys[:pressed], k.keys[pressed+1:]...)
+	}
+
+	f := k.focus
+	if f == nil {
+		return 0
+	}
+	serial := s.nextSerial(f.Resource.Conn())
+	if cr := s.clientOf(f); cr != nil {
+		for _, res := range cr.keyboards {
+			res.Key(serial, time, key, state)
+		}
+	}
+	return serial
+}
+
+// KeyboardModifiers updates the state of the modifiers and sends it to
+// the surface with keyboard focus. Compositors call it after feeding
+// key events into their xkb state.
+func (s *Seat) KeyboardModifiers(mods Modifiers) {
+	k := &s.keyboard
+	if mods == k.mods {
+		return
+	}
+	k.mods = mods
+	f := k.focus
+	if f == nil {
+		return
+	}
+	serial := s.nextSerial(f.Resource.Conn())
+	if cr := s.clientOf(f); cr != nil {
+		for _, res := range cr.keyboards {
+			res.Modifiers(serial, mods.Depressed, mods.Latched, mods.Locked, mods.Group)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package seat
+
+import (
+	"fmt"
+
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+// cursorRoleName is the compositor role of cursor surfaces.
+const cursorRoleName = "wl_pointer-cursor"
+
+// cursorRole is the role object of cursor surfaces.
+type cursorRole struct{}
+
+func (*cursorRole) Precommit(s *compositor.Surface) bool { return true }
+func (*cursorRole) Commit(s *compositor.Surface)         {}
+
+var cursor = &cursorRole{}
+
+// grab is an implicit grab, which keeps the pointer focus on a surface
+// while buttons are pressed.
+type grab struct {
+	surface *compositor.Surface
+	serial  uint32
+}
+
+type pointerState struct {
+	// x and y are the global position of the pointer
+	x, y  float64
+	focus *compositor.Surface
+	// originX and originY are the global position of the focused
+	// surface
+	originX, originY float64
+	enterSerial      uint32
+	buttons          []uint32
+	grab             *grab
+}
+
+// local returns the pointer's position relative to the focused surface.
+func (p *pointerState) local() (x, y float64) {
+	return p.x - p.originX, p.y - p.originY
+}
+
+// Pointer is a wl_pointer.
+type Pointer struct {
+	Resource wayland.Pointer
+
+	seat *Seat
+}
+
+func (ptr *Pointer) SetCursor(obj wayland.Pointer, serial uint32, surface wayland.Surface, hotspotX, hotspotY int32) {
+	s := ptr.seat
+	f := s.pointer.focus
+	if f == nil || f.Resource.Conn() != obj.Conn() || serial != s.pointer.enterSerial {
+		// Only the client with pointer focus may set the cursor, and
+		// only in response to the latest enter event.
+		return
+	}
+	var cs *compositor.Surface
+	if surface.Conn() != nil {
+		var ok bool
+		cs, ok = compositor.SurfaceFromResource(surface)
+		if !ok {
+			return
+		}
+		if cs.Role() != cursor && !cs.SetRole(cursorRoleName, cursor) {
+			s.dsp.Error(obj, uint32(wayland.PointerErrorRole),
+				fmt.Sprintf("%s@%d already has a role", surface.Interface().Name, surface.ID()))
+			return
+		}
+	}
+	if s.events.SetCursor != nil {
+		s.events.SetCursor(cs, hotspotX, hotspotY)
+	}
+}
+
+func (ptr *Pointer) Release(obj wayland.Pointer) {}
+
+// PointerPosition returns the global position of the pointer.
+func (s *Seat) PointerPosition() (x, y float64) { return s.pointer.x, s.pointer.y }
+
+// PointerFocus returns the surface with pointer focus, or nil.
+func (s *Seat) PointerFocus() *compositor.Surface { return s.pointer.focus }
+
+// PointerMotion moves the pointer to the global position x, y. The
+// pointer focus moves to the surface at the new position, unless a
+// surface has an implicit grab, which then keeps receiving the motion.
+// The position of a surface is assumed not to change during a grab.
+func (s *Seat) PointerMotion(time uint32, x, y float64) {
+	s.pointer.x, s.pointer.y = x, y
+	if s.pointer.grab == nil && s.updatePointerFocus() {
+		return
+	}
+	cr := s.clientOf(s.pointer.focus)
+	if cr == nil {
+		return
+	}
+	lx, ly := s.pointer.local()
+	for _, res := range cr.pointers {
+		res.Motion(time, wlshared.FromFloat64(lx), wlshared.FromFloat64(ly))
+		if res.Version() >= 5 {
+			res.Frame()
+		}
+	}
+}
+
+// updatePointerFocus gives the pointer focus to the surface under the
+// pointer. It reports whether the focus changed.
+func (s *Seat) updatePointerFocus() bool {
+	var surf *compositor.Surface
+	var sx, sy float64
+	if s.events.SurfaceAt != nil && s.caps&wayland.SeatCapabilityPointer != 0 {
+		surf, sx, sy = s.events.SurfaceAt(s.pointer.x, s.pointer.y)
+	}
+	if surf == s.pointer.focus {
+		// The surface may have moved.
+		s.pointer.originX, s.pointer.originY = s.pointer.x-sx, s.pointer.y-sy
+		return false
+	}
+	s.setPointerFocus(surf, s.pointer.x-sx, s.pointer.y-sy)
+	return true
+}
+
+// setPointerFocus sends leave and enter events and moves the focus to
+// surf, whose global position is originX, originY.
+func (s *Seat) setPointerFocus(surf *compositor.Surface, originX, originY float64) {
+	if old := s.pointer.focus; old != nil {
+		if cr := s.clientOf(old); cr != nil {
+			serial := s.nextSerial(old.Resource.Conn())
+			for _, res := range cr.pointers {
+				res.Leave(serial, old.Resource)
+				if res.Version() >= 5 {
+					res.Frame()
+				}
+			}
+		}
+	}
+	s.pointer.focus = surf
+	s.pointer.originX, s.pointer.originY = originX, originY
+	if surf == nil {
+		return
+	}
+	s.watch(surf)
+	s.pointer.enterSerial = s.nextSerial(surf.Resource.Conn())
+	lx, ly := s.pointer.local()
+	if cr := s.clientOf(surf); cr != nil {
+		for _, res := range cr.pointers {
+			res.Enter(s.pointer.enterSerial, surf.Resource, wlshared.FromFloat64(lx), wlshared.FromFloat64(ly))
+			if res.Version() >= 5 {
+				res.Frame()
+			}
+		}
+	}
+}
+
+// clearPointer removes the pointer focus and releases all buttons.
+func (s *Seat) clearPointer() {
+	s.pointer.grab = nil
+	s.pointer.buttons = s.pointer.buttons[:0]
+	s.setPointerFocus(nil, 0, 0)
+}
+
+// PointerButton presses or releases a button, identified by its Linux
+// input event code, such as BTN_LEFT. Pressing the first button starts
+// an implicit grab on the surface with pointer focus, which lasts until
+// all buttons have been released. PointerButton returns the serial of
+// the button event, or zero if no surface has pointer focus.
+func (s *Seat) PointerButton(time, button uint32, state wayland.PointerButtonState) uint32 {
+	p := &s.pointer
+	pressed := -1
+	for i, b := range p.buttons {
+		if b == button {
+			pressed = i
+			break
+		}
+	}
+	switch state {
+	case wayland.PointerButtonStatePressed:
+		if pressed != -1 {
+			return 0
+		}
+		p.buttons = append(p.buttons, button)
+	case wayland.PointerButtonStateReleased:
+		if pressed == -1 {
+			return 0
+		}
+		p.buttons = append(p.buttons[:pressed], p.buttons[pressed+1:]...)
+	}
+
+	var serial uint32
+	if f := p.focus; f != nil {
+		serial = s.nextSerial(f.Resource.Conn())
+		if cr := s.clientOf(f); cr != nil {
+			for _, res := range cr.pointers {
+				res.Button(serial, time, button, state)
+				if res.Version() >= 5 {
+					res.Frame()
+				}
+			}
+		}
+		if state == wayland.PointerButtonStatePressed && p.grab == nil {
+			p.grab = &grab{surface: f, serial: serial}
+		}
+	}
+	if len(p.buttons) == 0 && p.grab != nil {
+		// The grab has ended, and the pointer may be over a different
+		// surface by now.
+		p.grab = nil
+		s.updatePointerFocus()
+	}
+	return serial
+}
+
+// PointerAxis sends scroll events to the surface with pointer focus.
+// Value is the amount of scrolling in surface coordinates, and discrete
+// the number of steps for wheels with discrete steps, or zero. A value
+// of zero tells the client that scrolling on the axis stopped, which is
+// used for kinetic scrolling with touchpads.
+func (s *Seat) PointerAxis(time uint32, source wayland.PointerAxisSource, axis wayland.PointerAxis, value float64, discrete int32) {
+	cr := s.clientOf(s.pointer.focus)
+	if cr == nil {
+		return
+	}
+	for _, res := range cr.pointers {
+		if res.Version() < 5 {
+			if value != 0 {
+				res.Axis(time, axis, wlshared.FromFloat64(value))
+			}
+			continue
+		}
+		res.AxisSource(source)
+		if value == 0 {
+			res.AxisStop(time, axis)
+		} else {
+			if discrete != 0 {
+				res.AxisDiscrete(axis, discrete)
+			}
+			res.Axis(time, axis, wlshared.FromFloat64(value))
+		}
+		res.Frame()
+	}
+}
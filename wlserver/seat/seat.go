@@ -0,0 +1,355 @@
+// Package seat implements the wl_seat global for compositors.
+//
+// A seat is a group of input devices: a pointer, a keyboard and a
+// touch screen. The compositor feeds the input of these devices into a
+// Seat, which routes it to the clients whose surfaces have focus.
+// Pointer and touch input is delivered to the surface under the
+// pointer or touch point, as reported by Events.SurfaceAt, except while
+// an implicit grab is active: a surface that received a button press
+// keeps the pointer focus until all buttons have been released, and a
+// touch point stays with the surface it went down on. Keyboard focus
+// is set explicitly by the compositor.
+//
+// Events that clients may use to prove that they are responding to
+// user input, such as button presses, carry serials. ValidSerial and
+// HasGrab check these serials, e.g. for starting drags or grabbing
+// popups.
+package seat
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+var byteOrder binary.ByteOrder
+
+func init() {
+	var x uint32 = 0x01020304
+	if *(*byte)(unsafe.Pointer(&x)) == 0x01 {
+		byteOrder = binary.BigEndian
+	} else {
+		byteOrder = binary.LittleEndian
+	}
+}
+
+// Events are the callbacks of a Seat. All callbacks are optional.
+type Events struct {
+	// SurfaceAt returns the surface at the global position x, y, and
+	// the position relative to the surface. It returns nil if there is
+	// no surface at the position. If SurfaceAt is nil, pointer and
+	// touch input isn't delivered to any client.
+	SurfaceAt func(x, y float64) (s *compositor.Surface, sx, sy float64)
+	// SetCursor is called when the client with pointer focus sets the
+	// cursor image. Surface is nil if the client hid the cursor.
+	SetCursor func(surface *compositor.Surface, hotspotX, hotspotY int32)
+	// KeyboardFocus is called when the keyboard focus changes, either
+	// by a call to SetKeyboardFocus or because the focused surface got
+	// destroyed. Surface is nil if no surface has focus.
+	KeyboardFocus func(surface *compositor.Surface)
+}
+
+// recentSerials is the number of input serials remembered for
+// ValidSerial.
+const recentSerials = 16
+
+type serial struct {
+	client *wlserver.Client
+	serial uint32
+}
+
+// clientResources are the objects that a client has created for a
+// seat.
+type clientResources struct {
+	seats     []wayland.Seat
+	pointers  []wayland.Pointer
+	keyboards []wayland.Keyboard
+	touches   []wayland.Touch
+}
+
+// Seat is an implementation of the wl_seat global.
+type Seat struct {
+	dsp    *wlserver.Display
+	name   string
+	events Events
+
+	caps wayland.SeatCapability
+	// everCaps are all capabilities the seat has ever had; clients may
+	// get devices for these even after the capability got removed
+	everCaps wayland.SeatCapability
+	clients  map[*wlserver.Client]*clientResources
+	serials  [recentSerials]serial
+	nserials int
+	// watched are the surfaces whose destruction we listen for
+	watched map[*compositor.Surface]struct{}
+
+	pointer  pointerState
+	keyboard keyboardState
+	touch    touchState
+}
+
+// AddGlobal adds a wl_seat global with the given name, such as
+// "seat0", to the display. The seat initially has no capabilities.
+func AddGlobal(dsp *wlserver.Display, name string, events Events) *Seat {
+	s := &Seat{
+		dsp:     dsp,
+		name:    name,
+		events:  events,
+		clients: make(map[*wlserver.Client]*clientResources),
+		watched: make(map[*compositor.Surface]struct{}),
+		keyboard: keyboardState{
+			keymapFd:    -1,
+			repeatRate:  25,
+			repeatDelay: 600,
+		},
+		touch: touchState{
+			points: make(map[int32]*touchPoint),
+			frames: make(map[*wlserver.Client]struct{}),
+		},
+	}
+	wayland.AddSeatGlobal(dsp, 7, func(res wayland.Seat) wayland.SeatImplementation {
+		s.bind(res)
+		return s
+	})
+	return s
+}
+
+// SeatFromResource returns the seat that implements res. It returns
+// false if res is a null object.
+func SeatFromResource(res wayland.Seat) (*Seat, bool) {
+	if res.Conn() == nil {
+		return nil, false
+	}
+	s, ok := res.Implementation().(*Seat)
+	return s, ok
+}
+
+// Display returns the display of the seat.
+func (s *Seat) Display() *wlserver.Display { return s.dsp }
+
+// Name returns the name of the seat.
+func (s *Seat) Name() string { return s.name }
+
+// Capabilities returns the seat's capabilities.
+func (s *Seat) Capabilities() wayland.SeatCapability { return s.caps }
+
+// SetCapabilities sets the kinds of input devices that the seat has
+// and announces them to clients. Removing a capability ends the input
+// focus of the respective device.
+func (s *Seat) SetCapabilities(caps wayland.SeatCapability) {
+	if caps == s.caps {
+		return
+	}
+	removed := s.caps &^ caps
+	s.caps = caps
+	s.everCaps |= caps
+	if removed&wayland.SeatCapabilityPointer != 0 {
+		s.clearPointer()
+	}
+	if removed&wayland.SeatCapabilityKeyboard != 0 {
+		s.SetKeyboardFocus(nil)
+	}
+	if removed&wayland.SeatCapabilityTouch != 0 {
+		s.TouchCancel()
+	}
+	for _, cr := range s.clients {
+		for _, res := range cr.seats {
+			res.Capabilities(caps)
+		}
+	}
+}
+
+func (s *Seat) bind(res wayland.Seat) {
+	c := res.Conn()
+	cr := s.resources(c)
+	cr.seats = append(cr.seats, res)
+	res.OnDestroy(func() {
+		cr.seats = removeResource(cr.seats, res)
+		s.forgetClient(c, cr)
+	})
+	res.Capabilities(s.caps)
+	if res.Version() >= 2 {
+		res.Name(s.name)
+	}
+}
+
+func (s *Seat) resources(c *wlserver.Client) *clientResources {
+	cr, ok := s.clients[c]
+	if !ok {
+		cr = &clientResources{}
+		s.clients[c] = cr
+	}
+	return cr
+}
+
+// forgetClient stops tracking a client once it has no objects left.
+func (s *Seat) forgetClient(c *wlserver.Client, cr *clientResources) {
+	if len(cr.seats) == 0 && len(cr.pointers) == 0 && len(cr.keyboards) == 0 && len(cr.touches) == 0 {
+		delete(s.clients, c)
+	}
+}
+
+// nextSerial returns a new serial for an input event sent to c, and
+// remembers it for ValidSerial.
+func (s *Seat) nextSerial(c *wlserver.Client) uint32 {
+	n := s.dsp.NextSerial()
+	s.serials[s.nserials%recentSerials] = serial{c, n}
+	s.nserials++
+	return n
+}
+
+// ValidSerial reports whether serial is the serial of one of the recent
+// input events sent to client.
+func (s *Seat) ValidSerial(client *wlserver.Client, serial uint32) bool {
+	for _, sr := range s.serials {
+		if sr.client == client && sr.serial == serial && serial != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGrab reports whether surface has an implicit grab that was
+// started by the input event with the given serial, that is, whether
+// the serial is that of a button press or touch down on surface that
+// hasn't been released yet. Compositors use it to validate requests
+// such as wl_data_device.start_drag and xdg_toplevel.move.
+func (s *Seat) HasGrab(surface *compositor.Surface, serial uint32) bool {
+	if g := s.pointer.grab; g != nil && g.surface == surface && g.serial == serial {
+		return true
+	}
+	for _, pt := range s.touch.points {
+		if pt.surface == surface && pt.serial == serial {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Seat) GetPointer(obj wayland.Seat, id wayland.Pointer) wayland.PointerImplementation {
+	ptr := &Pointer{Resource: id, seat: s}
+	if !s.checkCapability(obj, wayland.SeatCapabilityPointer) {
+		return ptr
+	}
+	c := id.Conn()
+	cr := s.resources(c)
+	cr.pointers = append(cr.pointers, id)
+	id.OnDestroy(func() {
+		cr.pointers = removeResource(cr.pointers, id)
+		s.forgetClient(c, cr)
+	})
+	if f := s.pointer.focus; f != nil && f.Resource.Conn() == c {
+		x, y := s.pointer.local()
+		id.Enter(s.pointer.enterSerial, f.Resource, wlshared.FromFloat64(x), wlshared.FromFloat64(y))
+		if id.Version() >= 5 {
+			id.Frame()
+		}
+	}
+	return ptr
+}
+
+func (s *Seat) GetKeyboard(obj wayland.Seat, id wayland.Keyboard) wayland.KeyboardImplementation {
+	kbd := &Keyboard{Resource: id, seat: s}
+	if !s.checkCapability(obj, wayland.SeatCapabilityKeyboard) {
+		return kbd
+	}
+	c := id.Conn()
+	cr := s.resources(c)
+	cr.keyboards = append(cr.keyboards, id)
+	id.OnDestroy(func() {
+		cr.keyboards = removeResource(cr.keyboards, id)
+		s.forgetClient(c, cr)
+	})
+	s.sendKeymap(id)
+	if id.Version() >= 4 {
+		id.RepeatInfo(s.keyboard.repeatRate, s.keyboard.repeatDelay)
+	}
+	if f := s.keyboard.focus; f != nil && f.Resource.Conn() == c {
+		s.sendKeyboardEnter(id, s.keyboard.enterSerial)
+	}
+	return kbd
+}
+
+func (s *Seat) GetTouch(obj wayland.Seat, id wayland.Touch) wayland.TouchImplementation {
+	t := &Touch{Resource: id, seat: s}
+	if !s.checkCapability(obj, wayland.SeatCapabilityTouch) {
+		return t
+	}
+	c := id.Conn()
+	cr := s.resources(c)
+	cr.touches = append(cr.touches, id)
+	id.OnDestroy(func() {
+		cr.touches = removeResource(cr.touches, id)
+		s.forgetClient(c, cr)
+	})
+	return t
+}
+
+// checkCapability posts an error if the seat never had the capability.
+func (s *Seat) checkCapability(obj wayland.Seat, capability wayland.SeatCapability) bool {
+	if s.everCaps&capability == 0 {
+		s.dsp.Error(obj, uint32(wayland.SeatErrorMissingCapability), "seat lacks the capability")
+		return false
+	}
+	return true
+}
+
+func (s *Seat) Release(obj wayland.Seat) {}
+
+// watch makes sure that the seat forgets about surf when it gets
+// destroyed.
+func (s *Seat) watch(surf *compositor.Surface) {
+	if _, ok := s.watched[surf]; ok {
+		return
+	}
+	s.watched[surf] = struct{}{}
+	surf.Resource.OnDestroy(func() {
+		delete(s.watched, surf)
+		s.surfaceDestroyed(surf)
+	})
+}
+
+// surfaceDestroyed removes the input focus from a destroyed surface.
+// No leave events are sent, as clients have already forgotten about the
+// surface.
+func (s *Seat) surfaceDestroyed(surf *compositor.Surface) {
+	if s.pointer.focus == surf {
+		s.pointer.focus = nil
+	}
+	if g := s.pointer.grab; g != nil && g.surface == surf {
+		s.pointer.grab = nil
+	}
+	if s.keyboard.focus == surf {
+		s.keyboard.focus = nil
+		if s.events.KeyboardFocus != nil {
+			s.events.KeyboardFocus(nil)
+		}
+	}
+	for id, pt := range s.touch.points {
+		if pt.surface == surf {
+			delete(s.touch.points, id)
+		}
+	}
+}
+
+// clientOf returns the resources of the client that owns surf, or nil.
+func (s *Seat) clientOf(surf *compositor.Surface) *clientResources {
+	if surf == nil {
+		return nil
+	}
+	return s.clients[surf.Resource.Conn()]
+}
+
+// removeResource removes res from list.
+func removeResource[T comparable](list []T, res T) []T {
+	for i, r := range list {
+		if r == res {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
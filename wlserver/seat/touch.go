@@ -0,0 +1,134 @@
+package seat
+
+import (
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	"honnef.co/go/wayland/wlshared"
+)
+
+// touchPoint is a touch point that is down. Each touch point is bound
+// to the surface it went down on.
+type touchPoint struct {
+	// surface is nil if the point didn't go down on a surface
+	surface *compositor.Surface
+	serial  uint32
+	// originX and originY are the global position of the surface
+	originX, originY float64
+}
+
+type touchState struct {
+	points map[int32]*touchPoint
+	// frames are the clients that have received events since the
+	// last frame
+	frames map[*wlserver.Client]struct{}
+}
+
+// Touch is a wl_touch.
+type Touch struct {
+	Resource wayland.Touch
+
+	seat *Seat
+}
+
+func (t *Touch) Release(obj wayland.Touch) {}
+
+// TouchDown starts a new touch point with the given ID at the global
+// position x, y. The point is delivered to the surface at that
+// position for as long as it is down. TouchDown returns the serial of
+// the down event, or zero if there is no surface at the position.
+func (s *Seat) TouchDown(time uint32, id int32, x, y float64) uint32 {
+	pt := &touchPoint{}
+	s.touch.points[id] = pt
+	if s.events.SurfaceAt == nil || s.caps&wayland.SeatCapabilityTouch == 0 {
+		return 0
+	}
+	surf, sx, sy := s.events.SurfaceAt(x, y)
+	if surf == nil {
+		return 0
+	}
+	s.watch(surf)
+	c := surf.Resource.Conn()
+	pt.surface = surf
+	pt.serial = s.nextSerial(c)
+	pt.originX, pt.originY = x-sx, y-sy
+	if cr := s.clientOf(surf); cr != nil {
+		for _, res := range cr.touches {
+			res.Down(pt.serial, time, surf.Resource, id, wlshared.FromFloat64(sx), wlshared.FromFloat64(sy))
+		}
+	}
+	s.touch.frames[c] = struct{}{}
+	return pt.serial
+}
+
+// TouchMotion moves the touch point with the given ID to the global
+// position x, y.
+func (s *Seat) TouchMotion(time uint32, id int32, x, y float64) {
+	pt, ok := s.touch.points[id]
+	if !ok || pt.surface == nil {
+		return
+	}
+	if cr := s.clientOf(pt.surface); cr != nil {
+		for _, res := range cr.touches {
+			res.Motion(time, id, wlshared.FromFloat64(x-pt.originX), wlshared.FromFloat64(y-pt.originY))
+		}
+	}
+	s.touch.frames[pt.surface.Resource.Conn()] = struct{}{}
+}
+
+// TouchUp ends the touch point with the given ID. It returns the serial
+// of the up event, or zero if the point isn't on a surface.
+func (s *Seat) TouchUp(time uint32, id int32) uint32 {
+	pt, ok := s.touch.points[id]
+	if !ok {
+		return 0
+	}
+	delete(s.touch.points, id)
+	if pt.surface == nil {
+		return 0
+	}
+	c := pt.surface.Resource.Conn()
+	serial := s.nextSerial(c)
+	if cr := s.clientOf(pt.surface); cr != nil {
+		for _, res := range cr.touches {
+			res.Up(serial, time, id)
+		}
+	}
+	s.touch.frames[c] = struct{}{}
+	return serial
+}
+
+// TouchFrame ends a set of touch events that belong together, such as
+// the motion of several points. Clients process touch events once they
+// receive the frame.
+func (s *Seat) TouchFrame() {
+	for c := range s.touch.frames {
+		if cr := s.clients[c]; cr != nil {
+			for _, res := range cr.touches {
+				res.Frame()
+			}
+		}
+		delete(s.touch.frames, c)
+	}
+}
+
+// TouchCancel cancels all touch points, for example because the
+// compositor recognized a gesture. Clients should undo the effects of
+// the current touch sequence.
+func (s *Seat) TouchCancel() {
+	clients := make(map[*wlserver.Client]struct{})
+	for id, pt := range s.touch.points {
+		if pt.surface != nil {
+			clients[pt.surface.Resource.Conn()] = struct{}{}
+		}
+		delete(s.touch.points, id)
+	}
+	for c := range clients {
+		if cr := s.clients[c]; cr != nil {
+			for _, res := range cr.touches {
+				res.Cancel()
+			}
+		}
+		delete(s.touch.frames, c)
+	}
+}
@@ -0,0 +1,165 @@
+package shell
+
+import (
+	"fmt"
+	"image"
+
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	xdgShell "honnef.co/go/wayland/wlserver/protocols/xdg-shell"
+)
+
+// Popup is an xdg_popup, the role of short-lived windows such as menus
+// and tooltips, which are placed relative to a parent window.
+type Popup struct {
+	Resource xdgShell.Popup
+
+	shell *Shell
+	// xs is nil once the popup has become inert, because it or its
+	// xdg_surface got destroyed
+	xs *Surface
+	// parent is nil if the parent's role object has been destroyed
+	parent     *Surface
+	positioner PositionerState
+	// geometry is the geometry that the client has acknowledged and
+	// committed, and pending the geometry of the latest configure
+	// sequence
+	geometry image.Rectangle
+	pending  image.Rectangle
+	popups   []*Popup
+	grabbed  bool
+	done     bool
+}
+
+// Surface returns the popup's xdg_surface, or nil if the popup has been
+// destroyed.
+func (p *Popup) Surface() *Surface { return p.xs }
+
+// Parent returns the xdg_surface that the popup is placed relative to,
+// or nil if it has been destroyed.
+func (p *Popup) Parent() *Surface { return p.parent }
+
+// Positioner returns the rules for placing the popup.
+func (p *Popup) Positioner() PositionerState { return p.positioner }
+
+// Geometry returns the popup's window geometry relative to the window
+// geometry of its parent, as acknowledged and committed by the client.
+func (p *Popup) Geometry() image.Rectangle { return p.geometry }
+
+// Grabbed reports whether the client requested an explicit grab for
+// the popup.
+func (p *Popup) Grabbed() bool { return p.grabbed }
+
+// Configure places the popup according to its positioner and sends a
+// configure sequence to the client. Compositors call it for reactive
+// popups when their parents move. It returns the configure serial.
+func (p *Popup) Configure() uint32 {
+	if p.xs == nil || p.done {
+		return 0
+	}
+	var bounds image.Rectangle
+	if p.shell.events.PopupBounds != nil {
+		bounds = p.shell.events.PopupBounds(p)
+	}
+	p.pending = p.positioner.Geometry(bounds)
+	g := p.pending
+	p.Resource.Configure(int32(g.Min.X), int32(g.Min.Y), int32(g.Dx()), int32(g.Dy()))
+	return p.xs.scheduleConfigure(configure{popup: g})
+}
+
+// Dismiss dismisses the popup and its child popups, for example
+// because the user clicked outside of them. Child popups get dismissed
+// first.
+func (p *Popup) Dismiss() {
+	p.dismissChildren()
+	if p.done || p.xs == nil {
+		return
+	}
+	p.done = true
+	p.Resource.PopupDone()
+}
+
+func (p *Popup) dismissChildren() {
+	for i := len(p.popups) - 1; i >= 0; i-- {
+		p.popups[i].Dismiss()
+	}
+}
+
+func (p *Popup) Destroy(obj xdgShell.Popup) {
+	if len(p.popups) > 0 && p.xs != nil {
+		p.shell.dsp.Error(p.xs.wm.Resource, uint32(xdgShell.WmBaseErrorNotTheTopmostPopup),
+			fmt.Sprintf("%s was destroyed while it had child popups", objectString(obj)))
+	}
+}
+
+func (p *Popup) handleDestroy() {
+	xs := p.xs
+	if xs == nil {
+		return
+	}
+	xs.unmap()
+	for _, c := range p.popups {
+		c.parent = nil
+	}
+	p.popups = nil
+	if p.parent != nil {
+		p.parent.removePopup(p)
+		p.parent = nil
+	}
+	xs.popup = nil
+	p.xs = nil
+}
+
+func (p *Popup) Grab(obj xdgShell.Popup, seat wayland.Seat, serial uint32) {
+	if p.xs == nil {
+		return
+	}
+	if p.xs.mapped {
+		p.shell.dsp.Error(obj, uint32(xdgShell.PopupErrorInvalidGrab),
+			fmt.Sprintf("%s tried to grab after being mapped", objectString(obj)))
+		return
+	}
+	p.grabbed = true
+	if p.shell.events.PopupGrab != nil {
+		p.shell.events.PopupGrab(p, seat, serial)
+	}
+}
+
+func (p *Popup) Reposition(obj xdgShell.Popup, positioner xdgShell.Positioner, token uint32) {
+	if p.xs == nil {
+		return
+	}
+	pos, ok := positionerFromResource(positioner)
+	if !ok || !pos.state.Complete() {
+		p.shell.dsp.Error(p.xs.wm.Resource, uint32(xdgShell.WmBaseErrorInvalidPositioner),
+			fmt.Sprintf("%s is incomplete", objectString(positioner)))
+		return
+	}
+	p.positioner = pos.state
+	if !p.xs.initialCommitted {
+		// The initial configure will use the new positioner.
+		return
+	}
+	if p.Resource.Version() >= 3 {
+		p.Resource.Repositioned(token)
+	}
+	p.Configure()
+}
+
+// removePopup removes p from the popups of the surface's role object.
+func (xs *Surface) removePopup(p *Popup) {
+	var popups *[]*Popup
+	switch {
+	case xs.toplevel != nil:
+		popups = &xs.toplevel.popups
+	case xs.popup != nil:
+		popups = &xs.popup.popups
+	default:
+		return
+	}
+	for i, pp := range *popups {
+		if pp == p {
+			*popups = append((*popups)[:i], (*popups)[i+1:]...)
+			return
+		}
+	}
+}
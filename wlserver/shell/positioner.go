@@ -0,0 +1,265 @@
+package shell
+
+import (
+	"fmt"
+	"image"
+
+	xdgShell "honnef.co/go/wayland/wlserver/protocols/xdg-shell"
+)
+
+// PositionerState are the rules for placing a popup, as set by the
+// client on an xdg_positioner.
+type PositionerState struct {
+	// Size is the size of the popup's window geometry.
+	Size image.Point
+	// AnchorRect is the rectangle that the popup is anchored to,
+	// relative to the window geometry of the parent.
+	AnchorRect image.Rectangle
+	// Anchor is the point of the anchor rectangle that the popup is
+	// placed at, and Gravity the direction that the popup extends in
+	// from there.
+	Anchor               xdgShell.PositionerAnchor
+	Gravity              xdgShell.PositionerGravity
+	ConstraintAdjustment xdgShell.PositionerConstraintAdjustment
+	Offset               image.Point
+	// Reactive is set if the compositor should reposition the popup
+	// when the constraints change, for example because the parent
+	// moved.
+	Reactive bool
+	// ParentSize and ParentConfigure are the size of the parent and
+	// the serial of its configure sequence that the client expects the
+	// popup to be placed relative to, for reactive popups.
+	ParentSize      image.Point
+	ParentConfigure uint32
+
+	hasSize       bool
+	hasAnchorRect bool
+}
+
+// Complete reports whether the client set the size and the anchor
+// rectangle, which are needed to place a popup.
+func (st PositionerState) Complete() bool { return st.hasSize && st.hasAnchorRect }
+
+// Geometry returns the popup's window geometry, relative to the window
+// geometry of its parent. If bounds isn't empty, the popup is adjusted
+// to fit in bounds as far as the constraint adjustments allow: the
+// popup is flipped to the other side of the anchor, then slid along
+// the constrained axes, and finally resized.
+func (st PositionerState) Geometry(bounds image.Rectangle) image.Rectangle {
+	box := st.unconstrained(st.Anchor, st.Gravity)
+	if bounds.Empty() || box.In(bounds) {
+		return box
+	}
+	adj := st.ConstraintAdjustment
+
+	// Flip
+	if adj&xdgShell.PositionerConstraintAdjustmentFlipX != 0 && constrainedX(box, bounds) {
+		flipped := st.unconstrained(flipAnchorX(st.Anchor), flipGravityX(st.Gravity))
+		if !constrainedX(flipped, bounds) {
+			box.Min.X, box.Max.X = flipped.Min.X, flipped.Max.X
+		}
+	}
+	if adj&xdgShell.PositionerConstraintAdjustmentFlipY != 0 && constrainedY(box, bounds) {
+		flipped := st.unconstrained(flipAnchorY(st.Anchor), flipGravityY(st.Gravity))
+		if !constrainedY(flipped, bounds) {
+			box.Min.Y, box.Max.Y = flipped.Min.Y, flipped.Max.Y
+		}
+	}
+
+	// Slide
+	if adj&xdgShell.PositionerConstraintAdjustmentSlideX != 0 && constrainedX(box, bounds) {
+		if d := box.Max.X - bounds.Max.X; d > 0 {
+			box = box.Sub(image.Pt(d, 0))
+		}
+		if d := bounds.Min.X - box.Min.X; d > 0 {
+			// The leading edge takes precedence if the popup doesn't
+			// fit at all.
+			box = box.Add(image.Pt(d, 0))
+		}
+	}
+	if adj&xdgShell.PositionerConstraintAdjustmentSlideY != 0 && constrainedY(box, bounds) {
+		if d := box.Max.Y - bounds.Max.Y; d > 0 {
+			box = box.Sub(image.Pt(0, d))
+		}
+		if d := bounds.Min.Y - box.Min.Y; d > 0 {
+			box = box.Add(image.Pt(0, d))
+		}
+	}
+
+	// Resize
+	if adj&xdgShell.PositionerConstraintAdjustmentResizeX != 0 && constrainedX(box, bounds) {
+		minX, maxX := max(box.Min.X, bounds.Min.X), min(box.Max.X, bounds.Max.X)
+		if minX < maxX {
+			box.Min.X, box.Max.X = minX, maxX
+		}
+	}
+	if adj&xdgShell.PositionerConstraintAdjustmentResizeY != 0 && constrainedY(box, bounds) {
+		minY, maxY := max(box.Min.Y, bounds.Min.Y), min(box.Max.Y, bounds.Max.Y)
+		if minY < maxY {
+			box.Min.Y, box.Max.Y = minY, maxY
+		}
+	}
+	return box
+}
+
+// unconstrained returns the popup's geometry for the given anchor and
+// gravity, ignoring constraints.
+func (st PositionerState) unconstrained(anchor xdgShell.PositionerAnchor, gravity xdgShell.PositionerGravity) image.Rectangle {
+	r := st.AnchorRect
+	p := image.Pt((r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2)
+	switch anchor {
+	case xdgShell.PositionerAnchorLeft, xdgShell.PositionerAnchorTopLeft, xdgShell.PositionerAnchorBottomLeft:
+		p.X = r.Min.X
+	case xdgShell.PositionerAnchorRight, xdgShell.PositionerAnchorTopRight, xdgShell.PositionerAnchorBottomRight:
+		p.X = r.Max.X
+	}
+	switch anchor {
+	case xdgShell.PositionerAnchorTop, xdgShell.PositionerAnchorTopLeft, xdgShell.PositionerAnchorTopRight:
+		p.Y = r.Min.Y
+	case xdgShell.PositionerAnchorBottom, xdgShell.PositionerAnchorBottomLeft, xdgShell.PositionerAnchorBottomRight:
+		p.Y = r.Max.Y
+	}
+
+	p = p.Add(st.Offset)
+	w, h := st.Size.X, st.Size.Y
+	switch gravity {
+	case xdgShell.PositionerGravityLeft, xdgShell.PositionerGravityTopLeft, xdgShell.PositionerGravityBottomLeft:
+		p.X -= w
+	case xdgShell.PositionerGravityRight, xdgShell.PositionerGravityTopRight, xdgShell.PositionerGravityBottomRight:
+	default:
+		p.X -= w / 2
+	}
+	switch gravity {
+	case xdgShell.PositionerGravityTop, xdgShell.PositionerGravityTopLeft, xdgShell.PositionerGravityTopRight:
+		p.Y -= h
+	case xdgShell.PositionerGravityBottom, xdgShell.PositionerGravityBottomLeft, xdgShell.PositionerGravityBottomRight:
+	default:
+		p.Y -= h / 2
+	}
+	return image.Rectangle{Min: p, Max: p.Add(st.Size)}
+}
+
+func constrainedX(box, bounds image.Rectangle) bool {
+	return box.Min.X < bounds.Min.X || box.Max.X > bounds.Max.X
+}
+
+func constrainedY(box, bounds image.Rectangle) bool {
+	return box.Min.Y < bounds.Min.Y || box.Max.Y > bounds.Max.Y
+}
+
+// The anchor and gravity enums share their values, which lets us flip
+// both with the same tables.
+var (
+	flipX = [...]uint32{0, 1, 2, 4, 3, 7, 8, 5, 6}
+	flipY = [...]uint32{0, 2, 1, 3, 4, 6, 5, 8, 7}
+)
+
+func flipAnchorX(a xdgShell.PositionerAnchor) xdgShell.PositionerAnchor {
+	return xdgShell.PositionerAnchor(flipX[a])
+}
+
+func flipAnchorY(a xdgShell.PositionerAnchor) xdgShell.PositionerAnchor {
+	return xdgShell.PositionerAnchor(flipY[a])
+}
+
+func flipGravityX(g xdgShell.PositionerGravity) xdgShell.PositionerGravity {
+	return xdgShell.PositionerGravity(flipX[g])
+}
+
+func flipGravityY(g xdgShell.PositionerGravity) xdgShell.PositionerGravity {
+	return xdgShell.PositionerGravity(flipY[g])
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Positioner is an xdg_positioner.
+type Positioner struct {
+	Resource xdgShell.Positioner
+
+	shell *Shell
+	state PositionerState
+}
+
+// positionerFromResource returns the positioner that implements res.
+func positionerFromResource(res xdgShell.Positioner) (*Positioner, bool) {
+	if res.Conn() == nil {
+		return nil, false
+	}
+	pos, ok := res.Implementation().(*Positioner)
+	return pos, ok
+}
+
+// State returns the rules set by the client.
+func (pos *Positioner) State() PositionerState { return pos.state }
+
+func (pos *Positioner) Destroy(obj xdgShell.Positioner) {}
+
+func (pos *Positioner) SetSize(obj xdgShell.Positioner, width, height int32) {
+	if width < 1 || height < 1 {
+		pos.shell.dsp.Error(obj, uint32(xdgShell.PositionerErrorInvalidInput),
+			fmt.Sprintf("invalid size %dx%d", width, height))
+		return
+	}
+	pos.state.Size = image.Pt(int(width), int(height))
+	pos.state.hasSize = true
+}
+
+func (pos *Positioner) SetAnchorRect(obj xdgShell.Positioner, x, y, width, height int32) {
+	if width < 0 || height < 0 {
+		pos.shell.dsp.Error(obj, uint32(xdgShell.PositionerErrorInvalidInput),
+			fmt.Sprintf("invalid anchor rectangle size %dx%d", width, height))
+		return
+	}
+	pos.state.AnchorRect = image.Rect(int(x), int(y), int(x)+int(width), int(y)+int(height))
+	pos.state.hasAnchorRect = true
+}
+
+func (pos *Positioner) SetAnchor(obj xdgShell.Positioner, anchor xdgShell.PositionerAnchor) {
+	if anchor > xdgShell.PositionerAnchorBottomRight {
+		pos.shell.dsp.Error(obj, uint32(xdgShell.PositionerErrorInvalidInput),
+			fmt.Sprintf("invalid anchor %d", anchor))
+		return
+	}
+	pos.state.Anchor = anchor
+}
+
+func (pos *Positioner) SetGravity(obj xdgShell.Positioner, gravity xdgShell.PositionerGravity) {
+	if gravity > xdgShell.PositionerGravityBottomRight {
+		pos.shell.dsp.Error(obj, uint32(xdgShell.PositionerErrorInvalidInput),
+			fmt.Sprintf("invalid gravity %d", gravity))
+		return
+	}
+	pos.state.Gravity = gravity
+}
+
+func (pos *Positioner) SetConstraintAdjustment(obj xdgShell.Positioner, constraintAdjustment uint32) {
+	pos.state.ConstraintAdjustment = xdgShell.PositionerConstraintAdjustment(constraintAdjustment)
+}
+
+func (pos *Positioner) SetOffset(obj xdgShell.Positioner, x, y int32) {
+	pos.state.Offset = image.Pt(int(x), int(y))
+}
+
+func (pos *Positioner) SetReactive(obj xdgShell.Positioner) {
+	pos.state.Reactive = true
+}
+
+func (pos *Positioner) SetParentSize(obj xdgShell.Positioner, parentWidth, parentHeight int32) {
+	pos.state.ParentSize = image.Pt(int(parentWidth), int(parentHeight))
+}
+
+func (pos *Positioner) SetParentConfigure(obj xdgShell.Positioner, serial uint32) {
+	pos.state.ParentConfigure = serial
+}
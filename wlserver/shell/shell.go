@@ -0,0 +1,233 @@
+// Package shell implements the xdg_wm_base global of the xdg-shell
+// protocol for compositors.
+//
+// Clients turn surfaces into windows by giving them the xdg_toplevel
+// or xdg_popup role. This package enforces the rules of these roles,
+// such as that clients must not attach buffers before they have
+// acknowledged the first configure event, tracks configure serials
+// and applies acknowledged configurations when the client commits its
+// surface. The compositor decides about the state of windows, by
+// calling Toplevel.Configure, and gets notified of the client's
+// requests via Events.
+//
+// Popups are placed according to the rules of their positioners,
+// constrained to the area returned by Events.PopupBounds.
+package shell
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/compositor"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	xdgShell "honnef.co/go/wayland/wlserver/protocols/xdg-shell"
+)
+
+// DefaultPingTimeout is the default time clients have to respond to
+// pings before they are considered unresponsive.
+const DefaultPingTimeout = 5 * time.Second
+
+// Events are the callbacks of a Shell. All callbacks are optional.
+type Events struct {
+	// NewToplevel is called when a client creates a toplevel.
+	NewToplevel func(t *Toplevel)
+	// NewPopup is called when a client creates a popup.
+	NewPopup func(p *Popup)
+	// InitialCommit is called when a client commits a window for the
+	// first time, or for the first time after unmapping it. The
+	// compositor should respond by configuring the window. If it
+	// doesn't, a default configuration is sent after InitialCommit
+	// returns.
+	InitialCommit func(s *Surface)
+	// Map is called when a window gets mapped, i.e. when the client
+	// commits a buffer for the first time after configuring it.
+	Map func(s *Surface)
+	// Unmap is called when a window gets unmapped, either because the
+	// client committed a null buffer or because it destroyed the
+	// window.
+	Unmap func(s *Surface)
+	// Commit is called after the client committed new state for a
+	// window.
+	Commit func(s *Surface)
+	// DestroySurface is called when an xdg_surface gets destroyed.
+	DestroySurface func(s *Surface)
+
+	// The following callbacks are requests of clients that the
+	// compositor may honor by configuring the window accordingly. The
+	// serial is that of the input event that triggered the request,
+	// and should be validated by the compositor.
+	RequestMove       func(t *Toplevel, seat wayland.Seat, serial uint32)
+	RequestResize     func(t *Toplevel, seat wayland.Seat, serial uint32, edges xdgShell.ToplevelResizeEdge)
+	RequestWindowMenu func(t *Toplevel, seat wayland.Seat, serial uint32, x, y int32)
+	RequestMaximize   func(t *Toplevel, maximized bool)
+	// RequestFullscreen is called with the output requested by the
+	// client, whose Conn is nil if the client didn't request a
+	// particular output.
+	RequestFullscreen func(t *Toplevel, fullscreen bool, output wayland.Output)
+	RequestMinimize   func(t *Toplevel)
+	// SetTitle, SetAppID and SetParent are called when the client
+	// changes the respective properties of a toplevel.
+	SetTitle  func(t *Toplevel)
+	SetAppID  func(t *Toplevel)
+	SetParent func(t *Toplevel)
+
+	// PopupGrab is called when a client requests an explicit grab for
+	// a popup. The compositor should dismiss the popup if it denies
+	// the grab.
+	PopupGrab func(p *Popup, seat wayland.Seat, serial uint32)
+	// PopupBounds returns the area that a popup has to be placed in,
+	// in coordinates relative to the window geometry of its parent,
+	// usually the output the parent is on minus the parent's position.
+	// If it is nil or returns an empty rectangle, popups aren't
+	// constrained.
+	PopupBounds func(p *Popup) image.Rectangle
+
+	// Unresponsive is called when a client didn't respond to a ping
+	// in time, and Responsive when it responds again afterwards.
+	Unresponsive func(wm *WmBase)
+	Responsive   func(wm *WmBase)
+}
+
+// Shell is an implementation of the xdg_wm_base global.
+type Shell struct {
+	dsp         *wlserver.Display
+	comp        *compositor.Compositor
+	events      Events
+	pingTimeout time.Duration
+}
+
+// AddGlobal adds an xdg_wm_base global to the display of comp. Windows
+// can be created for all surfaces created by comp.
+func AddGlobal(comp *compositor.Compositor, events Events) *Shell {
+	sh := &Shell{
+		dsp:         comp.Display(),
+		comp:        comp,
+		events:      events,
+		pingTimeout: DefaultPingTimeout,
+	}
+	xdgShell.AddWmBaseGlobal(sh.dsp, 4, func(res xdgShell.WmBase) xdgShell.WmBaseImplementation {
+		wm := &WmBase{Resource: res, shell: sh}
+		// Cancel outstanding pings when the client destroys the
+		// object or disconnects.
+		res.OnDestroy(func() { wm.pingGen++ })
+		return wm
+	})
+	return sh
+}
+
+// SetPingTimeout sets the time clients have to respond to pings.
+func (sh *Shell) SetPingTimeout(d time.Duration) {
+	sh.pingTimeout = d
+}
+
+// WmBase is a client's xdg_wm_base.
+type WmBase struct {
+	Resource xdgShell.WmBase
+
+	shell    *Shell
+	surfaces []*Surface
+
+	pingSerial   uint32
+	pinging      bool
+	unresponsive bool
+	// pingGen invalidates the timers of earlier pings
+	pingGen uint64
+}
+
+// Surfaces returns the client's xdg_surfaces that were created through
+// wm.
+func (wm *WmBase) Surfaces() []*Surface { return wm.surfaces }
+
+// Responsive reports whether the client responded to the latest ping
+// in time, or is still within the time limit.
+func (wm *WmBase) Responsive() bool { return !wm.unresponsive }
+
+// Ping checks whether the client is responsive. If it doesn't respond
+// within the shell's ping timeout, Events.Unresponsive is called.
+// Pinging a client that hasn't responded to the previous ping yet
+// does nothing.
+func (wm *WmBase) Ping() {
+	if wm.pinging {
+		return
+	}
+	sh := wm.shell
+	wm.pinging = true
+	wm.pingSerial = sh.dsp.NextSerial()
+	wm.pingGen++
+	gen := wm.pingGen
+	wm.Resource.Ping(wm.pingSerial)
+	time.AfterFunc(sh.pingTimeout, func() {
+		sh.dsp.Post(func() {
+			if gen != wm.pingGen || !wm.pinging || wm.unresponsive {
+				return
+			}
+			wm.unresponsive = true
+			if sh.events.Unresponsive != nil {
+				sh.events.Unresponsive(wm)
+			}
+		})
+	})
+}
+
+func (wm *WmBase) Pong(obj xdgShell.WmBase, serial uint32) {
+	if !wm.pinging || serial != wm.pingSerial {
+		return
+	}
+	wm.pinging = false
+	wm.pingGen++
+	if wm.unresponsive {
+		wm.unresponsive = false
+		if wm.shell.events.Responsive != nil {
+			wm.shell.events.Responsive(wm)
+		}
+	}
+}
+
+func (wm *WmBase) Destroy(obj xdgShell.WmBase) {
+	if len(wm.surfaces) > 0 {
+		wm.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorDefunctSurfaces),
+			"xdg_wm_base was destroyed before its xdg_surfaces")
+	}
+}
+
+func (wm *WmBase) CreatePositioner(obj xdgShell.WmBase, id xdgShell.Positioner) xdgShell.PositionerImplementation {
+	return &Positioner{Resource: id, shell: wm.shell}
+}
+
+func (wm *WmBase) GetXdgSurface(obj xdgShell.WmBase, id xdgShell.Surface, surface wayland.Surface) xdgShell.SurfaceImplementation {
+	xs := &Surface{Resource: id, wm: wm}
+	s, ok := compositor.SurfaceFromResource(surface)
+	if !ok {
+		wm.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorRole), "invalid surface")
+		return xs
+	}
+	if s.Current().Buffer.Conn() != nil || s.Pending().Buffer.Conn() != nil {
+		wm.shell.dsp.Error(id, uint32(xdgShell.SurfaceErrorUnconfiguredBuffer),
+			"surface already has a buffer")
+		return xs
+	}
+	if !s.SetRole(roleName, xs) {
+		wm.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorRole), "surface already has a role")
+		return xs
+	}
+	xs.surface = s
+	wm.surfaces = append(wm.surfaces, xs)
+	id.OnDestroy(xs.handleDestroy)
+	surface.OnDestroy(xs.handleSurfaceDestroy)
+	return xs
+}
+
+func (wm *WmBase) removeSurface(xs *Surface) {
+	for i, s := range wm.surfaces {
+		if s == xs {
+			wm.surfaces = append(wm.surfaces[:i], wm.surfaces[i+1:]...)
+			return
+		}
+	}
+}
+
+func objectString(obj wlserver.Object) string {
+	return fmt.Sprintf("%s@%d", obj.Interface().Name, obj.ID())
+}
@@ -0,0 +1,26 @@
+package shell
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	xdgShell "honnef.co/go/wayland/wlserver/protocols/xdg-shell"
+)
+
+func TestRectangleOverflow(t *testing.T) {
+	// Coordinates and sizes are added without wrapping around.
+	want := image.Rect(math.MaxInt32-10, 5, math.MaxInt32+90, 25)
+
+	xs := &Surface{}
+	xs.SetWindowGeometry(xdgShell.Surface{}, math.MaxInt32-10, 5, 100, 20)
+	if xs.pendingGeometry != want {
+		t.Errorf("window geometry = %v, want %v", xs.pendingGeometry, want)
+	}
+
+	pos := &Positioner{}
+	pos.SetAnchorRect(xdgShell.Positioner{}, math.MaxInt32-10, 5, 100, 20)
+	if pos.state.AnchorRect != want {
+		t.Errorf("anchor rectangle = %v, want %v", pos.state.AnchorRect, want)
+	}
+}
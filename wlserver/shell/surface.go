@@ -0,0 +1,351 @@
+package shell
+
+import (
+	"fmt"
+	"image"
+
+	"honnef.co/go/wayland/wlserver/compositor"
+	xdgShell "honnef.co/go/wayland/wlserver/protocols/xdg-shell"
+)
+
+// roleName is the compositor role of surfaces that have an
+// xdg_surface.
+const roleName = "xdg_surface"
+
+// configure is a configure sequence that the client hasn't
+// acknowledged yet.
+type configure struct {
+	serial   uint32
+	toplevel ToplevelState
+	popup    image.Rectangle
+}
+
+// Surface is an xdg_surface, the base of windows. Its role object,
+// either a Toplevel or a Popup, determines what kind of window it is.
+type Surface struct {
+	Resource xdgShell.Surface
+
+	wm *WmBase
+	// surface is nil once the wl_surface has been destroyed
+	surface  *compositor.Surface
+	toplevel *Toplevel
+	popup    *Popup
+	// kind is the kind of role object that the surface has had, either
+	// "xdg_toplevel" or "xdg_popup"
+	kind string
+
+	// configures are the configure sequences that have been sent but
+	// not acknowledged, in the order they were sent
+	configures []configure
+	// acked is the configure sequence acknowledged by the client, which
+	// gets applied by the next commit
+	acked    configure
+	hasAcked bool
+	// configured is set once the client has committed an acknowledged
+	// configure sequence since the initial commit
+	configured bool
+	// initialCommitted is set by the first commit after the role object
+	// has been created, or after the surface got unmapped
+	initialCommitted bool
+	mapped           bool
+	destroyed        bool
+
+	geometry           image.Rectangle
+	pendingGeometry    image.Rectangle
+	hasPendingGeometry bool
+}
+
+// SurfaceFromResource returns the xdg_surface that implements res.
+// It returns false if res is a null object.
+func SurfaceFromResource(res xdgShell.Surface) (*Surface, bool) {
+	if res.Conn() == nil {
+		return nil, false
+	}
+	xs, ok := res.Implementation().(*Surface)
+	return xs, ok
+}
+
+// WmBase returns the xdg_wm_base that created the surface.
+func (xs *Surface) WmBase() *WmBase { return xs.wm }
+
+// Surface returns the wl_surface of the xdg_surface, or nil if it has
+// been destroyed.
+func (xs *Surface) Surface() *compositor.Surface { return xs.surface }
+
+// Toplevel returns the surface's toplevel, or nil if it isn't a
+// toplevel.
+func (xs *Surface) Toplevel() *Toplevel { return xs.toplevel }
+
+// Popup returns the surface's popup, or nil if it isn't a popup.
+func (xs *Surface) Popup() *Popup { return xs.popup }
+
+// Mapped reports whether the window is mapped, i.e. whether it should
+// be shown.
+func (xs *Surface) Mapped() bool { return xs.mapped }
+
+// Configured reports whether the client has acknowledged a configure
+// sequence and committed the surface since it was created or last
+// unmapped.
+func (xs *Surface) Configured() bool { return xs.configured }
+
+// Geometry returns the window geometry, the part of the surface that
+// makes up the window proper, excluding decorations such as shadows.
+// If the client never set a window geometry, it is the bounding box of
+// the surface and its sub-surfaces.
+func (xs *Surface) Geometry() image.Rectangle {
+	if !xs.geometry.Empty() || xs.surface == nil {
+		return xs.geometry
+	}
+	var bounds image.Rectangle
+	xs.surface.Walk(func(s *compositor.Surface, x, y int32) {
+		w, h := s.Size()
+		bounds = bounds.Union(image.Rect(int(x), int(y), int(x)+int(w), int(y)+int(h)))
+	})
+	return bounds
+}
+
+// scheduleConfigure ends a configure sequence by sending the
+// xdg_surface.configure event. It returns the configure serial.
+func (xs *Surface) scheduleConfigure(c configure) uint32 {
+	c.serial = xs.wm.shell.dsp.NextSerial()
+	xs.configures = append(xs.configures, c)
+	xs.Resource.Configure(c.serial)
+	return c.serial
+}
+
+// sendConfigure sends the current configuration of the role object.
+func (xs *Surface) sendConfigure() {
+	switch {
+	case xs.toplevel != nil:
+		xs.toplevel.Configure(xs.toplevel.pending)
+	case xs.popup != nil:
+		xs.popup.Configure()
+	}
+}
+
+func (xs *Surface) Destroy(obj xdgShell.Surface) {}
+
+func (xs *Surface) handleDestroy() {
+	// Role objects outliving their xdg_surface become inert.
+	if xs.toplevel != nil {
+		xs.toplevel.handleDestroy()
+	}
+	if xs.popup != nil {
+		xs.popup.handleDestroy()
+	}
+	xs.destroyed = true
+	xs.wm.removeSurface(xs)
+	if xs.surface != nil {
+		xs.surface.UnsetRole()
+		xs.surface = nil
+	}
+	if xs.wm.shell.events.DestroySurface != nil {
+		xs.wm.shell.events.DestroySurface(xs)
+	}
+}
+
+func (xs *Surface) handleSurfaceDestroy() {
+	if xs.destroyed {
+		return
+	}
+	xs.unmap()
+	xs.surface = nil
+}
+
+func (xs *Surface) GetToplevel(obj xdgShell.Surface, id xdgShell.Toplevel) xdgShell.ToplevelImplementation {
+	t := &Toplevel{Resource: id, shell: xs.wm.shell}
+	if !xs.setKind(obj, "xdg_toplevel") {
+		return t
+	}
+	t.xs = xs
+	xs.toplevel = t
+	id.OnDestroy(t.handleDestroy)
+	if xs.wm.shell.events.NewToplevel != nil {
+		xs.wm.shell.events.NewToplevel(t)
+	}
+	return t
+}
+
+func (xs *Surface) GetPopup(obj xdgShell.Surface, id xdgShell.Popup, parent xdgShell.Surface, positioner xdgShell.Positioner) xdgShell.PopupImplementation {
+	p := &Popup{Resource: id, shell: xs.wm.shell}
+	dsp := xs.wm.shell.dsp
+	pos, ok := positionerFromResource(positioner)
+	if !ok || !pos.state.Complete() {
+		dsp.Error(xs.wm.Resource, uint32(xdgShell.WmBaseErrorInvalidPositioner),
+			fmt.Sprintf("%s is incomplete", objectString(positioner)))
+		return p
+	}
+	var parentSurface *Surface
+	if parent.Conn() != nil {
+		// Popups without a parent have to be given one by other means,
+		// such as the layer shell. We don't support any.
+		parentSurface, ok = SurfaceFromResource(parent)
+		if !ok || (parentSurface.toplevel == nil && parentSurface.popup == nil) {
+			dsp.Error(obj, uint32(xdgShell.WmBaseErrorInvalidPopupParent),
+				fmt.Sprintf("%s is not a valid popup parent", objectString(parent)))
+			return p
+		}
+	}
+	if !xs.setKind(obj, "xdg_popup") {
+		return p
+	}
+	p.xs = xs
+	p.parent = parentSurface
+	p.positioner = pos.state
+	if parentSurface != nil {
+		parentSurface.addPopup(p)
+	}
+	xs.popup = p
+	id.OnDestroy(p.handleDestroy)
+	if xs.wm.shell.events.NewPopup != nil {
+		xs.wm.shell.events.NewPopup(p)
+	}
+	return p
+}
+
+// setKind checks that the surface can get a role object of the given
+// kind, posting an error if it can't.
+func (xs *Surface) setKind(obj xdgShell.Surface, kind string) bool {
+	dsp := xs.wm.shell.dsp
+	if xs.surface == nil {
+		return false
+	}
+	if xs.toplevel != nil || xs.popup != nil {
+		dsp.Error(obj, uint32(xdgShell.SurfaceErrorAlreadyConstructed),
+			fmt.Sprintf("%s already has a role object", objectString(obj)))
+		return false
+	}
+	if xs.kind != "" && xs.kind != kind {
+		dsp.Error(xs.wm.Resource, uint32(xdgShell.WmBaseErrorRole),
+			fmt.Sprintf("%s has had the %s role before", objectString(obj), xs.kind))
+		return false
+	}
+	xs.kind = kind
+	return true
+}
+
+// addPopup adds p to the popups of the surface's role object.
+func (xs *Surface) addPopup(p *Popup) {
+	switch {
+	case xs.toplevel != nil:
+		xs.toplevel.popups = append(xs.toplevel.popups, p)
+	case xs.popup != nil:
+		xs.popup.popups = append(xs.popup.popups, p)
+	}
+}
+
+func (xs *Surface) SetWindowGeometry(obj xdgShell.Surface, x, y, width, height int32) {
+	if width <= 0 || height <= 0 {
+		xs.wm.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorInvalidSurfaceState),
+			fmt.Sprintf("invalid window geometry size %dx%d", width, height))
+		return
+	}
+	xs.pendingGeometry = image.Rect(int(x), int(y), int(x)+int(width), int(y)+int(height))
+	xs.hasPendingGeometry = true
+}
+
+func (xs *Surface) AckConfigure(obj xdgShell.Surface, serial uint32) {
+	if xs.toplevel == nil && xs.popup == nil {
+		xs.wm.shell.dsp.Error(obj, uint32(xdgShell.SurfaceErrorNotConstructed),
+			fmt.Sprintf("%s has no role object", objectString(obj)))
+		return
+	}
+	for i, c := range xs.configures {
+		if c.serial == serial {
+			// Acknowledging a configure sequence implicitly
+			// acknowledges all earlier ones.
+			xs.acked = c
+			xs.hasAcked = true
+			xs.configures = append(xs.configures[:0], xs.configures[i+1:]...)
+			return
+		}
+	}
+	xs.wm.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorInvalidSurfaceState),
+		fmt.Sprintf("wrong configure serial %d", serial))
+}
+
+func (xs *Surface) Precommit(s *compositor.Surface) bool {
+	dsp := xs.wm.shell.dsp
+	if xs.toplevel == nil && xs.popup == nil {
+		dsp.Error(xs.Resource, uint32(xdgShell.SurfaceErrorNotConstructed),
+			fmt.Sprintf("%s has no role object", objectString(xs.Resource)))
+		return false
+	}
+	if s.Pending().Buffer.Conn() != nil && !xs.configured && !xs.hasAcked {
+		dsp.Error(xs.Resource, uint32(xdgShell.SurfaceErrorUnconfiguredBuffer),
+			fmt.Sprintf("%s has a buffer before it was configured", objectString(xs.Resource)))
+		return false
+	}
+	if xs.toplevel != nil && !xs.toplevel.check() {
+		return false
+	}
+	return true
+}
+
+func (xs *Surface) Commit(s *compositor.Surface) {
+	events := &xs.wm.shell.events
+	if xs.hasPendingGeometry {
+		xs.geometry = xs.pendingGeometry
+		xs.hasPendingGeometry = false
+	}
+	if xs.hasAcked {
+		xs.hasAcked = false
+		xs.configured = true
+		if xs.toplevel != nil {
+			xs.toplevel.current = xs.acked.toplevel
+		}
+		if xs.popup != nil {
+			xs.popup.geometry = xs.acked.popup
+		}
+	}
+	if xs.toplevel != nil {
+		xs.toplevel.commit()
+	}
+
+	hasBuffer := s.Current().Buffer.Conn() != nil
+	switch {
+	case !xs.initialCommitted:
+		xs.initialCommitted = true
+		n := len(xs.configures)
+		if events.InitialCommit != nil {
+			events.InitialCommit(xs)
+		}
+		if len(xs.configures) == n {
+			xs.sendConfigure()
+		}
+	case !hasBuffer && xs.mapped:
+		xs.unmap()
+	case hasBuffer && !xs.mapped && xs.configured:
+		xs.mapped = true
+		if events.Map != nil {
+			events.Map(xs)
+		}
+	}
+	if events.Commit != nil {
+		events.Commit(xs)
+	}
+}
+
+// unmap unmaps the window and resets it to the state right after the
+// creation of its role object.
+func (xs *Surface) unmap() {
+	if xs.popup != nil {
+		// Popups can't outlive their parents' mapping.
+		xs.popup.dismissChildren()
+	}
+	if xs.toplevel != nil {
+		for _, p := range xs.toplevel.popups {
+			p.Dismiss()
+		}
+	}
+	wasMapped := xs.mapped
+	xs.mapped = false
+	xs.configured = false
+	xs.initialCommitted = false
+	xs.configures = xs.configures[:0]
+	xs.hasAcked = false
+	xs.geometry = image.Rectangle{}
+	if wasMapped && xs.wm.shell.events.Unmap != nil {
+		xs.wm.shell.events.Unmap(xs)
+	}
+}
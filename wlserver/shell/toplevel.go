@@ -0,0 +1,370 @@
+package shell
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+	xdgShell "honnef.co/go/wayland/wlserver/protocols/xdg-shell"
+)
+
+var byteOrder binary.ByteOrder
+
+func init() {
+	var x uint32 = 0x01020304
+	if *(*byte)(unsafe.Pointer(&x)) == 0x01 {
+		byteOrder = binary.BigEndian
+	} else {
+		byteOrder = binary.LittleEndian
+	}
+}
+
+// ToplevelState is the state of a toplevel, as configured by the
+// compositor.
+type ToplevelState struct {
+	// Width and Height are the size of the window geometry. If they
+	// are zero, the client decides the size.
+	Width, Height int32
+
+	Maximized   bool
+	Fullscreen  bool
+	Resizing    bool
+	Activated   bool
+	TiledLeft   bool
+	TiledRight  bool
+	TiledTop    bool
+	TiledBottom bool
+}
+
+// encode returns the states array of the xdg_toplevel.configure event.
+func (st ToplevelState) encode() []byte {
+	var states []byte
+	add := func(set bool, s xdgShell.ToplevelState) {
+		if set {
+			var b [4]byte
+			byteOrder.PutUint32(b[:], uint32(s))
+			states = append(states, b[:]...)
+		}
+	}
+	add(st.Maximized, xdgShell.ToplevelStateMaximized)
+	add(st.Fullscreen, xdgShell.ToplevelStateFullscreen)
+	add(st.Resizing, xdgShell.ToplevelStateResizing)
+	add(st.Activated, xdgShell.ToplevelStateActivated)
+	add(st.TiledLeft, xdgShell.ToplevelStateTiledLeft)
+	add(st.TiledRight, xdgShell.ToplevelStateTiledRight)
+	add(st.TiledTop, xdgShell.ToplevelStateTiledTop)
+	add(st.TiledBottom, xdgShell.ToplevelStateTiledBottom)
+	return states
+}
+
+// Size is the size of a window. A zero dimension means that the size
+// isn't limited in that dimension.
+type Size struct {
+	Width, Height int32
+}
+
+// Toplevel is an xdg_toplevel, the role of application windows.
+type Toplevel struct {
+	Resource xdgShell.Toplevel
+
+	shell *Shell
+	// xs is nil once the toplevel has become inert, because it or its
+	// xdg_surface got destroyed
+	xs *Surface
+	// pending is the state of the latest configure sequence, and
+	// current the state that the client has acknowledged and committed
+	pending ToplevelState
+	current ToplevelState
+
+	title  string
+	appID  string
+	parent *Toplevel
+	// children are the toplevels whose parent is this toplevel
+	children []*Toplevel
+	popups   []*Popup
+
+	minSize, maxSize               Size
+	pendingMinSize, pendingMaxSize Size
+}
+
+// Surface returns the toplevel's xdg_surface, or nil if the toplevel
+// has been destroyed.
+func (t *Toplevel) Surface() *Surface { return t.xs }
+
+// Current returns the state that the client has acknowledged and
+// committed.
+func (t *Toplevel) Current() ToplevelState { return t.current }
+
+// Pending returns the state of the latest configure sequence, which the
+// client may not have acknowledged yet.
+func (t *Toplevel) Pending() ToplevelState { return t.pending }
+
+// Title returns the window title.
+func (t *Toplevel) Title() string { return t.title }
+
+// AppID returns the application ID.
+func (t *Toplevel) AppID() string { return t.appID }
+
+// MinSize returns the minimum size of the window geometry.
+func (t *Toplevel) MinSize() Size { return t.minSize }
+
+// MaxSize returns the maximum size of the window geometry.
+func (t *Toplevel) MaxSize() Size { return t.maxSize }
+
+// Parent returns the toplevel that the toplevel is a child of, such as
+// the main window of a dialog, or nil if it has no parent. Unmapped
+// parents are skipped: the toplevel is treated as a child of their
+// nearest mapped ancestor.
+func (t *Toplevel) Parent() *Toplevel {
+	p := t.parent
+	for p != nil && (p.xs == nil || !p.xs.mapped) {
+		p = p.parent
+	}
+	return p
+}
+
+// Configure sends a configure sequence with the given state to the
+// client, which will apply it with a later commit. It returns the
+// configure serial.
+func (t *Toplevel) Configure(st ToplevelState) uint32 {
+	if t.xs == nil {
+		return 0
+	}
+	t.pending = st
+	t.Resource.Configure(st.Width, st.Height, st.encode())
+	return t.xs.scheduleConfigure(configure{toplevel: st})
+}
+
+// SetBounds tells the client the size that its window geometry should
+// fit in, such as the size of the output minus panels. It takes effect
+// with the next call to Configure. Clients that bound xdg_wm_base
+// before version 4 don't get told the bounds.
+func (t *Toplevel) SetBounds(width, height int32) {
+	if t.xs == nil || t.Resource.Version() < 4 {
+		return
+	}
+	t.Resource.ConfigureBounds(width, height)
+}
+
+// Close asks the client to close the window.
+func (t *Toplevel) Close() {
+	if t.xs == nil {
+		return
+	}
+	t.Resource.Close()
+}
+
+// check validates the double-buffered state before it gets
+// committed.
+func (t *Toplevel) check() bool {
+	min, max := t.pendingMinSize, t.pendingMaxSize
+	if (max.Width != 0 && min.Width > max.Width) || (max.Height != 0 && min.Height > max.Height) {
+		t.shell.dsp.Error(t.xs.Resource, uint32(xdgShell.WmBaseErrorInvalidSurfaceState),
+			fmt.Sprintf("minimum size %dx%d exceeds maximum size %dx%d", min.Width, min.Height, max.Width, max.Height))
+		return false
+	}
+	return true
+}
+
+// commit applies the double-buffered state.
+func (t *Toplevel) commit() {
+	t.minSize = t.pendingMinSize
+	t.maxSize = t.pendingMaxSize
+}
+
+func (t *Toplevel) Destroy(obj xdgShell.Toplevel) {}
+
+func (t *Toplevel) handleDestroy() {
+	xs := t.xs
+	if xs == nil {
+		return
+	}
+	xs.unmap()
+	for _, p := range t.popups {
+		p.parent = nil
+	}
+	t.popups = nil
+	// Children of destroyed toplevels become children of their
+	// grandparents.
+	for _, c := range t.children {
+		c.parent = t.parent
+		if t.parent != nil {
+			t.parent.children = append(t.parent.children, c)
+		}
+	}
+	t.children = nil
+	t.setParent(nil)
+	xs.toplevel = nil
+	t.xs = nil
+}
+
+func (t *Toplevel) SetParent(obj xdgShell.Toplevel, parent xdgShell.Toplevel) {
+	if t.xs == nil {
+		return
+	}
+	var p *Toplevel
+	if parent.Conn() != nil {
+		var ok bool
+		p, ok = parent.Implementation().(*Toplevel)
+		if !ok || p.xs == nil {
+			// Inert toplevels are treated like null parents.
+			p = nil
+		}
+	}
+	for anc := p; anc != nil; anc = anc.parent {
+		if anc == t {
+			t.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorInvalidSurfaceState),
+				fmt.Sprintf("%s would be its own ancestor", objectString(obj)))
+			return
+		}
+	}
+	t.setParent(p)
+	if t.shell.events.SetParent != nil {
+		t.shell.events.SetParent(t)
+	}
+}
+
+func (t *Toplevel) setParent(p *Toplevel) {
+	if old := t.parent; old != nil {
+		for i, c := range old.children {
+			if c == t {
+				old.children = append(old.children[:i], old.children[i+1:]...)
+				break
+			}
+		}
+	}
+	t.parent = p
+	if p != nil {
+		p.children = append(p.children, t)
+	}
+}
+
+func (t *Toplevel) SetTitle(obj xdgShell.Toplevel, title string) {
+	if t.xs == nil {
+		return
+	}
+	t.title = title
+	if t.shell.events.SetTitle != nil {
+		t.shell.events.SetTitle(t)
+	}
+}
+
+func (t *Toplevel) SetAppID(obj xdgShell.Toplevel, appId string) {
+	if t.xs == nil {
+		return
+	}
+	t.appID = appId
+	if t.shell.events.SetAppID != nil {
+		t.shell.events.SetAppID(t)
+	}
+}
+
+func (t *Toplevel) ShowWindowMenu(obj xdgShell.Toplevel, seat wayland.Seat, serial uint32, x, y int32) {
+	if t.xs == nil || !t.xs.configured {
+		return
+	}
+	if t.shell.events.RequestWindowMenu != nil {
+		t.shell.events.RequestWindowMenu(t, seat, serial, x, y)
+	}
+}
+
+func (t *Toplevel) Move(obj xdgShell.Toplevel, seat wayland.Seat, serial uint32) {
+	if t.xs == nil || !t.xs.configured {
+		return
+	}
+	if t.shell.events.RequestMove != nil {
+		t.shell.events.RequestMove(t, seat, serial)
+	}
+}
+
+func (t *Toplevel) Resize(obj xdgShell.Toplevel, seat wayland.Seat, serial uint32, edges xdgShell.ToplevelResizeEdge) {
+	switch edges {
+	case xdgShell.ToplevelResizeEdgeNone,
+		xdgShell.ToplevelResizeEdgeTop,
+		xdgShell.ToplevelResizeEdgeBottom,
+		xdgShell.ToplevelResizeEdgeLeft,
+		xdgShell.ToplevelResizeEdgeTopLeft,
+		xdgShell.ToplevelResizeEdgeBottomLeft,
+		xdgShell.ToplevelResizeEdgeRight,
+		xdgShell.ToplevelResizeEdgeTopRight,
+		xdgShell.ToplevelResizeEdgeBottomRight:
+	default:
+		t.shell.dsp.Error(obj, uint32(xdgShell.ToplevelErrorInvalidResizeEdge),
+			fmt.Sprintf("invalid resize edge %d", edges))
+		return
+	}
+	if t.xs == nil || !t.xs.configured {
+		return
+	}
+	if t.shell.events.RequestResize != nil {
+		t.shell.events.RequestResize(t, seat, serial, edges)
+	}
+}
+
+func (t *Toplevel) SetMaxSize(obj xdgShell.Toplevel, width, height int32) {
+	if !t.checkSize(obj, width, height) {
+		return
+	}
+	t.pendingMaxSize = Size{width, height}
+}
+
+func (t *Toplevel) SetMinSize(obj xdgShell.Toplevel, width, height int32) {
+	if !t.checkSize(obj, width, height) {
+		return
+	}
+	t.pendingMinSize = Size{width, height}
+}
+
+func (t *Toplevel) checkSize(obj xdgShell.Toplevel, width, height int32) bool {
+	if width < 0 || height < 0 {
+		t.shell.dsp.Error(obj, uint32(xdgShell.WmBaseErrorInvalidSurfaceState),
+			fmt.Sprintf("invalid size %dx%d", width, height))
+		return false
+	}
+	return true
+}
+
+func (t *Toplevel) SetMaximized(obj xdgShell.Toplevel)   { t.requestMaximize(true) }
+func (t *Toplevel) UnsetMaximized(obj xdgShell.Toplevel) { t.requestMaximize(false) }
+
+func (t *Toplevel) requestMaximize(maximized bool) {
+	if t.xs == nil {
+		return
+	}
+	if t.shell.events.RequestMaximize != nil {
+		t.shell.events.RequestMaximize(t, maximized)
+	} else if t.xs.initialCommitted {
+		// The protocol requires a configure in response, even if the
+		// state doesn't change.
+		t.Configure(t.pending)
+	}
+}
+
+func (t *Toplevel) SetFullscreen(obj xdgShell.Toplevel, output wayland.Output) {
+	t.requestFullscreen(true, output)
+}
+
+func (t *Toplevel) UnsetFullscreen(obj xdgShell.Toplevel) {
+	t.requestFullscreen(false, wayland.Output{})
+}
+
+func (t *Toplevel) requestFullscreen(fullscreen bool, output wayland.Output) {
+	if t.xs == nil {
+		return
+	}
+	if t.shell.events.RequestFullscreen != nil {
+		t.shell.events.RequestFullscreen(t, fullscreen, output)
+	} else if t.xs.initialCommitted {
+		t.Configure(t.pending)
+	}
+}
+
+func (t *Toplevel) SetMinimized(obj xdgShell.Toplevel) {
+	if t.xs == nil {
+		return
+	}
+	if t.shell.events.RequestMinimize != nil {
+		t.shell.events.RequestMinimize(t)
+	}
+}
@@ -0,0 +1,354 @@
+// Package shm implements the wl_shm global for compositors.
+//
+// Clients share memory with the compositor by passing file
+// descriptors to wl_shm.create_pool. This package maps these pools,
+// validates the buffers that clients create from them and provides
+// access to their pixels.
+//
+// Clients are free to truncate the files backing their pools at any
+// time, which causes accesses to the mapped memory to fault. Use
+// Buffer.Access to access buffer contents safely.
+package shm
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"golang.org/x/sys/unix"
+	"honnef.co/go/wayland/wlserver"
+	"honnef.co/go/wayland/wlserver/protocols/wayland"
+)
+
+// ErrFault is returned by Buffer.Access when the client's memory
+// couldn't be accessed, usually because the client truncated the
+// file backing the pool.
+var ErrFault = errors.New("error accessing SHM buffer")
+
+// ErrDestroyed is returned by Buffer.Access when the buffer has no
+// memory, because it has been destroyed or failed to be created.
+var ErrDestroyed = errors.New("SHM buffer has been destroyed")
+
+// bytesPerPixel maps formats to the number of bytes used by a single
+// pixel. Only formats listed here can be accessed via Image.
+var bytesPerPixel = map[wayland.ShmFormat]int32{
+	wayland.ShmFormatArgb8888: 4,
+	wayland.ShmFormatXrgb8888: 4,
+	wayland.ShmFormatAbgr8888: 4,
+	wayland.ShmFormatXbgr8888: 4,
+	wayland.ShmFormatRgb565:   2,
+}
+
+// Shm is an implementation of the wl_shm global.
+type Shm struct {
+	dsp     *wlserver.Display
+	formats []wayland.ShmFormat
+}
+
+// AddGlobal adds a wl_shm global to the display. The argb8888 and
+// xrgb8888 formats, which must be supported by all compositors, are
+// always advertised, in addition to the provided formats.
+func AddGlobal(dsp *wlserver.Display, formats ...wayland.ShmFormat) *Shm {
+	shm := &Shm{
+		dsp:     dsp,
+		formats: []wayland.ShmFormat{wayland.ShmFormatArgb8888, wayland.ShmFormatXrgb8888},
+	}
+	for _, f := range formats {
+		if !shm.supports(f) {
+			shm.formats = append(shm.formats, f)
+		}
+	}
+	wayland.AddShmGlobal(dsp, 1, func(res wayland.Shm) wayland.ShmImplementation {
+		for _, f := range shm.formats {
+			res.Format(f)
+		}
+		return shm
+	})
+	return shm
+}
+
+// Formats returns the formats advertised by the global.
+func (shm *Shm) Formats() []wayland.ShmFormat {
+	return shm.formats
+}
+
+func (shm *Shm) supports(format wayland.ShmFormat) bool {
+	for _, f := range shm.formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func (shm *Shm) CreatePool(obj wayland.Shm, id wayland.ShmPool, fd uintptr, size int32) wayland.ShmPoolImplementation {
+	if size <= 0 {
+		unix.Close(int(fd))
+		shm.dsp.Error(obj, uint32(wayland.ShmErrorInvalidStride), fmt.Sprintf("invalid size (%d)", size))
+		return &Pool{shm: shm, fd: -1}
+	}
+
+	data, err := unix.Mmap(int(fd), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		unix.Close(int(fd))
+		shm.dsp.Error(obj, uint32(wayland.ShmErrorInvalidFd), fmt.Sprintf("failed mmap fd %d: %s", fd, err))
+		return &Pool{shm: shm, fd: -1}
+	}
+
+	pool := &Pool{
+		shm:  shm,
+		fd:   int(fd),
+		data: data,
+		refs: 1,
+	}
+	// Clients that disconnect don't destroy their objects, so don't
+	// rely on the destructor request.
+	id.OnDestroy(pool.unref)
+	return pool
+}
+
+// Pool is a memory pool shared between a client and the compositor.
+//
+// A pool stays mapped until both the wl_shm_pool and all the buffers
+// created from it have been destroyed.
+type Pool struct {
+	shm  *Shm
+	fd   int
+	data []byte
+	// refs counts the wl_shm_pool object as well as all of the pool's
+	// buffers.
+	refs int
+}
+
+func (pool *Pool) unref() {
+	pool.refs--
+	if pool.refs > 0 {
+		return
+	}
+	if pool.data != nil {
+		unix.Munmap(pool.data)
+		pool.data = nil
+	}
+	if pool.fd != -1 {
+		unix.Close(pool.fd)
+		pool.fd = -1
+	}
+}
+
+// Size returns the size of the pool in bytes.
+func (pool *Pool) Size() int {
+	return len(pool.data)
+}
+
+func (pool *Pool) CreateBuffer(obj wayland.ShmPool, id wayland.Buffer, offset int32, width int32, height int32, stride int32, format wayland.ShmFormat) wayland.BufferImplementation {
+	if !pool.shm.supports(format) {
+		pool.shm.dsp.Error(obj, uint32(wayland.ShmErrorInvalidFormat), fmt.Sprintf("invalid format 0x%x", uint32(format)))
+		return &Buffer{Resource: id}
+	}
+
+	minStride := width
+	if bpp, ok := bytesPerPixel[format]; ok && width <= math.MaxInt32/bpp {
+		minStride = width * bpp
+	}
+	if offset < 0 || width <= 0 || height <= 0 || stride < minStride ||
+		math.MaxInt32/stride <= height || int64(offset) > int64(len(pool.data))-int64(stride)*int64(height) {
+		pool.shm.dsp.Error(obj, uint32(wayland.ShmErrorInvalidStride),
+			fmt.Sprintf("invalid width, height or stride (%dx%d, %d)", width, height, stride))
+		return &Buffer{Resource: id}
+	}
+
+	pool.refs++
+	buf := &Buffer{
+		Resource: id,
+		pool:     pool,
+		Offset:   offset,
+		Width:    width,
+		Height:   height,
+		Stride:   stride,
+		Format:   format,
+	}
+	id.OnDestroy(func() {
+		buf.pool = nil
+		pool.unref()
+	})
+	return buf
+}
+
+func (pool *Pool) Destroy(obj wayland.ShmPool) {}
+
+func (pool *Pool) Resize(obj wayland.ShmPool, size int32) {
+	if int(size) < len(pool.data) {
+		pool.shm.dsp.Error(obj, uint32(wayland.ShmErrorInvalidStride), "shrinking pool invalid")
+		return
+	}
+	if int(size) == len(pool.data) {
+		return
+	}
+
+	data, err := unix.Mmap(pool.fd, 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		pool.shm.dsp.Error(obj, uint32(wayland.ShmErrorInvalidFd), fmt.Sprintf("failed mmap fd %d: %s", pool.fd, err))
+		return
+	}
+	// Buffers don't hold on to the old mapping, they always go
+	// through the pool.
+	unix.Munmap(pool.data)
+	pool.data = data
+}
+
+// Buffer is a wl_buffer backed by shared memory.
+type Buffer struct {
+	Resource wayland.Buffer
+
+	Offset int32
+	Width  int32
+	Height int32
+	Stride int32
+	Format wayland.ShmFormat
+
+	pool *Pool
+}
+
+// BufferFromResource returns the shm buffer that implements res. It
+// returns false if res isn't an shm buffer, for example because it
+// was created by a different buffer factory.
+func BufferFromResource(res wayland.Buffer) (*Buffer, bool) {
+	buf, ok := res.Implementation().(*Buffer)
+	if !ok || buf.pool == nil {
+		return nil, false
+	}
+	return buf, true
+}
+
+// Size returns the buffer's width and height in pixels.
+func (buf *Buffer) Size() (width, height int32) {
+	return buf.Width, buf.Height
+}
+
+func (buf *Buffer) Destroy(obj wayland.Buffer) {}
+
+// Data returns the buffer's memory. The returned slice is only valid
+// until the client's next request has been processed.
+//
+// Accessing the memory may fault if the client truncated the backing
+// file. Use Access to guard against this.
+func (buf *Buffer) Data() []byte {
+	if buf.pool == nil {
+		return nil
+	}
+	return buf.pool.data[buf.Offset : buf.Offset+buf.Stride*buf.Height]
+}
+
+// Access calls fn with an image providing access to the buffer's
+// pixels. If accessing the memory faults, Access recovers, posts an
+// invalid_fd error to the client and returns ErrFault. If the buffer
+// has been destroyed, fn isn't called and Access returns ErrDestroyed.
+//
+// The image must not be retained after fn returns.
+func (buf *Buffer) Access(fn func(img *Image)) (err error) {
+	if buf.pool == nil {
+		return ErrDestroyed
+	}
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			if rerr, ok := r.(runtime.Error); ok {
+				if _, ok := rerr.(interface{ Addr() uintptr }); ok {
+					buf.pool.shm.dsp.Error(buf.Resource, uint32(wayland.ShmErrorInvalidFd), ErrFault.Error())
+					err = ErrFault
+					return
+				}
+			}
+			panic(r)
+		}
+	}()
+	fn(buf.Image())
+	return nil
+}
+
+// Image returns an image that provides access to the buffer's pixels.
+// The same caveats as for Data apply. The image of a destroyed buffer
+// is empty.
+func (buf *Buffer) Image() *Image {
+	img := &Image{
+		Pix:    buf.Data(),
+		Stride: int(buf.Stride),
+		Format: buf.Format,
+	}
+	if img.Pix != nil {
+		img.Rect = image.Rect(0, 0, int(buf.Width), int(buf.Height))
+	}
+	return img
+}
+
+// Image is an image.Image backed by shm memory. Pixels are interpreted
+// according to Format; formats whose layout isn't known to this
+// package read as transparent.
+//
+// As required by wl_shm, pixels with alpha are premultiplied.
+type Image struct {
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+	Format wayland.ShmFormat
+}
+
+func (img *Image) ColorModel() color.Model { return color.RGBAModel }
+func (img *Image) Bounds() image.Rectangle { return img.Rect }
+
+func (img *Image) At(x, y int) color.Color {
+	return img.RGBAAt(x, y)
+}
+
+// RGBAAt returns the color of the pixel at (x, y).
+func (img *Image) RGBAAt(x, y int) color.RGBA {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.RGBA{}
+	}
+	bpp, ok := bytesPerPixel[img.Format]
+	if !ok {
+		return color.RGBA{}
+	}
+	i := (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*int(bpp)
+	p := img.Pix[i : i+int(bpp)]
+	// All formats are little endian
+	switch img.Format {
+	case wayland.ShmFormatArgb8888:
+		return color.RGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+	case wayland.ShmFormatXrgb8888:
+		return color.RGBA{R: p[2], G: p[1], B: p[0], A: 0xFF}
+	case wayland.ShmFormatAbgr8888:
+		return color.RGBA{R: p[0], G: p[1], B: p[2], A: p[3]}
+	case wayland.ShmFormatXbgr8888:
+		return color.RGBA{R: p[0], G: p[1], B: p[2], A: 0xFF}
+	case wayland.ShmFormatRgb565:
+		v := uint16(p[0]) | uint16(p[1])<<8
+		r := uint8(v >> 11)
+		g := uint8(v>>5) & 0x3F
+		b := uint8(v) & 0x1F
+		return color.RGBA{R: r<<3 | r>>2, G: g<<2 | g>>4, B: b<<3 | b>>2, A: 0xFF}
+	default:
+		return color.RGBA{}
+	}
+}
+
+// SubImage returns an image representing the portion of img visible
+// through r.
+func (img *Image) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
+		return &Image{Format: img.Format}
+	}
+	bpp := int(bytesPerPixel[img.Format])
+	i := (r.Min.Y-img.Rect.Min.Y)*img.Stride + (r.Min.X-img.Rect.Min.X)*bpp
+	return &Image{
+		Pix:    img.Pix[i:],
+		Stride: img.Stride,
+		Rect:   r,
+		Format: img.Format,
+	}
+}
@@ -0,0 +1,23 @@
+package shm
+
+import "testing"
+
+func TestAccessDestroyed(t *testing.T) {
+	// Buffers without a pool are what clients get after a failed
+	// create_buffer, and what remains after the buffer was destroyed.
+	buf := &Buffer{Width: 4, Height: 4, Stride: 16}
+	called := false
+	if err := buf.Access(func(*Image) { called = true }); err != ErrDestroyed {
+		t.Errorf("Access = %v, want %v", err, ErrDestroyed)
+	}
+	if called {
+		t.Error("Access called fn for a destroyed buffer")
+	}
+	img := buf.Image()
+	if !img.Bounds().Empty() {
+		t.Errorf("image of destroyed buffer has bounds %v, want empty", img.Bounds())
+	}
+	if c := img.RGBAAt(1, 1); c.A != 0 {
+		t.Errorf("RGBAAt(1, 1) = %v, want transparent", c)
+	}
+}
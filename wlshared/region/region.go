@@ -0,0 +1,323 @@
+// Package region implements regions, sets of pixels described by
+// rectangles, such as the opaque and input regions of surfaces or the
+// damaged parts of buffers.
+//
+// Like pixman's regions, a Region is stored as a list of disjoint
+// rectangles in y-x banded order: the rectangles are grouped into
+// horizontal bands, all rectangles in a band have the same vertical
+// extent, bands are sorted from top to bottom, and the rectangles in a
+// band are sorted from left to right and don't touch. Adjacent bands
+// with identical rectangles are merged. This makes the representation
+// of a region unique, and allows for efficient set operations.
+package region
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Region is a set of pixels. The zero value is the empty region.
+//
+// Regions are values: operations return new regions and never modify
+// their operands.
+type Region struct {
+	rects []image.Rectangle
+}
+
+// Rect returns the region covering r.
+func Rect(r image.Rectangle) Region {
+	if r.Empty() {
+		return Region{}
+	}
+	return Region{rects: []image.Rectangle{r.Canon()}}
+}
+
+// New returns the union of rects, which may overlap.
+func New(rects ...image.Rectangle) Region {
+	var out Region
+	for _, r := range rects {
+		out = out.UnionRect(r)
+	}
+	return out
+}
+
+// Rects returns the disjoint rectangles that make up the region, in
+// banded order. The slice must not be modified.
+func (r Region) Rects() []image.Rectangle { return r.rects }
+
+// Empty reports whether the region contains no pixels.
+func (r Region) Empty() bool { return len(r.rects) == 0 }
+
+// Bounds returns the smallest rectangle containing the region.
+func (r Region) Bounds() image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	b := image.Rectangle{
+		Min: image.Pt(math.MaxInt, r.rects[0].Min.Y),
+		Max: image.Pt(math.MinInt, r.rects[len(r.rects)-1].Max.Y),
+	}
+	for _, rect := range r.rects {
+		if rect.Min.X < b.Min.X {
+			b.Min.X = rect.Min.X
+		}
+		if rect.Max.X > b.Max.X {
+			b.Max.X = rect.Max.X
+		}
+	}
+	return b
+}
+
+// Equal reports whether r and o contain the same pixels.
+func (r Region) Equal(o Region) bool {
+	if len(r.rects) != len(o.rects) {
+		return false
+	}
+	for i := range r.rects {
+		if r.rects[i] != o.rects[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether p is in the region.
+func (r Region) Contains(p image.Point) bool {
+	for _, rect := range r.rects {
+		if rect.Min.Y > p.Y {
+			// bands are sorted, the remaining rectangles are all
+			// below p
+			break
+		}
+		if p.In(rect) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsRect reports whether all of rect is in the region. Empty
+// rectangles are contained in every region.
+func (r Region) ContainsRect(rect image.Rectangle) bool {
+	return Rect(rect).Subtract(r).Empty()
+}
+
+// Overlaps reports whether any part of rect is in the region.
+func (r Region) Overlaps(rect image.Rectangle) bool {
+	for _, rr := range r.rects {
+		if rr.Overlaps(rect) {
+			return true
+		}
+	}
+	return false
+}
+
+// Union returns the pixels that are in r or in o.
+func (r Region) Union(o Region) Region {
+	if r.Empty() {
+		return o
+	}
+	if o.Empty() {
+		return r
+	}
+	return combine(r, o, func(a, b bool) bool { return a || b })
+}
+
+// UnionRect returns the union of r and rect.
+func (r Region) UnionRect(rect image.Rectangle) Region {
+	return r.Union(Rect(rect))
+}
+
+// Subtract returns the pixels that are in r but not in o.
+func (r Region) Subtract(o Region) Region {
+	if r.Empty() || o.Empty() {
+		return r
+	}
+	return combine(r, o, func(a, b bool) bool { return a && !b })
+}
+
+// SubtractRect returns the pixels of r that aren't in rect.
+func (r Region) SubtractRect(rect image.Rectangle) Region {
+	return r.Subtract(Rect(rect))
+}
+
+// Intersect returns the pixels that are in both r and o.
+func (r Region) Intersect(o Region) Region {
+	if r.Empty() || o.Empty() {
+		return Region{}
+	}
+	return combine(r, o, func(a, b bool) bool { return a && b })
+}
+
+// IntersectRect returns the pixels of r that are in rect.
+func (r Region) IntersectRect(rect image.Rectangle) Region {
+	return r.Intersect(Rect(rect))
+}
+
+// Translate returns the region moved by dx, dy.
+func (r Region) Translate(dx, dy int) Region {
+	if r.Empty() || (dx == 0 && dy == 0) {
+		return r
+	}
+	d := image.Pt(dx, dy)
+	out := make([]image.Rectangle, len(r.rects))
+	for i, rect := range r.rects {
+		out[i] = rect.Add(d)
+	}
+	return Region{rects: out}
+}
+
+// Scale returns the region scaled by factor. Rectangles are extended
+// to whole pixels, so that scaling down never loses pixels.
+func (r Region) Scale(factor float64) Region {
+	if r.Empty() || factor == 1 {
+		return r
+	}
+	if factor <= 0 {
+		return Region{}
+	}
+	if f := int(factor); float64(f) == factor {
+		// Scaling by an integer preserves the banding.
+		out := make([]image.Rectangle, len(r.rects))
+		for i, rect := range r.rects {
+			out[i] = image.Rectangle{Min: rect.Min.Mul(f), Max: rect.Max.Mul(f)}
+		}
+		return Region{rects: out}
+	}
+	rects := make([]image.Rectangle, len(r.rects))
+	for i, rect := range r.rects {
+		rects[i] = image.Rect(
+			int(math.Floor(float64(rect.Min.X)*factor)),
+			int(math.Floor(float64(rect.Min.Y)*factor)),
+			int(math.Ceil(float64(rect.Max.X)*factor)),
+			int(math.Ceil(float64(rect.Max.Y)*factor)),
+		)
+	}
+	return New(rects...)
+}
+
+// band returns the rectangles of the band starting at index i.
+func band(rects []image.Rectangle, i int) []image.Rectangle {
+	j := i + 1
+	for j < len(rects) && rects[j].Min.Y == rects[i].Min.Y {
+		j++
+	}
+	return rects[i:j]
+}
+
+// combine computes a set operation on two regions. op reports whether
+// a pixel is in the result, given whether it is in a and in b.
+func combine(a, b Region, op func(inA, inB bool) bool) Region {
+	// Split the plane at every band edge of either region. Within each
+	// of the resulting horizontal strips, both regions consist of
+	// fixed sets of spans.
+	ys := make([]int, 0, 2*(len(a.rects)+len(b.rects)))
+	for _, rs := range [][]image.Rectangle{a.rects, b.rects} {
+		for i := 0; i < len(rs); {
+			bd := band(rs, i)
+			ys = append(ys, bd[0].Min.Y, bd[0].Max.Y)
+			i += len(bd)
+		}
+	}
+	sort.Ints(ys)
+
+	var out []image.Rectangle
+	// prev is the index of the band of the output that was added last,
+	// or -1 if the previous strip was empty
+	prev := -1
+	ia, ib := 0, 0
+	for k := 0; k+1 < len(ys); k++ {
+		y0, y1 := ys[k], ys[k+1]
+		if y0 == y1 {
+			continue
+		}
+		for ia < len(a.rects) && a.rects[ia].Max.Y <= y0 {
+			ia++
+		}
+		for ib < len(b.rects) && b.rects[ib].Max.Y <= y0 {
+			ib++
+		}
+		var sa, sb []image.Rectangle
+		if ia < len(a.rects) && a.rects[ia].Min.Y <= y0 {
+			sa = band(a.rects, ia)
+		}
+		if ib < len(b.rects) && b.rects[ib].Min.Y <= y0 {
+			sb = band(b.rects, ib)
+		}
+
+		n := len(out)
+		out = combineSpans(out, sa, sb, y0, y1, op)
+		if len(out) == n {
+			prev = -1
+			continue
+		}
+		if prev != -1 && out[prev].Max.Y == y0 && sameSpans(out[prev:n], out[n:]) {
+			// Coalesce with the previous band.
+			for i := prev; i < n; i++ {
+				out[i].Max.Y = y1
+			}
+			out = out[:n]
+			continue
+		}
+		prev = n
+	}
+	return Region{rects: out}
+}
+
+// combineSpans appends to out the rectangles from y0 to y1 covering
+// the spans that result from applying op to the spans of the bands a
+// and b.
+func combineSpans(out, a, b []image.Rectangle, y0, y1 int, op func(bool, bool) bool) []image.Rectangle {
+	xs := make([]int, 0, 2*(len(a)+len(b)))
+	for _, r := range a {
+		xs = append(xs, r.Min.X, r.Max.X)
+	}
+	for _, r := range b {
+		xs = append(xs, r.Min.X, r.Max.X)
+	}
+	sort.Ints(xs)
+
+	start := 0
+	open := false
+	ia, ib := 0, 0
+	for k := 0; k+1 < len(xs); k++ {
+		x0, x1 := xs[k], xs[k+1]
+		if x0 == x1 {
+			continue
+		}
+		for ia < len(a) && a[ia].Max.X <= x0 {
+			ia++
+		}
+		for ib < len(b) && b[ib].Max.X <= x0 {
+			ib++
+		}
+		inA := ia < len(a) && a[ia].Min.X <= x0
+		inB := ib < len(b) && b[ib].Min.X <= x0
+		in := op(inA, inB)
+		if in && !open {
+			start = x0
+			open = true
+		} else if !in && open {
+			out = append(out, image.Rect(start, y0, x0, y1))
+			open = false
+		}
+	}
+	if open {
+		out = append(out, image.Rect(start, y0, xs[len(xs)-1], y1))
+	}
+	return out
+}
+
+// sameSpans reports whether two bands have the same horizontal spans.
+func sameSpans(a, b []image.Rectangle) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Min.X != b[i].Min.X || a[i].Max.X != b[i].Max.X {
+			return false
+		}
+	}
+	return true
+}
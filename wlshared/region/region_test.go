@@ -0,0 +1,203 @@
+package region
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func rect(x0, y0, x1, y1 int) image.Rectangle { return image.Rect(x0, y0, x1, y1) }
+
+// checkBanded verifies the invariants of the banded representation.
+func checkBanded(t *testing.T, r Region) {
+	t.Helper()
+	rects := r.Rects()
+	for i, rc := range rects {
+		if rc.Empty() {
+			t.Errorf("rectangle %d is empty: %v", i, rc)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := rects[i-1]
+		switch {
+		case rc.Min.Y == prev.Min.Y:
+			if rc.Max.Y != prev.Max.Y {
+				t.Errorf("rectangles %d and %d are in the same band but have different heights: %v, %v", i-1, i, prev, rc)
+			}
+			if rc.Min.X <= prev.Max.X {
+				t.Errorf("rectangles %d and %d overlap, touch or are out of order: %v, %v", i-1, i, prev, rc)
+			}
+		case rc.Min.Y < prev.Max.Y:
+			t.Errorf("bands overlap or are out of order: %v, %v", prev, rc)
+		}
+	}
+	// Adjacent bands must not have identical spans.
+	var bands [][]image.Rectangle
+	for i := 0; i < len(rects); {
+		bd := band(rects, i)
+		bands = append(bands, bd)
+		i += len(bd)
+	}
+	for i := 1; i < len(bands); i++ {
+		if bands[i-1][0].Max.Y == bands[i][0].Min.Y && sameSpans(bands[i-1], bands[i]) {
+			t.Errorf("adjacent bands weren't merged: %v, %v", bands[i-1], bands[i])
+		}
+	}
+}
+
+func checkRects(t *testing.T, name string, got Region, want []image.Rectangle) {
+	t.Helper()
+	checkBanded(t, got)
+	if len(want) == 0 {
+		if !got.Empty() {
+			t.Errorf("%s = %v, want empty region", name, got.Rects())
+		}
+		return
+	}
+	if !reflect.DeepEqual(got.Rects(), want) {
+		t.Errorf("%s = %v, want %v", name, got.Rects(), want)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Region
+		want []image.Rectangle
+	}{
+		{"empty", Region{}, Region{}, nil},
+		{"empty operand", Rect(rect(0, 0, 10, 10)), Region{}, []image.Rectangle{rect(0, 0, 10, 10)}},
+		{"disjoint in band", Rect(rect(20, 0, 30, 10)), Rect(rect(0, 0, 10, 10)),
+			[]image.Rectangle{rect(0, 0, 10, 10), rect(20, 0, 30, 10)}},
+		{"touching horizontally", Rect(rect(0, 0, 10, 10)), Rect(rect(10, 0, 20, 10)),
+			[]image.Rectangle{rect(0, 0, 20, 10)}},
+		{"touching vertically", Rect(rect(0, 0, 10, 10)), Rect(rect(0, 10, 10, 20)),
+			[]image.Rectangle{rect(0, 0, 10, 20)}},
+		{"overlapping", Rect(rect(0, 0, 10, 10)), Rect(rect(5, 5, 15, 15)),
+			[]image.Rectangle{rect(0, 0, 10, 5), rect(0, 5, 15, 10), rect(5, 10, 15, 15)}},
+		{"contained", Rect(rect(0, 0, 10, 10)), Rect(rect(2, 2, 8, 8)),
+			[]image.Rectangle{rect(0, 0, 10, 10)}},
+		{"disjoint bands", Rect(rect(0, 20, 10, 30)), Rect(rect(0, 0, 10, 10)),
+			[]image.Rectangle{rect(0, 0, 10, 10), rect(0, 20, 10, 30)}},
+	}
+	for _, tt := range tests {
+		checkRects(t, tt.name, tt.a.Union(tt.b), tt.want)
+		checkRects(t, tt.name+" (commuted)", tt.b.Union(tt.a), tt.want)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Region
+		want []image.Rectangle
+	}{
+		{"from empty", Region{}, Rect(rect(0, 0, 10, 10)), nil},
+		{"empty", Rect(rect(0, 0, 10, 10)), Region{}, []image.Rectangle{rect(0, 0, 10, 10)}},
+		{"everything", Rect(rect(0, 0, 10, 10)), Rect(rect(-5, -5, 15, 15)), nil},
+		{"disjoint", Rect(rect(0, 0, 10, 10)), Rect(rect(10, 0, 20, 10)), []image.Rectangle{rect(0, 0, 10, 10)}},
+		{"hole", Rect(rect(0, 0, 30, 30)), Rect(rect(10, 10, 20, 20)),
+			[]image.Rectangle{rect(0, 0, 30, 10), rect(0, 10, 10, 20), rect(20, 10, 30, 20), rect(0, 20, 30, 30)}},
+		{"corner", Rect(rect(0, 0, 10, 10)), Rect(rect(5, 5, 15, 15)),
+			[]image.Rectangle{rect(0, 0, 10, 5), rect(0, 5, 5, 10)}},
+		{"vertical strip", Rect(rect(0, 0, 30, 10)), Rect(rect(10, -5, 20, 15)),
+			[]image.Rectangle{rect(0, 0, 10, 10), rect(20, 0, 30, 10)}},
+	}
+	for _, tt := range tests {
+		checkRects(t, tt.name, tt.a.Subtract(tt.b), tt.want)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Region
+		want []image.Rectangle
+	}{
+		{"empty", Rect(rect(0, 0, 10, 10)), Region{}, nil},
+		{"overlapping", Rect(rect(0, 0, 10, 10)), Rect(rect(5, 5, 15, 15)), []image.Rectangle{rect(5, 5, 10, 10)}},
+		{"disjoint", Rect(rect(0, 0, 10, 10)), Rect(rect(20, 20, 30, 30)), nil},
+		{"touching", Rect(rect(0, 0, 10, 10)), Rect(rect(10, 0, 20, 10)), nil},
+		{"several rectangles", New(rect(0, 0, 10, 10), rect(20, 0, 30, 10)), Rect(rect(5, 0, 25, 10)),
+			[]image.Rectangle{rect(5, 0, 10, 10), rect(20, 0, 25, 10)}},
+		{"merges bands", New(rect(0, 0, 10, 10), rect(0, 10, 20, 20)), Rect(rect(0, 0, 10, 20)),
+			[]image.Rectangle{rect(0, 0, 10, 20)}},
+	}
+	for _, tt := range tests {
+		checkRects(t, tt.name, tt.a.Intersect(tt.b), tt.want)
+		checkRects(t, tt.name+" (commuted)", tt.b.Intersect(tt.a), tt.want)
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name   string
+		r      Region
+		factor float64
+		want   []image.Rectangle
+	}{
+		{"identity", Rect(rect(1, 1, 3, 3)), 1, []image.Rectangle{rect(1, 1, 3, 3)}},
+		{"integer", New(rect(0, 0, 1, 1), rect(2, 0, 3, 1)), 2, []image.Rectangle{rect(0, 0, 2, 2), rect(4, 0, 6, 2)}},
+		{"down", Rect(rect(1, 1, 3, 3)), 0.5, []image.Rectangle{rect(0, 0, 2, 2)}},
+		{"fraction merges", New(rect(0, 0, 1, 1), rect(1, 1, 2, 2)), 1.5,
+			[]image.Rectangle{rect(0, 0, 2, 1), rect(0, 1, 3, 2), rect(1, 2, 3, 3)}},
+		{"zero", Rect(rect(1, 1, 3, 3)), 0, nil},
+		{"negative", Rect(rect(1, 1, 3, 3)), -1, nil},
+	}
+	for _, tt := range tests {
+		checkRects(t, tt.name, tt.r.Scale(tt.factor), tt.want)
+	}
+}
+
+func TestBanding(t *testing.T) {
+	// The same set of pixels has the same representation, regardless
+	// of how it was constructed.
+	rects := []image.Rectangle{
+		rect(0, 0, 10, 10),
+		rect(5, 5, 15, 15),
+		rect(20, 0, 25, 20),
+		rect(-5, 12, 0, 14),
+		rect(0, 30, 10, 40),
+	}
+	want := New(rects...)
+	checkBanded(t, want)
+	for i := range rects {
+		// rotate the order of the rectangles
+		order := append(append([]image.Rectangle(nil), rects[i:]...), rects[:i]...)
+		got := New(order...)
+		if !got.Equal(want) || !reflect.DeepEqual(got.Rects(), want.Rects()) {
+			t.Errorf("New(%v) = %v, want %v", order, got.Rects(), want.Rects())
+		}
+	}
+
+	// Removing and re-adding a part restores the original bands.
+	full := Rect(rect(0, 0, 30, 30))
+	hole := rect(10, 10, 20, 20)
+	got := full.SubtractRect(hole).UnionRect(hole)
+	checkRects(t, "subtract and union", got, []image.Rectangle{rect(0, 0, 30, 30)})
+
+	// Empty and inverted rectangles don't add bands.
+	checkRects(t, "empty rectangles", New(rect(0, 0, 0, 10), rect(5, 5, 5, 5)), nil)
+	checkRects(t, "inverted rectangle", New(image.Rectangle{Min: image.Pt(10, 10), Max: image.Pt(0, 0)}), nil)
+}
+
+func TestContains(t *testing.T) {
+	r := New(rect(0, 0, 10, 10), rect(20, 0, 30, 10))
+	tests := []struct {
+		p    image.Point
+		want bool
+	}{
+		{image.Pt(0, 0), true},
+		{image.Pt(9, 9), true},
+		{image.Pt(10, 5), false},
+		{image.Pt(25, 5), true},
+		{image.Pt(5, 10), false},
+		{image.Pt(-1, 0), false},
+	}
+	for _, tt := range tests {
+		if got := r.Contains(tt.p); got != tt.want {
+			t.Errorf("Contains(%v) = %t, want %t", tt.p, got, tt.want)
+		}
+	}
+}
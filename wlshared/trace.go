@@ -0,0 +1,228 @@
+package wlshared
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"honnef.co/go/wayland/wlproto"
+)
+
+// TraceMessage describes a message that was sent or received, for
+// tracing connections.
+type TraceMessage struct {
+	Time time.Time
+	// Sent is set for messages that we sent, and unset for messages
+	// that we received.
+	Sent bool
+	// Interface and Object identify the object that the message was
+	// sent to or by.
+	Interface *wlproto.Interface
+	Object    ObjectID
+	// Opcode and Name identify the request or event.
+	Opcode int
+	Name   string
+	Args   []TraceArg
+}
+
+// TraceArg is an argument of a traced message.
+type TraceArg struct {
+	Type wlproto.ArgType
+	// Enum describes the values of int and uint arguments that refer
+	// to an enum.
+	Enum *wlproto.Enum
+	// Value is the value of the argument. Its type depends on Type:
+	// int32 for ints, uint32 for uints, Fixed, string, ObjectID for
+	// objects and new IDs, []byte for arrays and int for file
+	// descriptors. It is nil for null strings and objects.
+	Value interface{}
+	// Interface is the name of the interface of object and new ID
+	// arguments, or the empty string if it isn't known.
+	Interface string
+}
+
+// A Tracer receives the messages that are sent and received over a
+// connection. Tracers must not retain messages after Trace returns.
+type Tracer interface {
+	Trace(msg *TraceMessage)
+}
+
+// TracerFunc is an adapter that allows using a function as a Tracer.
+type TracerFunc func(msg *TraceMessage)
+
+func (fn TracerFunc) Trace(msg *TraceMessage) { fn(msg) }
+
+// DebugEnabled reports whether the WAYLAND_DEBUG environment variable
+// enables tracing for side, which is either "client" or "server". Like
+// libwayland, a value of 1 enables tracing for both sides.
+func DebugEnabled(side string) bool {
+	v := os.Getenv("WAYLAND_DEBUG")
+	return strings.Contains(v, side) || strings.Contains(v, "1")
+}
+
+type writerTracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterTracer returns a tracer that writes messages to w in the
+// format used by libwayland, such as
+//
+//	[1234567.890]  -> wl_surface@3.attach(wl_buffer@7, 0, 0)
+//
+// It is safe to use the tracer from several goroutines.
+func NewWriterTracer(w io.Writer) Tracer {
+	return &writerTracer{w: w}
+}
+
+func (t *writerTracer) Trace(msg *TraceMessage) {
+	s := msg.String()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintln(t.w, s)
+}
+
+// String formats the message like libwayland does when WAYLAND_DEBUG
+// is set. Enum values are followed by their names.
+func (msg *TraceMessage) String() string {
+	var sb strings.Builder
+	// Like libwayland, print the time in milliseconds with microsecond
+	// precision, truncated to 32 bits of microseconds.
+	usec := uint32(msg.Time.UnixNano() / 1000)
+	fmt.Fprintf(&sb, "[%7d.%03d] ", usec/1000, usec%1000)
+	if msg.Sent {
+		sb.WriteString(" -> ")
+	}
+	fmt.Fprintf(&sb, "%s@%d.%s(", msg.Interface.Name, msg.Object, msg.Name)
+	for i, arg := range msg.Args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(arg.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// String formats the argument like libwayland does.
+func (arg TraceArg) String() string {
+	iface := arg.Interface
+	if iface == "" {
+		iface = "[unknown]"
+	}
+	switch arg.Type {
+	case wlproto.ArgTypeInt, wlproto.ArgTypeUint:
+		if arg.Enum != nil {
+			return fmt.Sprintf("%d (%s)", arg.Value, arg.Enum.Format(enumValue(arg.Value)))
+		}
+		return fmt.Sprint(arg.Value)
+	case wlproto.ArgTypeFixed:
+		return fmt.Sprintf("%f", arg.Value.(Fixed).Float64())
+	case wlproto.ArgTypeString:
+		if arg.Value == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("%q", arg.Value)
+	case wlproto.ArgTypeObject:
+		if arg.Value == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("%s@%d", iface, arg.Value)
+	case wlproto.ArgTypeNewID:
+		if arg.Value == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("new id %s@%d", iface, arg.Value)
+	case wlproto.ArgTypeArray:
+		return fmt.Sprintf("array[%d]", len(arg.Value.([]byte)))
+	case wlproto.ArgTypeFd:
+		return fmt.Sprintf("fd %d", arg.Value)
+	default:
+		return fmt.Sprint(arg.Value)
+	}
+}
+
+func enumValue(v interface{}) uint32 {
+	switch v := v.(type) {
+	case int32:
+		return uint32(v)
+	case uint32:
+		return v
+	default:
+		return 0
+	}
+}
+
+// TraceArgs converts the arguments of a message, as passed to
+// EncodeMessage, to their traced form. Arguments that are objects
+// should have an Interface method, like the objects of the client and
+// server packages, for their interface to be known.
+func TraceArgs(sig []wlproto.Arg, args []interface{}) []TraceArg {
+	out := make([]TraceArg, len(args))
+	for i, arg := range args {
+		var a wlproto.Arg
+		if i < len(sig) {
+			a = sig[i]
+		}
+		out[i] = TraceArg{Type: a.Type, Enum: a.Enum}
+		if obj, ok := arg.(Object); ok {
+			if a.Type == 0 {
+				out[i].Type = wlproto.ArgTypeObject
+			}
+			if rv := reflect.ValueOf(obj); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				// null object
+				continue
+			}
+			if obj.ID() == 0 {
+				continue
+			}
+			out[i].Value = obj.ID()
+			if obj, ok := obj.(interface{ Interface() *wlproto.Interface }); ok {
+				out[i].Interface = obj.Interface().Name
+			}
+			continue
+		}
+		if arg == nil {
+			continue
+		}
+		v := reflect.ValueOf(arg)
+		switch a.Type {
+		case wlproto.ArgTypeInt:
+			out[i].Value = int32(word(v))
+		case wlproto.ArgTypeUint:
+			out[i].Value = word(v)
+		case wlproto.ArgTypeFixed:
+			out[i].Value = Fixed(word(v))
+		case wlproto.ArgTypeString:
+			if s := v.String(); s != "" || !a.AllowNull {
+				out[i].Value = s
+			}
+		case wlproto.ArgTypeObject, wlproto.ArgTypeNewID:
+			if id := ObjectID(v.Uint()); id != 0 {
+				out[i].Value = id
+			}
+		case wlproto.ArgTypeArray:
+			out[i].Value = v.Bytes()
+		case wlproto.ArgTypeFd:
+			out[i].Value = int(v.Uint())
+		default:
+			out[i].Value = arg
+		}
+	}
+	return out
+}
+
+// word returns the bits of an integer as they are sent on the wire.
+// Enum arguments have unsigned types even if they are sent as ints.
+func word(v reflect.Value) uint32 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint32(v.Int())
+	default:
+		return uint32(v.Uint())
+	}
+}
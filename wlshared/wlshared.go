@@ -3,6 +3,7 @@ package wlshared
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"reflect"
 	"syscall"
 	"unsafe"
@@ -21,14 +22,17 @@ func init() {
 	}
 }
 
+// Fixed is a signed 24.8 fixed-point number.
 type Fixed uint32
 
+// Float64 converts f to a float64. The conversion is exact.
 func (f Fixed) Float64() float64 {
-	panic("XXX")
+	return float64(int32(f)) / 256
 }
 
+// FromFloat64 converts f to the nearest fixed-point number.
 func FromFloat64(f float64) Fixed {
-	panic("XXX")
+	return Fixed(int32(math.Round(f * 256)))
 }
 
 type ObjectID uint32
@@ -38,14 +42,36 @@ type Object interface {
 	ID() ObjectID
 }
 
-func EncodeRequest(buf []byte, source ObjectID, request int, args []interface{}) (data []byte, oob []byte) {
+func EncodeRequest(buf []byte, source ObjectID, request int, sig []wlproto.Arg, args []interface{}) (data []byte, oob []byte) {
+	buf, fds := EncodeMessage(buf, source, request, sig, args)
+	if len(fds) > 0 {
+		// OPT(dh): we send file descriptors so rarely that allocating
+		// here isn't an issue.
+		oob = syscall.UnixRights(fds...)
+	}
+	return buf, oob
+}
+
+// EncodeMessage appends the wire representation of a message to buf.
+// Unlike EncodeRequest, it returns the file descriptors to be sent
+// alongside the message instead of encoding them as a control
+// message, so that callers can buffer several messages before
+// sending them.
+//
+// Sig is the message's signature. It is used to encode empty strings
+// as null if the argument allows it, and may be nil otherwise.
+func EncodeMessage(buf []byte, source ObjectID, opcode int, sig []wlproto.Arg, args []interface{}) (data []byte, fds []int) {
+	start := len(buf)
 	buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
 	var scratch [4]byte
 
-	var fds []int
-	for _, arg := range args {
+	for i, arg := range args {
 		if v, ok := arg.(Object); ok {
-			id := v.ID()
+			var id ObjectID
+			// nil pointers encode null objects
+			if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || !rv.IsNil() {
+				id = v.ID()
+			}
 			byteOrder.PutUint32(scratch[:], uint32(id))
 			buf = append(buf, scratch[:]...)
 		} else {
@@ -59,6 +85,12 @@ func EncodeRequest(buf []byte, source ObjectID, request int, args []interface{})
 				buf = append(buf, scratch[:]...)
 			case reflect.String:
 				str := v.String()
+				if str == "" && i < len(sig) && sig[i].AllowNull {
+					// null string
+					byteOrder.PutUint32(scratch[:], 0)
+					buf = append(buf, scratch[:]...)
+					continue
+				}
 				byteOrder.PutUint32(scratch[:], uint32(len(str)+1))
 				buf = append(buf, scratch[:]...)
 				buf = append(buf, str...)
@@ -68,7 +100,15 @@ func EncodeRequest(buf []byte, source ObjectID, request int, args []interface{})
 				for i := n - m; i > 0; i-- {
 					buf = append(buf, 0)
 				}
-			// XXX array
+			case reflect.Slice:
+				// arrays are passed as []byte
+				data := v.Bytes()
+				byteOrder.PutUint32(scratch[:], uint32(len(data)))
+				buf = append(buf, scratch[:]...)
+				buf = append(buf, data...)
+				for i := ((len(data) + 3) &^ 3) - len(data); i > 0; i-- {
+					buf = append(buf, 0)
+				}
 			case reflect.Uintptr:
 				fds = append(fds, int(v.Uint()))
 			default:
@@ -76,16 +116,12 @@ func EncodeRequest(buf []byte, source ObjectID, request int, args []interface{})
 			}
 		}
 	}
-	byteOrder.PutUint32(buf[0:4], uint32(source))
-	byteOrder.PutUint16(buf[4:6], uint16(request))
-	byteOrder.PutUint16(buf[6:8], uint16(len(buf)))
+	hdr := buf[start:]
+	byteOrder.PutUint32(hdr[0:4], uint32(source))
+	byteOrder.PutUint16(hdr[4:6], uint16(opcode))
+	byteOrder.PutUint16(hdr[6:8], uint16(len(hdr)))
 
-	if len(fds) > 0 {
-		// OPT(dh): we send file descriptors so rarely that allocating
-		// here isn't an issue.
-		oob = syscall.UnixRights(fds...)
-	}
-	return buf, oob
+	return buf, fds
 }
 
 func ParseArgument(arg wlproto.Arg, d []byte, off int) (newOff int, v interface{}) {
@@ -97,7 +133,11 @@ func ParseArgument(arg wlproto.Arg, d []byte, off int) (newOff int, v interface{
 	var out interface{}
 	switch arg.Type {
 	case wlproto.ArgTypeInt:
-		out = int32(num)
+		if arg.Aux != nil {
+			out = reflect.ValueOf(int32(num)).Convert(arg.Aux).Interface()
+		} else {
+			out = int32(num)
+		}
 	case wlproto.ArgTypeUint:
 		if arg.Aux != nil {
 			out = reflect.ValueOf(uint32(num)).Convert(arg.Aux).Interface()
@@ -107,6 +147,11 @@ func ParseArgument(arg wlproto.Arg, d []byte, off int) (newOff int, v interface{
 	case wlproto.ArgTypeFixed:
 		out = Fixed(num)
 	case wlproto.ArgTypeString:
+		if num == 0 {
+			// null string
+			out = ""
+			break
+		}
 		out = string(d[off : off+int(num)-1])
 		off += int(num)
 		off = (off + 3) & ^3
@@ -127,3 +172,35 @@ func ParseArgument(arg wlproto.Arg, d []byte, off int) (newOff int, v interface{
 
 	return off, out
 }
+
+// MaxFds is the maximum number of file descriptors that the kernel
+// passes with a single message (SCM_MAX_FD).
+const MaxFds = 253
+
+// OOBSize is the size of the buffer for out-of-band data needed to
+// receive all file descriptors passed with a single message.
+var OOBSize = syscall.CmsgSpace(MaxFds * 4)
+
+// ParseFds returns the file descriptors contained in the control
+// messages in oob, in the order they were sent.
+func ParseFds(oob []byte) ([]int, error) {
+	if len(oob) == 0 {
+		return nil, nil
+	}
+	scms, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	var fds []int
+	for i := range scms {
+		if scms[i].Header.Level != syscall.SOL_SOCKET || scms[i].Header.Type != syscall.SCM_RIGHTS {
+			continue
+		}
+		rights, err := syscall.ParseUnixRights(&scms[i])
+		if err != nil {
+			return fds, err
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}